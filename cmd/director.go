@@ -19,33 +19,56 @@ import (
 	"context"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/Octops/agones-discover-openmatch/pkg/director/election"
 	"github.com/Octops/agones-discover-openmatch/pkg/director/openmatch"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/frontend"
 	"github.com/pkg/errors"
+	"open-match.dev/open-match/pkg/pb"
+	"os"
 
 	"github.com/spf13/cobra"
 )
 
-type AgonesAllocatorArgs struct {
-	KeyFile              string
-	CertFile             string
-	CaCertFile           string
-	AllocatorServiceHost string
-	AllocatorServicePort int
-	Namespace            string
-	MultiCluster         bool
+// directorFlags binds the config keys to the director flags
+var directorFlags = map[string]string{
+	"director.interval":                      "interval",
+	"director.profile_intervals":             "profile-interval",
+	"director.max_concurrency":               "max-concurrency",
+	"director.jitter":                        "jitter",
+	"director.fetch_timeout":                 "fetch-timeout",
+	"director.drain_timeout":                 "drain-timeout",
+	"director.allocation_workers":            "allocation-workers",
+	"director.mode":                          "mode",
+	"director.connection_template":           "connection-template",
+	"director.capacity.source":               "capacity-source",
+	"director.capacity.key":                  "capacity-key",
+	"director.capacity.unknown":              "capacity-unknown",
+	"director.capacity.assume":               "capacity-assume",
+	"director.octops_discover_url":           "octops-discover-url",
+	"director.agones_allocator.key_file":     "key",
+	"director.agones_allocator.cert_file":    "cert",
+	"director.agones_allocator.ca_cert_file": "cacert",
+	"director.agones_allocator.host":         "allocator-host",
+	"director.agones_allocator.port":         "allocator-port",
+	"director.agones_allocator.namespace":    "namespace",
+	"director.agones_allocator.multicluster": "multicluster",
+	"director.election.mode":                 "election",
+	"director.election.shards":               "election-shards",
+	"director.election.backend":              "election-backend",
+	"director.election.lease_name":           "election-lease-name",
+	"director.election.namespace":            "election-namespace",
+	"director.election.identity":             "election-identity",
+	"director.election.lease_duration":       "election-lease-duration",
+	"director.election.renew_deadline":       "election-renew-deadline",
+	"director.election.retry_period":         "election-retry-period",
+	"director.backfill.enabled":              "backfill",
+	"director.backfill.interval":             "backfill-interval",
+	"director.backfill.pending_timeout":      "backfill-pending-timeout",
 }
 
-type OctopsDiscoverArgs struct {
-	DiscoverServiceURL string
-}
-
-var (
-	intervalDirector    string
-	allocatorMode       string
-	agonesAllocatorArgs = &AgonesAllocatorArgs{}
-	octopsDiscoverArgs  = &OctopsDiscoverArgs{}
-)
-
 // directorCmd represents the director command
 var directorCmd = &cobra.Command{
 	Use:   "director",
@@ -54,34 +77,154 @@ var directorCmd = &cobra.Command{
 For these matches, it fetches Game Server from a DGS allocation system. 
 It can then communicate the Assignments back to the Game Frontend.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		logger := runtime.NewLogger(verbose).WithField("component", "director")
+		cfg, err := loadConfig(cmd, directorFlags)
+		if err != nil {
+			runtime.NewLogger(verbose).Fatal(err)
+		}
+
+		logger := runtime.NewLogger(cfg.Verbose).WithField("component", "director")
+		if err := cfg.ValidateDirector(); err != nil {
+			logger.Fatal(errors.Wrap(err, "invalid config"))
+		}
+
 		ctx, cancel := context.WithCancel(context.Background())
 		runtime.SetupSignal(cancel)
 
 		logger.Info("starting OpenMatch Director")
-		agonesAllocator, err := BuildAgonesAllocatorService(allocatorMode)
+		agonesAllocator, err := BuildAgonesAllocatorService(cfg.Director)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		capacity := cfg.Director.Capacity
+		capacityPolicy, err := extensions.ParseCapacityPolicy(capacity.Source, capacity.Key, capacity.Unknown, capacity.Assume)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		scheduler, err := BuildScheduler(cfg.Director)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		elector, err := BuildElector(cfg.Director.Election)
 		if err != nil {
 			logger.Fatal(err)
 		}
 
-		if err := openmatch.RunDirector(ctx, logger, openmatch.ConnFuncInsecure, intervalDirector, agonesAllocator); err != nil {
+		options := openmatch.DirectorOptions{
+			Profile: openmatch.ProfileOptions{
+				ConnectionTemplate: cfg.Director.ConnectionTemplate,
+				CapacityPolicy:     capacityPolicy,
+			},
+			MatchFunction: openmatch.MatchFunctionServer{
+				HostName: cfg.OpenMatch.MatchFunctionHost,
+				Port:     cfg.OpenMatch.MatchFunctionPort,
+			},
+			FetchTimeout:      cfg.Director.FetchTimeout,
+			AllocationWorkers: cfg.Director.AllocationWorkers,
+			Election:          elector,
+		}
+
+		if cfg.Director.Backfill.Enabled {
+			conn, err := frontend.FrontEndConn(cfg.OpenMatch)
+			if err != nil {
+				logger.Fatal(errors.Wrap(err, "failed to connect to Open Match Frontend"))
+			}
+			defer conn.Close()
+
+			options.Backfills, err = BuildBackfills(cfg.Director.Backfill, pb.NewFrontendServiceClient(conn), agonesAllocator)
+			if err != nil {
+				logger.Fatal(err)
+			}
+		}
+
+		if err := openmatch.RunDirector(ctx, logger, openmatch.ConnFuncFromConfig(cfg.OpenMatch), scheduler, options, agonesAllocator); err != nil {
 			logger.Fatal(errors.Wrap(err, "failed to start the Director"))
 		}
 	},
 }
 
-func BuildAgonesAllocatorService(mode string) (*allocator.AllocatorService, error) {
+func BuildScheduler(cfg config.DirectorConfig) (*director.Scheduler, error) {
+	profileIntervals, err := director.ParseProfileIntervals(cfg.ProfileIntervals)
+	if err != nil {
+		return nil, err
+	}
+
+	return &director.Scheduler{
+		Interval:         cfg.Interval,
+		ProfileIntervals: profileIntervals,
+		MaxConcurrency:   cfg.MaxConcurrency,
+		Jitter:           cfg.Jitter,
+		DrainTimeout:     cfg.DrainTimeout,
+	}, nil
+}
+
+// BuildElector returns nil if the election is off
+func BuildElector(cfg config.ElectionConfig) (*election.Elector, error) {
+	if cfg.Mode == "none" {
+		return nil, nil
+	}
+
+	identity := cfg.Identity
+	if len(identity) == 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get hostname, set the election identity")
+		}
+		identity = hostname
+	}
+
+	var lock election.Lock
+	switch cfg.Backend {
+	case "memory":
+		lock = election.NewMemoryLock()
+	default: // "kubernetes"
+		k8sLock, err := election.NewKubernetesLockInCluster(cfg.Namespace)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create Kubernetes lease lock")
+		}
+		lock = k8sLock
+	}
+
+	shards := 1
+	if cfg.Mode == "shard" {
+		shards = cfg.Shards
+	}
+
+	return &election.Elector{
+		Lock:          lock,
+		Name:          cfg.LeaseName,
+		Identity:      identity,
+		Shards:        shards,
+		LeaseDuration: cfg.LeaseDuration,
+		RenewDeadline: cfg.RenewDeadline,
+		RetryPeriod:   cfg.RetryPeriod,
+	}, nil
+}
+
+// BuildBackfills uses the allocator of the director to look up the capacity of the GameServers of the backfills
+func BuildBackfills(cfg config.BackfillConfig, service openmatch.BackfillService, allocatorSvc *allocator.AllocatorService) (*openmatch.Backfills, error) {
+	lookup, ok := allocatorSvc.GameServerAllocator.(allocator.GameServerLookup)
+	if !ok {
+		return nil, errors.New("the allocator can't look up gameservers, backfills require the discover mode")
+	}
+
+	return openmatch.NewBackfills(service, lookup, cfg.Interval, cfg.PendingTimeout)
+}
+
+func BuildAgonesAllocatorService(cfg config.DirectorConfig) (*allocator.AllocatorService, error) {
 	var allocatorSvc *allocator.AllocatorService
-	switch mode {
+	switch cfg.Mode {
 	case "agones":
 		config := &allocator.AgonesAllocatorClientConfig{
-			KeyFile:              agonesAllocatorArgs.KeyFile,
-			CertFile:             agonesAllocatorArgs.CertFile,
-			CaCertFile:           agonesAllocatorArgs.CaCertFile,
-			AllocatorServiceHost: agonesAllocatorArgs.AllocatorServiceHost,
-			AllocatorServicePort: agonesAllocatorArgs.AllocatorServicePort,
-			Namespace:            agonesAllocatorArgs.Namespace,
-			MultiCluster:         agonesAllocatorArgs.MultiCluster,
+			KeyFile:              cfg.AgonesAllocator.KeyFile,
+			CertFile:             cfg.AgonesAllocator.CertFile,
+			CaCertFile:           cfg.AgonesAllocator.CaCertFile,
+			AllocatorServiceHost: cfg.AgonesAllocator.Host,
+			AllocatorServicePort: cfg.AgonesAllocator.Port,
+			Namespace:            cfg.AgonesAllocator.Namespace,
+			MultiCluster:         cfg.AgonesAllocator.MultiCluster,
 		}
 
 		client, err := allocator.NewAgonesAllocatorClient(config)
@@ -93,7 +236,7 @@ func BuildAgonesAllocatorService(mode string) (*allocator.AllocatorService, erro
 		})
 	default: //"discover"
 		// TODO: Refactor using Flags and Registry
-		client, err := allocator.NewAgonesDiscoverClientHTTP(octopsDiscoverArgs.DiscoverServiceURL)
+		client, err := allocator.NewAgonesDiscoverClientHTTP(cfg.OctopsDiscoverURL)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create AgonesDiscoverClientHTTP")
 		}
@@ -108,14 +251,38 @@ func BuildAgonesAllocatorService(mode string) (*allocator.AllocatorService, erro
 func init() {
 	rootCmd.AddCommand(directorCmd)
 
-	directorCmd.Flags().StringVar(&intervalDirector, "interval", "5s", "interval the Director will fetch matches")
-	directorCmd.Flags().StringVar(&allocatorMode, "mode", "discover", "allocator mode for the director")
-	directorCmd.Flags().StringVar(&octopsDiscoverArgs.DiscoverServiceURL, "octops-discover-url", "http://localhost:8081", "the Octops Discover server URL")
-	directorCmd.Flags().StringVar(&agonesAllocatorArgs.KeyFile, "key", "", "the private key file for the client certificate in PEM format")
-	directorCmd.Flags().StringVar(&agonesAllocatorArgs.CertFile, "cert", "", "the public key file for the client certificate in PEM format")
-	directorCmd.Flags().StringVar(&agonesAllocatorArgs.CaCertFile, "cacert", "", "the CA cert file for server signing certificate in PEM format")
-	directorCmd.Flags().StringVar(&agonesAllocatorArgs.AllocatorServiceHost, "allocator-host", "0.0.0.0", "the host address for allocator server")
-	directorCmd.Flags().IntVar(&agonesAllocatorArgs.AllocatorServicePort, "allocator-port", 443, "the host address for allocator server")
-	directorCmd.Flags().StringVar(&agonesAllocatorArgs.Namespace, "namespace", "default", "the game server kubernetes namespace")
-	directorCmd.Flags().BoolVar(&agonesAllocatorArgs.MultiCluster, "multicluster", false, "set to true to enable the multi-cluster allocation")
+	defaults := config.Default().Director
+	directorCmd.Flags().Duration("interval", defaults.Interval, "interval the Director will fetch matches")
+	directorCmd.Flags().StringSlice("profile-interval", defaults.ProfileIntervals, "interval by profile name overriding --interval, i.e. world_based_profile_Dune_us-east-1=10s")
+	directorCmd.Flags().Int("max-concurrency", defaults.MaxConcurrency, "max number of profiles fetching matches at the same time, 0 means unbounded")
+	directorCmd.Flags().Duration("jitter", defaults.Jitter, "upper bound of the random delay added to the first run of each profile")
+	directorCmd.Flags().Duration("fetch-timeout", defaults.FetchTimeout, "deadline of every FetchMatches call, matches streamed before it are still assigned. 0 means no deadline")
+	directorCmd.Flags().Duration("drain-timeout", defaults.DrainTimeout, "time in-flight matches have to be assigned on shutdown, the tickets of the matches not assigned by then are released")
+	directorCmd.Flags().Int("allocation-workers", defaults.AllocationWorkers, "number of matches of a profile allocated at the same time")
+	directorCmd.Flags().String("mode", defaults.Mode, "allocator mode for the director")
+	directorCmd.Flags().String("connection-template", defaults.ConnectionTemplate, "template of the assignment connection, supports {address}, {port}, {port:<name>} and {ports}")
+	directorCmd.Flags().String("capacity-source", defaults.Capacity.Source, "source of the gameserver capacity: players, counter or list")
+	directorCmd.Flags().String("capacity-key", defaults.Capacity.Key, "name of the Agones counter or list used as capacity source")
+	directorCmd.Flags().String("capacity-unknown", defaults.Capacity.Unknown, "policy for gameservers with unknown capacity: allow, deny or assume")
+	directorCmd.Flags().Int64("capacity-assume", defaults.Capacity.Assume, "capacity assumed for gameservers with unknown capacity when --capacity-unknown=assume")
+	directorCmd.Flags().String("octops-discover-url", defaults.OctopsDiscoverURL, "the Octops Discover server URL")
+	directorCmd.Flags().String("key", defaults.AgonesAllocator.KeyFile, "the private key file for the client certificate in PEM format")
+	directorCmd.Flags().String("cert", defaults.AgonesAllocator.CertFile, "the public key file for the client certificate in PEM format")
+	directorCmd.Flags().String("cacert", defaults.AgonesAllocator.CaCertFile, "the CA cert file for server signing certificate in PEM format")
+	directorCmd.Flags().String("allocator-host", defaults.AgonesAllocator.Host, "the host address for allocator server")
+	directorCmd.Flags().Int("allocator-port", defaults.AgonesAllocator.Port, "the host address for allocator server")
+	directorCmd.Flags().String("namespace", defaults.AgonesAllocator.Namespace, "the game server kubernetes namespace")
+	directorCmd.Flags().Bool("multicluster", defaults.AgonesAllocator.MultiCluster, "set to true to enable the multi-cluster allocation")
+	directorCmd.Flags().String("election", defaults.Election.Mode, "how multiple replicas split the profiles: none, leader or shard")
+	directorCmd.Flags().Int("election-shards", defaults.Election.Shards, "number of shards the profiles are split into when --election=shard, it should match the number of replicas")
+	directorCmd.Flags().String("election-backend", defaults.Election.Backend, "lock backend of the election: kubernetes or memory")
+	directorCmd.Flags().String("election-lease-name", defaults.Election.LeaseName, "name of the Lease, shards use <name>-<index>")
+	directorCmd.Flags().String("election-namespace", defaults.Election.Namespace, "namespace of the Lease, the pod namespace if empty")
+	directorCmd.Flags().String("election-identity", defaults.Election.Identity, "identity of the replica, the hostname if empty")
+	directorCmd.Flags().Duration("election-lease-duration", defaults.Election.LeaseDuration, "time a lease is valid without being renewed")
+	directorCmd.Flags().Duration("election-renew-deadline", defaults.Election.RenewDeadline, "time a replica keeps its profiles without renewing the lease, it must be shorter than the lease duration")
+	directorCmd.Flags().Duration("election-retry-period", defaults.Election.RetryPeriod, "interval between tries to acquire or renew the lease")
+	directorCmd.Flags().Bool("backfill", defaults.Backfill.Enabled, "keep backfills for the allocated gameservers with free slots, requires --mode=discover and the Open Match Frontend")
+	directorCmd.Flags().Duration("backfill-interval", defaults.Backfill.Interval, "interval between the updates of the backfills open slots")
+	directorCmd.Flags().Duration("backfill-pending-timeout", defaults.Backfill.PendingTimeout, "time the players assigned to a backfill have to be counted by Agones before their slots are open again")
 }
@@ -0,0 +1,165 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/Octops/agones-discover-openmatch/pkg/fakefleet"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+var (
+	fakeFleetFile            string
+	fakeFleetAddress         string
+	fakeFleetPortBase        int32
+	fakeFleetSessionDuration time.Duration
+	fakeFleetConnectOnAlloc  bool
+	fakeFleetDiscoverAddr    string
+	fakeFleetAllocatorAddr   string
+	fakeFleetTLSCert         string
+	fakeFleetTLSKey          string
+	fakeFleetTLSClientCA     string
+	fakeFleetTLSDir          string
+)
+
+// fakeFleetCmd represents the fake-fleet command
+var fakeFleetCmd = &cobra.Command{
+	Use:   "fake-fleet",
+	Short: "Serve an in-memory fleet of GameServers for local demos",
+	Long: `Serve an in-memory fleet of GameServers behind a fake Octops Discover HTTP API and a fake Agones Allocator
+Service, so the director can run in discover or agones mode without Kubernetes. Every GameServer listens on its own
+port and counts the players that connect to it, like the simulate command does with --connect.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := runtime.NewLogger(verbose).WithField("component", "fake_fleet")
+
+		specs := fakefleet.DefaultFleets()
+		if len(fakeFleetFile) > 0 {
+			var err error
+			if specs, err = fakefleet.LoadFleets(fakeFleetFile); err != nil {
+				return err
+			}
+		}
+
+		fleet, err := fakefleet.NewFleet(specs, fakeFleetAddress, fakeFleetPortBase)
+		if err != nil {
+			return err
+		}
+		fleet.SessionDuration = fakeFleetSessionDuration
+		fleet.ConnectOnAllocate = fakeFleetConnectOnAlloc
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		runtime.SetupSignal(cancel)
+
+		errServe := make(chan error, 3)
+		go func() {
+			errServe <- fleet.ServePlayers(ctx, fakeFleetAddress)
+		}()
+
+		if len(fakeFleetDiscoverAddr) > 0 {
+			server := &http.Server{Addr: fakeFleetDiscoverAddr, Handler: fakefleet.DiscoverHandler(fleet)}
+			defer server.Close()
+
+			go func() {
+				if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					errServe <- errors.Wrap(err, "failed to serve Octops Discover")
+				}
+			}()
+			logger.Infof("serving Octops Discover on %s", fakeFleetDiscoverAddr)
+		}
+
+		if len(fakeFleetAllocatorAddr) > 0 {
+			server, ln, err := fakeAllocatorServer(fleet)
+			if err != nil {
+				return err
+			}
+			defer server.Stop()
+
+			go func() {
+				errServe <- server.Serve(ln)
+			}()
+			logger.Infof("serving Agones Allocator Service on %s", fakeFleetAllocatorAddr)
+		}
+
+		logger.Infof("serving %d gameservers on %s from port %d", len(fleet.List(nil)), fakeFleetAddress, fakeFleetPortBase)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errServe:
+			return err
+		}
+	},
+}
+
+// fakeAllocatorServer builds the fake Agones Allocator Service with the certificates of the flags or generated ones
+func fakeAllocatorServer(fleet *fakefleet.Fleet) (*grpc.Server, net.Listener, error) {
+	logger := runtime.Logger().WithField("component", "fake_fleet")
+
+	if len(fakeFleetTLSDir) > 0 {
+		host, _, err := net.SplitHostPort(fakeFleetAllocatorAddr)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "allocator address %s is invalid", fakeFleetAllocatorAddr)
+		}
+
+		certs, err := fakefleet.GenerateCertificates(fakeFleetTLSDir, host)
+		if err != nil {
+			return nil, nil, err
+		}
+		fakeFleetTLSCert, fakeFleetTLSKey, fakeFleetTLSClientCA = certs.ServerCert, certs.ServerKey, certs.CACert
+		logger.Infof("certificates written to %s, run the director with --cert %s --key %s --cacert %s", fakeFleetTLSDir, certs.ClientCert, certs.ClientKey, certs.CACert)
+	}
+
+	if len(fakeFleetTLSCert) == 0 || len(fakeFleetTLSKey) == 0 {
+		return nil, nil, errors.New("the fake Allocator Service requires --tls-cert and --tls-key or --tls-dir")
+	}
+
+	tlsConfig, err := config.ServerTLSConfig(fakeFleetTLSCert, fakeFleetTLSKey, fakeFleetTLSClientCA)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ln, err := net.Listen("tcp", fakeFleetAllocatorAddr)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to listen on %s", fakeFleetAllocatorAddr)
+	}
+
+	return fakefleet.NewAllocationGRPCServer(fleet, tlsConfig), ln, nil
+}
+
+func init() {
+	rootCmd.AddCommand(fakeFleetCmd)
+
+	fakeFleetCmd.Flags().StringVar(&fakeFleetFile, "fleets", "", "YAML file with the list of fleets, the default fleets cover the worlds and regions of the simulator")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetAddress, "address", "127.0.0.1", "address of the GameServers, the players connect to it")
+	fakeFleetCmd.Flags().Int32Var(&fakeFleetPortBase, "port-base", 7000, "port of the first GameServer, the next ones use the following ports")
+	fakeFleetCmd.Flags().DurationVar(&fakeFleetSessionDuration, "session-duration", time.Minute, "how long players stay connected and Allocated GameServers without players stay Allocated, 0 keeps them forever")
+	fakeFleetCmd.Flags().BoolVar(&fakeFleetConnectOnAlloc, "connect-on-allocate", false, "connect the players of every allocation of the Agones Allocator Service, so the fleet fills up without the simulator connecting")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetDiscoverAddr, "discover-addr", ":8081", "address of the Octops Discover HTTP API, empty to disable it")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetAllocatorAddr, "allocator-addr", "", "address of the Agones Allocator Service, empty to disable it")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetTLSCert, "tls-cert", "", "server certificate of the Agones Allocator Service")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetTLSKey, "tls-key", "", "server key of the Agones Allocator Service")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetTLSClientCA, "tls-client-ca", "", "CA of the client certificates, clients must present one if it is set")
+	fakeFleetCmd.Flags().StringVar(&fakeFleetTLSDir, "tls-dir", "", "dir to generate a CA, server and client certificates into, overrides the --tls flags")
+}
@@ -20,8 +20,10 @@ import (
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/config"
 	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/functions"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"strings"
 )
 
 // functionCmd represents the function command
@@ -31,8 +33,17 @@ var functionCmd = &cobra.Command{
 	Long: `The Match Function is the component that implements the core matchmaking logic. 
 A Match Function receives a MatchProfile as input should return matches for this MatchProfile.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		logger := runtime.NewLogger(verbose)
-		mmfServer, err := matchfunction.NewServer()
+		cfg, err := loadConfig(cmd, functionFlags)
+		if err != nil {
+			runtime.NewLogger(verbose).Fatal(err)
+		}
+
+		logger := runtime.NewLogger(cfg.Verbose)
+		if err := cfg.ValidateMatchFunction(); err != nil {
+			logger.Fatal(errors.Wrap(err, "invalid config"))
+		}
+
+		mmfServer, err := matchfunction.NewServer(cfg.OpenMatch, cfg.MatchFunction)
 		if err != nil {
 			logger.Fatal(errors.Wrap(err, "failed to create match function server"))
 		}
@@ -40,15 +51,48 @@ A Match Function receives a MatchProfile as input should return matches for this
 		ctx, cancel := context.WithCancel(context.Background())
 		runtime.SetupSignal(cancel)
 
-		if err := mmfServer.Serve(ctx, config.OpenMatch().MatchFunctionPort); err != nil {
+		if err := mmfServer.Serve(ctx, cfg.OpenMatch.MatchFunctionPort); err != nil {
 			logger.Fatal(errors.Wrap(err, "failed to start match function server"))
 		}
 	},
 }
 
+// functionFlags binds the config keys to the mmf flags
+var functionFlags = map[string]string{
+	"openmatch.match_function_port":       "port",
+	"mmf.graceful_stop_timeout":           "graceful-stop-timeout",
+	"mmf.max_recv_msg_size":               "max-recv-msg-size",
+	"mmf.max_send_msg_size":               "max-send-msg-size",
+	"mmf.reflection":                      "reflection",
+	"mmf.keepalive.time":                  "keepalive-time",
+	"mmf.keepalive.timeout":               "keepalive-timeout",
+	"mmf.keepalive.min_time":              "keepalive-min-time",
+	"mmf.keepalive.permit_without_stream": "keepalive-permit-without-stream",
+	"mmf.backfill":                        "backfill",
+	"mmf.function":                        "function",
+	"mmf.player_capacity":                 "player-capacity",
+	"mmf.record_dir":                      "record-dir",
+}
+
 func init() {
 	rootCmd.AddCommand(functionCmd)
 
+	functionCmd.Flags().Int32("port", config.Default().OpenMatch.MatchFunctionPort, "port the match function server listens on")
+
+	defaults := config.Default().MatchFunction
+	functionCmd.Flags().Duration("graceful-stop-timeout", defaults.GracefulStopTimeout, "time the match function streams in progress have to finish on shutdown")
+	functionCmd.Flags().Int("max-recv-msg-size", defaults.MaxRecvMsgSize, "max size in bytes of a received message, 0 uses the gRPC default")
+	functionCmd.Flags().Int("max-send-msg-size", defaults.MaxSendMsgSize, "max size in bytes of a sent message, 0 uses the gRPC default")
+	functionCmd.Flags().Bool("reflection", defaults.Reflection, "register the gRPC reflection service")
+	functionCmd.Flags().Bool("backfill", defaults.Backfill, "match tickets into the backfills of the GameServers with open slots")
+	functionCmd.Flags().String("function", defaults.Function, "name of the registered match function, one of "+strings.Join(functions.Names(), ", ")+", empty uses backfill with --backfill and player_capacity otherwise")
+	functionCmd.Flags().Int("player-capacity", defaults.PlayerCapacity, "player capacity of the GameServers passed to the match function")
+	functionCmd.Flags().String("record-dir", defaults.RecordDir, "dir the profile and pools of every run are recorded to for the replay command, empty disables recording")
+	functionCmd.Flags().Duration("keepalive-time", defaults.Keepalive.Time, "idle time before the server pings the client")
+	functionCmd.Flags().Duration("keepalive-timeout", defaults.Keepalive.Timeout, "time the server waits for the ping ack before closing the connection")
+	functionCmd.Flags().Duration("keepalive-min-time", defaults.Keepalive.MinTime, "shortest interval allowed between client pings")
+	functionCmd.Flags().Bool("keepalive-permit-without-stream", defaults.Keepalive.PermitWithoutStream, "allow client pings without active streams")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -0,0 +1,89 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/functions"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/replay"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replayFunction string
+	replayCapacity int
+	replayOutput   string
+)
+
+// replayCmd represents the replay command
+var replayCmd = &cobra.Command{
+	Use:   "replay <recording>...",
+	Short: "Run a match function against recorded ticket pools",
+	Long: `Run a registered match function in-process against ticket pools and profiles recorded by the mmf command
+with --record-dir, and print the proposals with match statistics. Open Match is not required.
+A recording can be a file with one recording, a file with a list of recordings or a dir of recording files.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runtime.NewLogger(verbose)
+
+		recordings, err := replay.Load(args...)
+		if err != nil {
+			return err
+		}
+
+		// The flags override the match function the recordings were made with
+		function, capacity, err := replay.RecordedFunction(recordings)
+		if err != nil {
+			return err
+		}
+
+		if len(function) == 0 || cmd.Flags().Changed("function") {
+			function = replayFunction
+		}
+
+		if capacity == 0 || cmd.Flags().Changed("capacity") {
+			capacity = replayCapacity
+		}
+
+		makeMatches, err := functions.Get(function, capacity)
+		if err != nil {
+			return err
+		}
+
+		results := replay.Run(recordings, makeMatches, capacity)
+
+		switch replayOutput {
+		case "json":
+			return replay.WriteJSON(os.Stdout, results)
+		case "table":
+			return replay.WriteTable(os.Stdout, results)
+		default:
+			return errors.Errorf("output %q is invalid, it should be table or json", replayOutput)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	replayCmd.Flags().StringVar(&replayFunction, "function", "player_capacity", "name of the registered match function, one of "+strings.Join(functions.Names(), ", ")+", overrides the one of the recordings")
+	replayCmd.Flags().IntVar(&replayCapacity, "capacity", 10, "player capacity of the GameServers passed to the match function, overrides the one of the recordings")
+	replayCmd.Flags().StringVar(&replayOutput, "output", "table", "output format: table or json")
+}
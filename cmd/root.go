@@ -17,11 +17,9 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
 	"github.com/spf13/cobra"
 	"os"
-
-	homedir "github.com/mitchellh/go-homedir"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -54,8 +52,6 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
@@ -67,28 +63,12 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "output verbose logs")
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-
-		// Search config in home directory with name ".agones-discover-openmatch" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".agones-discover-openmatch")
+// loadConfig loads the config file, the env vars and the flags of the command bound to the config keys
+func loadConfig(cmd *cobra.Command, bindings map[string]string) (*config.Config, error) {
+	flags := map[string]string{"verbose": "verbose"}
+	for key, name := range bindings {
+		flags[key] = name
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
-
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
-	}
+	return config.Load(cfgFile, cmd.Flags(), flags)
 }
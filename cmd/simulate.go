@@ -19,13 +19,27 @@ import (
 	"context"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/app"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
-var (
-	interval    string
-	playersPool int
-)
+// simulateFlags binds the config keys to the player simulate flags
+var simulateFlags = map[string]string{
+	"simulate.interval":        "interval",
+	"simulate.players_pool":    "players-pool",
+	"simulate.scenario":        "scenario",
+	"simulate.patience":        "patience",
+	"simulate.assignment_mode": "assignment-mode",
+	"simulate.poll_interval":   "poll-interval",
+	"simulate.connect":         "connect",
+	"simulate.connect_timeout": "connect-timeout",
+	"simulate.report_file":     "report-file",
+	"simulate.workers":         "workers",
+	"simulate.rate":            "rate",
+	"simulate.burst":           "burst",
+	"simulate.request_timeout": "request-timeout",
+}
 
 // simulateCmd represents the simulate command
 var simulateCmd = &cobra.Command{
@@ -34,7 +48,17 @@ var simulateCmd = &cobra.Command{
 	Long:  `The Player Simulator will request matches on a interval basis for different pool of players.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		logger := runtime.NewLogger(true).WithField("source", "player_simulator")
-		if err := app.RunPlayerSimulator(logger, context.Background(), interval, playersPool); err != nil {
+
+		cfg, err := loadConfig(cmd, simulateFlags)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if err := cfg.ValidateSimulate(); err != nil {
+			logger.Fatal(errors.Wrap(err, "invalid config"))
+		}
+
+		if err := app.RunPlayerSimulator(logger, context.Background(), cfg.OpenMatch, cfg.Simulate); err != nil {
 			logger.Fatal(err)
 		}
 	},
@@ -51,6 +75,18 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	simulateCmd.Flags().StringVar(&interval, "interval", "5s", "interval between match requests, 10s, 1m, 5m")
-	simulateCmd.Flags().IntVar(&playersPool, "players-pool", 10, "number of players to create matchmaking requests")
+	defaults := config.Default().Simulate
+	simulateCmd.Flags().Duration("interval", defaults.Interval, "interval between match requests, 10s, 1m, 5m")
+	simulateCmd.Flags().Int("players-pool", defaults.PlayersPool, "number of players to create matchmaking requests")
+	simulateCmd.Flags().String("scenario", defaults.Scenario, "scenario file with the arrival curve, attribute distributions and party sizes. --interval and --players-pool are ignored when set")
+	simulateCmd.Flags().Duration("patience", defaults.Patience, "time a player waits for a match before deleting the ticket, 0 waits until interrupted")
+	simulateCmd.Flags().String("assignment-mode", defaults.AssignmentMode, "how players wait for their assignment: watch or poll")
+	simulateCmd.Flags().Duration("poll-interval", defaults.PollInterval, "interval between GetTicket calls in poll mode and between retries in watch mode")
+	simulateCmd.Flags().Bool("connect", defaults.Connect, "dial the address of the assignment once the player is matched")
+	simulateCmd.Flags().Duration("connect-timeout", defaults.ConnectTimeout, "timeout of the connection to the assigned address")
+	simulateCmd.Flags().String("report-file", defaults.ReportFile, "file the JSON report is written to at the end of the simulation")
+	simulateCmd.Flags().Int("workers", defaults.Workers, "number of tickets created concurrently")
+	simulateCmd.Flags().Float64("rate", defaults.Rate, "limit of tickets created per second, 0 is unlimited")
+	simulateCmd.Flags().Int("burst", defaults.Burst, "number of tickets that can be created at once when --rate is set")
+	simulateCmd.Flags().Duration("request-timeout", defaults.RequestTimeout, "timeout of every CreateTicket call")
 }
@@ -0,0 +1,165 @@
+/*
+Copyright © 2020 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/app"
+	"github.com/Octops/agones-discover-openmatch/pkg/simulators/players"
+	"github.com/Octops/agones-discover-openmatch/pkg/tickets"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ticketsTags      []string
+	ticketsOlderThan time.Duration
+	ticketsOutput    string
+	ticketsWorkers   int
+	ticketsDryRun    bool
+)
+
+// ticketsCmd represents the tickets command
+var ticketsCmd = &cobra.Command{
+	Use:   "tickets",
+	Short: "List and delete the tickets of Open Match",
+	Long: `List the tickets waiting for a match with the QueryService and delete them with the FrontEnd service.
+The tickets of the player simulator are tagged with ` + players.SIMULATOR_TAG + `, expire them with:
+
+  tickets expire --tag ` + players.SIMULATOR_TAG + ` --older-than 10m`,
+}
+
+// ticketsListCmd represents the tickets list command
+var ticketsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tickets waiting for a match by tag and age",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTickets(cmd, func(ctx context.Context, logger *logrus.Entry, manager *tickets.Manager) error {
+			list, err := manager.List(ctx, tickets.Filter{Tags: ticketsTags, OlderThan: ticketsOlderThan})
+			if err != nil {
+				return err
+			}
+
+			switch ticketsOutput {
+			case "json":
+				return tickets.WriteJSON(os.Stdout, list)
+			case "table":
+				return tickets.WriteTable(os.Stdout, list, time.Now())
+			default:
+				return errors.Errorf("output %q is invalid, it should be table or json", ticketsOutput)
+			}
+		})
+	},
+}
+
+// ticketsDeleteCmd represents the tickets delete command
+var ticketsDeleteCmd = &cobra.Command{
+	Use:   "delete <ticket-id>...",
+	Short: "Delete tickets by id",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTickets(cmd, func(ctx context.Context, logger *logrus.Entry, manager *tickets.Manager) error {
+			return reportDeleted(logger, manager.Delete(ctx, args))
+		})
+	},
+}
+
+// ticketsExpireCmd represents the tickets expire command
+var ticketsExpireCmd = &cobra.Command{
+	Use:   "expire",
+	Short: "Delete the tickets waiting for a match by tag and age",
+	Long: `Delete the tickets waiting for a match that have all the tags and are older than --older-than.
+At least one of --tag or --older-than is required. The tickets in a proposal or assigned are not deleted.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := tickets.Filter{Tags: ticketsTags, OlderThan: ticketsOlderThan}
+		if filter.Empty() {
+			return errors.New("--tag or --older-than is required, use hack/reset_openmatch.sh to remove every ticket")
+		}
+
+		return runTickets(cmd, func(ctx context.Context, logger *logrus.Entry, manager *tickets.Manager) error {
+			if !ticketsDryRun {
+				stats, err := manager.Expire(ctx, filter)
+				if err != nil {
+					return err
+				}
+				return reportDeleted(logger, stats)
+			}
+
+			list, err := manager.List(ctx, filter)
+			if err != nil {
+				return err
+			}
+
+			logger.Infof("dry run, %d tickets would be deleted", len(list))
+			return tickets.WriteTable(os.Stdout, list, time.Now())
+		})
+	},
+}
+
+// runTickets loads the config and runs the tickets command until it finishes or is interrupted
+func runTickets(cmd *cobra.Command, run func(ctx context.Context, logger *logrus.Entry, manager *tickets.Manager) error) error {
+	logger := runtime.NewLogger(verbose).WithField("source", "tickets")
+
+	cfg, err := loadConfig(cmd, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.ValidateTickets(); err != nil {
+		return errors.Wrap(err, "invalid config")
+	}
+
+	manager, closeConns, err := app.NewTicketsManager(logger, cfg.OpenMatch, ticketsWorkers)
+	if err != nil {
+		return err
+	}
+	defer closeConns()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runtime.SetupSignal(cancel)
+
+	return run(ctx, logger, manager)
+}
+
+func reportDeleted(logger *logrus.Entry, stats tickets.DeleteStats) error {
+	logger.Infof("tickets summary: deleted=%d failed=%d", stats.Deleted, stats.Failed)
+	if stats.Failed > 0 {
+		return errors.Errorf("failed to delete %d tickets", stats.Failed)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(ticketsCmd)
+	ticketsCmd.AddCommand(ticketsListCmd, ticketsDeleteCmd, ticketsExpireCmd)
+
+	ticketsCmd.PersistentFlags().IntVar(&ticketsWorkers, "workers", 4, "number of tickets deleted concurrently")
+
+	for _, cmd := range []*cobra.Command{ticketsListCmd, ticketsExpireCmd} {
+		cmd.Flags().StringSliceVar(&ticketsTags, "tag", nil, "tag the tickets must have, repeat it or separate the tags with commas to require several")
+		cmd.Flags().DurationVar(&ticketsOlderThan, "older-than", 0, "only select the tickets created more than this long ago, i.e. 10m")
+	}
+
+	ticketsListCmd.Flags().StringVar(&ticketsOutput, "output", "table", "output format: table or json")
+	ticketsExpireCmd.Flags().BoolVar(&ticketsDryRun, "dry-run", false, "list the tickets that would be deleted without deleting them")
+}
@@ -3,9 +3,9 @@ package allocator
 import (
 	pb_agones "agones.dev/agones/pkg/allocation/go"
 	"context"
-	"fmt"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/status"
 	"open-match.dev/open-match/pkg/pb"
@@ -29,18 +29,21 @@ func (a *AgonesAllocator) Allocate(ctx context.Context, req *pb.AssignTicketsReq
 	logger := runtime.Logger().WithField("component", "allocator")
 
 	for _, assignmentGroup := range req.Assignments {
-		filter, err := extensions.ExtractFilterFromExtensions(assignmentGroup.Assignment.Extensions)
+		filter, err := extensions.GetFilter(assignmentGroup.Assignment)
 		if err != nil {
 			return errors.Wrap(err, "the assignment does not have a valid filter extension")
 		}
 
+		policy, err := extensions.GetCapacityPolicy(assignmentGroup.Assignment)
+		if err != nil {
+			return errors.Wrap(err, "the assignment does not have a valid capacity extension")
+		}
+
 		//TODO: Add PreferredGameServerSelector, MetaPatch, Scheduling. It must be part of the extensions
 		request := &pb_agones.AllocationRequest{
 			Namespace: a.Client.Config.Namespace,
 			GameServerSelectors: []*pb_agones.GameServerSelector{
-				{
-					MatchLabels: filter.Labels,
-				},
+				GameServerSelectorWithCapacity(filter.Labels, policy, extensions.GetPlayers(assignmentGroup.Assignment, int64(len(assignmentGroup.TicketIds)))),
 			},
 			MultiClusterSetting: &pb_agones.MultiClusterSetting{
 				Enabled: a.Client.Config.MultiCluster,
@@ -58,16 +61,55 @@ func (a *AgonesAllocator) Allocate(ctx context.Context, req *pb.AssignTicketsReq
 			return err
 		}
 
-		if len(resp.GetPorts()) > 0 {
-			address := fmt.Sprintf("%s:%d", resp.Address, resp.Ports[0].Port)
-			assignmentGroup.Assignment.Connection = address
-			logger.Infof("gameserver %s connection %s assigned to request, total tickets: %d", resp.GameServerName, assignmentGroup.Assignment.Connection, len(assignmentGroup.TicketIds))
+		if err := SetGameServerAssignment(assignmentGroup, AssignmentFromAllocationResponse(a.Client.Config.Namespace, resp, filter)); err != nil {
+			return err
 		}
+		logger.Infof("gameserver %s connection %s assigned to request, total tickets: %d", resp.GameServerName, assignmentGroup.Assignment.Connection, len(assignmentGroup.TicketIds))
 	}
 
 	return nil
 }
 
+// GameServerSelectorWithCapacity builds the selector for the labels. Counter and List capacity policies are translated to
+// selectors so the Agones Allocator Service only returns GameServers with room for the tickets.
+func GameServerSelectorWithCapacity(labels map[string]string, policy *extpb.CapacityPolicy, required int64) *pb_agones.GameServerSelector {
+	selector := &pb_agones.GameServerSelector{
+		MatchLabels: labels,
+	}
+
+	switch policy.GetSource() {
+	case extpb.CapacityPolicy_COUNTER:
+		selector.Counters = map[string]*pb_agones.CounterSelector{
+			policy.GetKey(): {MinAvailable: required},
+		}
+	case extpb.CapacityPolicy_LIST:
+		selector.Lists = map[string]*pb_agones.ListSelector{
+			policy.GetKey(): {MinAvailable: required},
+		}
+	}
+
+	return selector
+}
+
+// AssignmentFromAllocationResponse builds the gameserver extension from the Agones Allocator Service response
+func AssignmentFromAllocationResponse(namespace string, resp *pb_agones.AllocationResponse, filter *extensions.AllocatorFilterExtension) *extpb.GameServerAssignment {
+	var ports []*extpb.GameServerPort
+	for _, port := range resp.GetPorts() {
+		ports = append(ports, &extpb.GameServerPort{
+			Name: port.GetName(),
+			Port: port.GetPort(),
+		})
+	}
+
+	return &extpb.GameServerAssignment{
+		Name:      resp.GetGameServerName(),
+		Namespace: namespace,
+		Address:   resp.GetAddress(),
+		Ports:     ports,
+		Region:    RegionFromLabels(resp.GetMetadata().GetLabels(), filter),
+	}
+}
+
 func ValueIsEmpty(value string, err error) (bool, error) {
 	if len(value) == 0 {
 		return true, err
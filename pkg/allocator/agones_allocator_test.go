@@ -0,0 +1,30 @@
+package allocator
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGameServerSelectorWithCapacity(t *testing.T) {
+	labels := map[string]string{"region": "us-east-1"}
+
+	t.Run("it should only match labels for the players source", func(t *testing.T) {
+		selector := GameServerSelectorWithCapacity(labels, &extpb.CapacityPolicy{}, 2)
+		require.Equal(t, labels, selector.MatchLabels)
+		require.Nil(t, selector.Counters)
+		require.Nil(t, selector.Lists)
+	})
+
+	t.Run("it should select by counter available capacity", func(t *testing.T) {
+		selector := GameServerSelectorWithCapacity(labels, &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_COUNTER, Key: "players"}, 2)
+		require.EqualValues(t, 2, selector.Counters["players"].MinAvailable)
+		require.Nil(t, selector.Lists)
+	})
+
+	t.Run("it should select by list available capacity", func(t *testing.T) {
+		selector := GameServerSelectorWithCapacity(labels, &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_LIST, Key: "players"}, 3)
+		require.EqualValues(t, 3, selector.Lists["players"].MinAvailable)
+		require.Nil(t, selector.Counters)
+	})
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
 	"github.com/pkg/errors"
 	"open-match.dev/open-match/pkg/pb"
+	"time"
 )
 
 var _ GameSessionAllocatorService = (*AgonesDiscoverAllocator)(nil)
+var _ GameServerLookup = (*AgonesDiscoverAllocator)(nil)
 
 type AgonesDiscoverClient interface {
 	ListGameServers(ctx context.Context, filter map[string]string) ([]byte, error)
@@ -24,7 +27,8 @@ type GameServersResponse struct {
 }
 
 // Allocate will only assign a GameServer to an Assignment if the Capacity (Players.Status.Capacity - Players.Stats.Count)
-// is <= the number of the TicketsIds part of the Assignment
+// is <= the number of the TicketsIds part of the Assignment. The capacity extension of the profile can replace the Players
+// status by an Agones Counter or List. GameServers Reserved by another allocation are skipped.
 func (c *AgonesDiscoverAllocator) Allocate(ctx context.Context, req *pb.AssignTicketsRequest) error {
 	logger := runtime.Logger().WithField("component", "allocator")
 
@@ -33,11 +37,16 @@ func (c *AgonesDiscoverAllocator) Allocate(ctx context.Context, req *pb.AssignTi
 			return err
 		}
 
-		filter, err := extensions.ExtractFilterFromExtensions(assignmentGroup.Assignment.Extensions)
+		filter, err := extensions.GetFilter(assignmentGroup.Assignment)
 		if err != nil {
 			return errors.Wrap(err, "the assignment does not have a valid filter extension")
 		}
 
+		policy, err := extensions.GetCapacityPolicy(assignmentGroup.Assignment)
+		if err != nil {
+			return errors.Wrap(err, "the assignment does not have a valid capacity extension")
+		}
+
 		gameservers, err := c.ListGameServers(ctx, filter)
 		if err != nil {
 			logger.Error(err)
@@ -52,9 +61,23 @@ func (c *AgonesDiscoverAllocator) Allocate(ctx context.Context, req *pb.AssignTi
 		// NiceToHave: Filter GameServers by Capacity and Count
 		// Remove not assigned tickets based on playersCapacity - Count
 		// strategy: allTogether, CapacityBased FallBack
+		now := time.Now()
 		for _, gs := range gameservers {
-			if HasCapacity(assignmentGroup, gs) {
-				assignmentGroup.Assignment.Connection = gs.Status.Address
+			if gs.IsReserved(now) {
+				logger.Debugf("gameserver %s skipped, it is reserved until %s", gs.Name, gs.Status.ReservedUntil)
+				continue
+			}
+
+			if HasCapacity(assignmentGroup, gs, policy) {
+				assignment, err := AssignmentFromGameServer(gs, filter)
+				if err == nil {
+					err = SetGameServerAssignment(assignmentGroup, assignment)
+				}
+
+				if err != nil {
+					logger.Warn(errors.Wrap(err, "gameserver skipped").Error())
+					continue
+				}
 				//logger.Debugf("extension %v", assignmentGroup.Assignment.Extensions)
 				logger.Infof("gameserver %s connection %s assigned to request, total tickets: %d", gs.Name, assignmentGroup.Assignment.Connection, len(assignmentGroup.TicketIds))
 				break
@@ -84,6 +107,23 @@ func (c *AgonesDiscoverAllocator) ListGameServers(ctx context.Context, filter *e
 	return gameservers, nil
 }
 
+// GetGameServer finds the GameServer by name among the ones with the labels of the filter. The fields of the filter are
+// ignored so allocated GameServers are found too. It returns ErrGameServersNotFound if the GameServer is gone.
+func (c *AgonesDiscoverAllocator) GetGameServer(ctx context.Context, name string, filter *extensions.AllocatorFilterExtension) (*GameServer, error) {
+	gameservers, err := c.ListGameServers(ctx, &extensions.AllocatorFilterExtension{Labels: filter.Labels})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gs := range gameservers {
+		if gs.Name == name {
+			return gs, nil
+		}
+	}
+
+	return nil, ErrGameServersNotFound
+}
+
 func (c *AgonesDiscoverAllocator) FindGameServers(ctx context.Context, filters map[string]string) ([]byte, error) {
 	return c.Client.ListGameServers(ctx, filters)
 }
@@ -100,15 +140,80 @@ func IsAssignmentGroupValidForAllocation(group *pb.AssignmentGroup) error {
 	return nil
 }
 
-func HasCapacity(group *pb.AssignmentGroup, gs *GameServer) bool {
-	// Allow any number of users to join the GameServer if the PlayerTracking feature flag is not enabled
-	if gs.Status.Players == nil {
-		return true
+// HasCapacity checks if the GameServer has room for the players of the group using the capacity policy of the profile
+func HasCapacity(group *pb.AssignmentGroup, gs *GameServer, policy *extpb.CapacityPolicy) bool {
+	required := extensions.GetPlayers(group.Assignment, int64(len(group.TicketIds)))
+
+	available, known := AvailableCapacity(gs, policy)
+	if !known {
+		switch policy.GetUnknown() {
+		case extpb.CapacityPolicy_DENY:
+			return false
+		case extpb.CapacityPolicy_ASSUME:
+			return policy.GetAssumedCapacity() >= required
+		default:
+			// Allow any number of users to join the GameServer if the capacity is not tracked
+			return true
+		}
+	}
+
+	return available >= required
+}
+
+// AvailableCapacity returns the room left on the GameServer for the source of the policy.
+// It returns false if the GameServer does not report the capacity for that source.
+func AvailableCapacity(gs *GameServer, policy *extpb.CapacityPolicy) (int64, bool) {
+	if gs.Status == nil {
+		return 0, false
+	}
+
+	switch policy.GetSource() {
+	case extpb.CapacityPolicy_COUNTER:
+		counter, ok := gs.Counter(policy.GetKey())
+		if !ok {
+			return 0, false
+		}
+
+		return counter.Available(), true
+	case extpb.CapacityPolicy_LIST:
+		list, ok := gs.List(policy.GetKey())
+		if !ok {
+			return 0, false
+		}
+
+		return list.Available(), true
+	default:
+		// PlayerTracking is an alpha feature flag. Count and Capacity are zero when it is not set for the GameServer.
+		if gs.Status.Players == nil || (gs.Status.Players.Count == 0 && gs.Status.Players.Capacity == 0) {
+			return 0, false
+		}
+
+		return gs.Status.Players.Capacity - gs.Status.Players.Count, true
+	}
+}
+
+// AssignmentFromGameServer builds the gameserver extension from a GameServer returned by Octops Discover.
+// It returns an error if the GameServer has no status, its address is unknown.
+func AssignmentFromGameServer(gs *GameServer, filter *extensions.AllocatorFilterExtension) (*extpb.GameServerAssignment, error) {
+	if gs.Status == nil {
+		return nil, errors.Errorf("gameserver %s has no status", gs.Name)
+	}
+
+	var ports []*extpb.GameServerPort
+	for _, port := range gs.Status.Ports {
+		ports = append(ports, &extpb.GameServerPort{
+			Name: port.Name,
+			Port: port.Port,
+		})
 	}
 
-	capacity := gs.Status.Players.Capacity - gs.Status.Players.Count
-	// If Count and Capacity are not it should allow allocation. This is just a possible scenario to be reviewed in the future
-	return (capacity >= int64(len(group.TicketIds))) || (gs.Status.Players.Count == 0 && gs.Status.Players.Capacity == 0)
+	return &extpb.GameServerAssignment{
+		Name:      gs.Name,
+		Namespace: gs.Namespace,
+		Address:   gs.Status.Address,
+		Ports:     ports,
+		Region:    RegionFromLabels(gs.Labels, filter),
+	}, nil
 }
 
 func ParseGameServersResponse(resp []byte) ([]*GameServer, error) {
@@ -6,6 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -26,6 +29,9 @@ func TestAgonesDiscoverAllocator_SetConnection(t *testing.T) {
 		},
 	}
 
+	filterExtensions, err := filter.Any()
+	require.NoError(t, err)
+
 	testCases := []struct {
 		name          string
 		filter        *extensions.AllocatorFilterExtension
@@ -44,7 +50,7 @@ func TestAgonesDiscoverAllocator_SetConnection(t *testing.T) {
 							uuid.New().String(),
 						},
 						Assignment: &pb.Assignment{
-							Extensions: filter.Any(),
+							Extensions: filterExtensions,
 						},
 					},
 				},
@@ -63,7 +69,7 @@ func TestAgonesDiscoverAllocator_SetConnection(t *testing.T) {
 							uuid.New().String(),
 						},
 						Assignment: &pb.Assignment{
-							Extensions: filter.Any(),
+							Extensions: filterExtensions,
 						},
 					},
 				},
@@ -93,6 +99,12 @@ func TestAgonesDiscoverAllocator_SetConnection(t *testing.T) {
 			if tc.gameServers > 0 {
 				for _, assignment := range tc.ticketRequest.Assignments {
 					require.NotEmpty(t, assignment.Assignment.Connection)
+
+					got, err := extensions.GameServer.Get(assignment.Assignment)
+					require.NoError(t, err)
+					require.Equal(t, gs[0].Name, got.Name)
+					require.Equal(t, gs[0].Namespace, got.Namespace)
+					require.Equal(t, "us-east-1", got.Region)
 				}
 			} else {
 				for _, assignment := range tc.ticketRequest.Assignments {
@@ -131,6 +143,31 @@ func TestAgonesDiscoverAllocator_Call_FindGameServer(t *testing.T) {
 	})
 }
 
+func TestAgonesDiscoverAllocator_GetGameServer(t *testing.T) {
+	filter := &extensions.AllocatorFilterExtension{
+		Labels: map[string]string{"world": "Dune"},
+		Fields: map[string]string{"status.state": "Ready"},
+	}
+
+	_, resp, err := createGameServersResponse([]*GameServer{{Name: "gs-1"}, {Name: "gs-2"}})
+	require.NoError(t, err)
+
+	client := &mockAgonesDiscoverClient{}
+	client.On("ListGameServers", context.Background(), map[string]string{"labels": "world=Dune", "fields": ""}).Return(resp, nil)
+	discoverAllocator := &AgonesDiscoverAllocator{Client: client}
+
+	t.Run("it should find the gameserver by name ignoring the fields of the filter", func(t *testing.T) {
+		gs, err := discoverAllocator.GetGameServer(context.Background(), "gs-2", filter)
+		require.NoError(t, err)
+		require.Equal(t, "gs-2", gs.Name)
+	})
+
+	t.Run("it should return ErrGameServersNotFound if the gameserver is gone", func(t *testing.T) {
+		_, err := discoverAllocator.GetGameServer(context.Background(), "gs-3", filter)
+		require.Equal(t, ErrGameServersNotFound, err)
+	})
+}
+
 func TestAgonesDiscoverAllocator_Allocate(t *testing.T) {
 	type wantError struct {
 		want bool
@@ -253,7 +290,7 @@ func TestAgonesDiscoverAllocator_Allocate(t *testing.T) {
 				Return(resp, tc.wantErr.err)
 
 			req := &pb.AssignTicketsRequest{
-				Assignments: generateAssignments(tc.assignments, generateTicketsIds(tc.tickets), filter),
+				Assignments: generateAssignments(t, tc.assignments, generateTicketsIds(tc.tickets), filter),
 			}
 
 			err = discoverAllocator.Allocate(context.Background(), req)
@@ -277,14 +314,97 @@ func TestAgonesDiscoverAllocator_Allocate(t *testing.T) {
 	}
 }
 
-func generateAssignments(count int, tickets []string, filter *extensions.AllocatorFilterExtension) []*pb.AssignmentGroup {
+func TestAgonesDiscoverAllocator_Allocate_MissingFilter(t *testing.T) {
+	t.Run("it should return not found error for assignment without filter", func(t *testing.T) {
+		client := &mockAgonesDiscoverClient{}
+		discoverAllocator := &AgonesDiscoverAllocator{
+			Client: client,
+		}
+
+		req := &pb.AssignTicketsRequest{
+			Assignments: []*pb.AssignmentGroup{
+				{
+					TicketIds: generateTicketsIds(1),
+					Assignment: &pb.Assignment{
+						Extensions: map[string]*any.Any{},
+					},
+				},
+			},
+		}
+
+		err := discoverAllocator.Allocate(context.Background(), req)
+		require.Error(t, err)
+		require.True(t, extensions.IsNotFound(err))
+		client.AssertNumberOfCalls(t, "ListGameServers", 0)
+	})
+}
+
+func TestAgonesDiscoverAllocator_Allocate_NilStatus(t *testing.T) {
+	t.Run("it should skip GameServers without status when the unknown capacity is allowed", func(t *testing.T) {
+		filter := &extensions.AllocatorFilterExtension{Labels: map[string]string{"region": "us-east-1"}}
+
+		gameservers := createGameServersWithCapacity(1, 10, 0, map[string]string{})
+		gameservers = append([]*GameServer{{Name: "starting", Namespace: "default"}}, gameservers...)
+		_, resp, err := createGameServersResponse(gameservers)
+		require.NoError(t, err)
+
+		client := &mockAgonesDiscoverClient{}
+		client.On("ListGameServers", context.Background(), filter.Map()).Return(resp, nil)
+
+		req := &pb.AssignTicketsRequest{Assignments: generateAssignments(t, 1, generateTicketsIds(1), filter)}
+		policy := &extpb.CapacityPolicy{Unknown: extpb.CapacityPolicy_ALLOW}
+		require.NoError(t, extensions.Capacity.Set(req.Assignments[0].Assignment, policy))
+
+		discoverAllocator := &AgonesDiscoverAllocator{Client: client}
+		require.NotPanics(t, func() {
+			require.NoError(t, discoverAllocator.Allocate(context.Background(), req))
+		})
+
+		gs, err := extensions.GetGameServer(req.Assignments[0].Assignment)
+		require.NoError(t, err)
+		require.Equal(t, "gameserver-0", gs.GetName())
+		require.NotEmpty(t, req.Assignments[0].Assignment.Connection)
+
+		_, err = AssignmentFromGameServer(&GameServer{Name: "starting"}, filter)
+		require.Error(t, err)
+	})
+}
+
+func TestAgonesDiscoverAllocator_Allocate_Reserved(t *testing.T) {
+	t.Run("it should skip GameServers reserved by another allocation", func(t *testing.T) {
+		filter := &extensions.AllocatorFilterExtension{Labels: map[string]string{"region": "us-east-1"}}
+
+		gameservers := createGameServersWithCapacity(2, 10, 0, map[string]string{})
+		reservedUntil := time.Now().Add(time.Minute)
+		gameservers[0].Status.State = "Reserved"
+		gameservers[0].Status.ReservedUntil = &reservedUntil
+		_, resp, err := createGameServersResponse(gameservers)
+		require.NoError(t, err)
+
+		client := &mockAgonesDiscoverClient{}
+		client.On("ListGameServers", context.Background(), filter.Map()).Return(resp, nil)
+
+		req := &pb.AssignTicketsRequest{Assignments: generateAssignments(t, 1, generateTicketsIds(1), filter)}
+		discoverAllocator := &AgonesDiscoverAllocator{Client: client}
+		require.NoError(t, discoverAllocator.Allocate(context.Background(), req))
+
+		gs, err := extensions.GetGameServer(req.Assignments[0].Assignment)
+		require.NoError(t, err)
+		require.Equal(t, "gameserver-1", gs.GetName())
+	})
+}
+
+func generateAssignments(t *testing.T, count int, tickets []string, filter *extensions.AllocatorFilterExtension) []*pb.AssignmentGroup {
 	var group []*pb.AssignmentGroup
 
+	filterExtensions, err := filter.Any()
+	require.NoError(t, err)
+
 	for i := 0; i < count; i++ {
 		group = append(group, &pb.AssignmentGroup{
 			TicketIds: tickets,
 			Assignment: &pb.Assignment{
-				Extensions: filter.Any(),
+				Extensions: filterExtensions,
 			},
 		})
 	}
@@ -343,6 +463,9 @@ func createRawGameServersWithLabels(count int, labels map[string]string) []byte
 			Status: &GameServerStatus{
 				State:   "Ready",
 				Address: generateAddress(),
+				Ports: []GameServerStatusPort{
+					{Name: "default", Port: int32(rand.Intn(8000-7000) + 7000)},
+				},
 				Players: &PlayerStatus{
 					Count:    int64(rand.Intn(100)),
 					Capacity: 100,
@@ -372,5 +495,68 @@ func generateTicketsIds(count int) []string {
 
 func generateAddress() string {
 	rand.Seed(time.Now().UTC().UnixNano())
-	return fmt.Sprintf("%d.%d.%d.%d:%d", rand.Intn(256), rand.Intn(256), rand.Intn(256), rand.Intn(256), rand.Intn(8000-7000)+7000)
+	return fmt.Sprintf("%d.%d.%d.%d", rand.Intn(256), rand.Intn(256), rand.Intn(256), rand.Intn(256))
+}
+
+func TestHasCapacity(t *testing.T) {
+	players := func(count, capacity int64) *GameServer {
+		return &GameServer{Status: &GameServerStatus{Players: &PlayerStatus{Count: count, Capacity: capacity}}}
+	}
+
+	counter := func(count, capacity int64) *GameServer {
+		return &GameServer{Status: &GameServerStatus{Counters: map[string]CounterStatus{
+			"players": {Count: count, Capacity: capacity},
+		}}}
+	}
+
+	list := func(capacity int64, values ...string) *GameServer {
+		return &GameServer{Status: &GameServerStatus{Lists: map[string]ListStatus{
+			"players": {Capacity: capacity, Values: values},
+		}}}
+	}
+
+	unknown := &GameServer{Status: &GameServerStatus{}}
+
+	playersPolicy := &extpb.CapacityPolicy{}
+	counterPolicy := &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_COUNTER, Key: "players"}
+	listPolicy := &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_LIST, Key: "players"}
+
+	testCases := []struct {
+		name    string
+		gs      *GameServer
+		policy  *extpb.CapacityPolicy
+		tickets int
+		want    bool
+	}{
+		{name: "players: it should allow if there is room", gs: players(8, 10), policy: playersPolicy, tickets: 2, want: true},
+		{name: "players: it should deny if there is no room", gs: players(9, 10), policy: playersPolicy, tickets: 2, want: false},
+		{name: "players: it should allow without player tracking", gs: unknown, policy: playersPolicy, tickets: 100, want: true},
+		{name: "players: it should allow for zero count and capacity", gs: players(0, 0), policy: playersPolicy, tickets: 100, want: true},
+		{name: "players: it should use the default policy for nil policy", gs: players(9, 10), policy: nil, tickets: 2, want: false},
+		{name: "counter: it should allow if there is room", gs: counter(3, 5), policy: counterPolicy, tickets: 2, want: true},
+		{name: "counter: it should deny if there is no room", gs: counter(4, 5), policy: counterPolicy, tickets: 2, want: false},
+		{name: "counter: it should ignore players status", gs: &GameServer{Status: &GameServerStatus{Players: &PlayerStatus{Count: 0, Capacity: 10}, Counters: map[string]CounterStatus{"players": {Count: 10, Capacity: 10}}}}, policy: counterPolicy, tickets: 1, want: false},
+		{name: "list: it should allow if there is room", gs: list(4, "p1", "p2"), policy: listPolicy, tickets: 2, want: true},
+		{name: "list: it should deny if there is no room", gs: list(4, "p1", "p2", "p3"), policy: listPolicy, tickets: 2, want: false},
+		{name: "unknown: it should allow by default", gs: unknown, policy: counterPolicy, tickets: 100, want: true},
+		{name: "unknown: it should deny", gs: unknown, policy: &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_LIST, Key: "players", Unknown: extpb.CapacityPolicy_DENY}, tickets: 1, want: false},
+		{name: "unknown: it should allow up to the assumed capacity", gs: unknown, policy: &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_COUNTER, Key: "players", Unknown: extpb.CapacityPolicy_ASSUME, AssumedCapacity: 4}, tickets: 4, want: true},
+		{name: "unknown: it should deny above the assumed capacity", gs: unknown, policy: &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_COUNTER, Key: "players", Unknown: extpb.CapacityPolicy_ASSUME, AssumedCapacity: 4}, tickets: 5, want: false},
+		{name: "unknown: it should deny GameServers without status", gs: &GameServer{}, policy: &extpb.CapacityPolicy{Unknown: extpb.CapacityPolicy_DENY}, tickets: 1, want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			group := &pb.AssignmentGroup{TicketIds: generateTicketsIds(tc.tickets)}
+			require.Equal(t, tc.want, HasCapacity(group, tc.gs, tc.policy))
+		})
+	}
+
+	t.Run("it should count the players of the party tickets", func(t *testing.T) {
+		group := &pb.AssignmentGroup{TicketIds: generateTicketsIds(2), Assignment: &pb.Assignment{}}
+		require.NoError(t, extensions.Players.Set(group.Assignment, &wrappers.Int64Value{Value: 5}))
+
+		require.False(t, HasCapacity(group, players(6, 10), playersPolicy))
+		require.True(t, HasCapacity(group, players(5, 10), playersPolicy))
+	})
 }
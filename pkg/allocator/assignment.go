@@ -0,0 +1,62 @@
+package allocator
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/pkg/errors"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	RegionLabel = "region"
+)
+
+// SetGameServerAssignment sets the Connection of the group, built from the connection template of the profile, and fills
+// its gameserver extension with the details of the allocated GameServer. The MatchId and Team set by the director are preserved.
+func SetGameServerAssignment(group *pb.AssignmentGroup, gs *extpb.GameServerAssignment) error {
+	template, err := extensions.GetConnectionTemplate(group.Assignment)
+	if err != nil {
+		return errors.Wrap(err, "the assignment does not have a valid connection extension")
+	}
+
+	connection, err := FormatConnection(template, gs.GetAddress(), gs.GetPorts())
+	if err != nil {
+		return errors.Wrapf(err, "gameserver %s", gs.GetName())
+	}
+
+	// Extensions may be shared with the Match and other groups
+	group.Assignment.Extensions = extensions.Clone(group.Assignment.Extensions)
+
+	assignment, err := extensions.GetGameServer(group.Assignment)
+	if err != nil {
+		return errors.Wrap(err, "the assignment does not have a valid gameserver extension")
+	}
+
+	assignment.Name = gs.GetName()
+	assignment.Namespace = gs.GetNamespace()
+	assignment.Address = gs.GetAddress()
+	assignment.Ports = gs.GetPorts()
+	if len(gs.GetRegion()) > 0 {
+		assignment.Region = gs.GetRegion()
+	}
+
+	if err := extensions.GameServer.Set(group.Assignment, assignment); err != nil {
+		return errors.Wrap(err, "failed to set gameserver extension")
+	}
+
+	group.Assignment.Connection = connection
+	return nil
+}
+
+// RegionFromLabels returns the region label of the GameServer falling back to the region label of the filter
+func RegionFromLabels(labels map[string]string, filter *extensions.AllocatorFilterExtension) string {
+	if region, ok := labels[RegionLabel]; ok {
+		return region
+	}
+
+	if filter != nil {
+		return filter.Labels[RegionLabel]
+	}
+
+	return ""
+}
@@ -0,0 +1,68 @@
+package allocator
+
+import (
+	pb_agones "agones.dev/agones/pkg/allocation/go"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestSetGameServerAssignment(t *testing.T) {
+	t.Run("it should keep the match id and not change shared extensions", func(t *testing.T) {
+		shared := &pb.Assignment{}
+		require.NoError(t, extensions.GameServer.Set(shared, &extpb.GameServerAssignment{MatchId: "match-1"}))
+
+		group := &pb.AssignmentGroup{
+			TicketIds:  generateTicketsIds(2),
+			Assignment: &pb.Assignment{Extensions: shared.Extensions},
+		}
+
+		err := SetGameServerAssignment(group, &extpb.GameServerAssignment{
+			Name:      "gameserver-1",
+			Namespace: "default",
+			Address:   "10.0.0.1",
+			Ports:     []*extpb.GameServerPort{{Name: "default", Port: 7000}},
+			Region:    "us-east-1",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "10.0.0.1:7000", group.Assignment.Connection)
+
+		got, err := extensions.GameServer.Get(group.Assignment)
+		require.NoError(t, err)
+		require.Equal(t, "match-1", got.MatchId)
+		require.Equal(t, "gameserver-1", got.Name)
+		require.Equal(t, "default", got.Namespace)
+		require.Equal(t, "us-east-1", got.Region)
+		require.Len(t, got.Ports, 1)
+
+		sharedGot, err := extensions.GameServer.Get(shared)
+		require.NoError(t, err)
+		require.Empty(t, sharedGot.Name)
+	})
+}
+
+func TestAssignmentFromAllocationResponse(t *testing.T) {
+	resp := &pb_agones.AllocationResponse{
+		GameServerName: "gameserver-1",
+		Address:        "10.0.0.1",
+		Ports: []*pb_agones.AllocationResponse_GameServerStatusPort{
+			{Name: "game", Port: 7000},
+			{Name: "query", Port: 7001},
+		},
+	}
+
+	filter := &extensions.AllocatorFilterExtension{
+		Labels: map[string]string{"region": "us-east-2"},
+	}
+
+	got := AssignmentFromAllocationResponse("default", resp, filter)
+	require.Equal(t, "gameserver-1", got.Name)
+	require.Equal(t, "default", got.Namespace)
+	require.Equal(t, "10.0.0.1", got.Address)
+	require.Equal(t, "us-east-2", got.Region)
+	require.Len(t, got.Ports, 2)
+	require.Equal(t, "query", got.Ports[1].Name)
+	require.EqualValues(t, 7001, got.Ports[1].Port)
+}
@@ -0,0 +1,93 @@
+package allocator
+
+import (
+	"encoding/json"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPortNotFound        = errors.New("the GameServer does not expose the port")
+	ErrPlaceholderInvalid  = errors.New("the connection template placeholder is invalid")
+	ErrConnectionIsEmpty   = errors.New("the connection template produced an empty connection")
+	placeholderPattern     = regexp.MustCompile(`\{([a-z]+)(?::([^{}]*))?\}`)
+	placeholderWithoutArgs = map[string]bool{"address": true, "ports": true}
+)
+
+// FormatConnection builds the Assignment Connection for the GameServer from the template.
+// Both allocators call it so the same GameServer always gets the same Connection.
+func FormatConnection(template, address string, ports []*extpb.GameServerPort) (string, error) {
+	var errs []string
+
+	connection := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		parts := placeholderPattern.FindStringSubmatch(placeholder)
+		value, err := placeholderValue(parts[1], parts[2], address, ports)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		return value
+	})
+
+	if len(errs) > 0 {
+		return "", errors.Errorf("failed to format connection %s: %s", template, strings.Join(errs, ","))
+	}
+
+	if len(connection) == 0 {
+		return "", ErrConnectionIsEmpty
+	}
+
+	return connection, nil
+}
+
+// ValidateConnectionTemplate checks that every placeholder of the template is supported
+func ValidateConnectionTemplate(template string) error {
+	for _, parts := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		switch {
+		case parts[1] == "port":
+		case placeholderWithoutArgs[parts[1]] && len(parts[2]) == 0:
+		default:
+			return errors.Wrapf(ErrPlaceholderInvalid, "%s", parts[0])
+		}
+	}
+
+	return nil
+}
+
+func placeholderValue(name, arg, address string, ports []*extpb.GameServerPort) (string, error) {
+	switch {
+	case name == "address" && len(arg) == 0:
+		return address, nil
+	case name == "port" && len(arg) == 0:
+		if len(ports) == 0 {
+			return "", ErrPortNotFound
+		}
+
+		return strconv.Itoa(int(ports[0].GetPort())), nil
+	case name == "port":
+		for _, port := range ports {
+			if port.GetName() == arg {
+				return strconv.Itoa(int(port.GetPort())), nil
+			}
+		}
+
+		return "", errors.Wrapf(ErrPortNotFound, "%s", arg)
+	case name == "ports" && len(arg) == 0:
+		named := map[string]int32{}
+		for _, port := range ports {
+			named[port.GetName()] = port.GetPort()
+		}
+
+		b, err := json.Marshal(named)
+		if err != nil {
+			return "", err
+		}
+
+		return string(b), nil
+	}
+
+	return "", errors.Wrapf(ErrPlaceholderInvalid, "{%s}", strings.TrimSuffix(name+":"+arg, ":"))
+}
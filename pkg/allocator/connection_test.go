@@ -0,0 +1,137 @@
+package allocator
+
+import (
+	pb_agones "agones.dev/agones/pkg/allocation/go"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestFormatConnection(t *testing.T) {
+	ports := []*extpb.GameServerPort{
+		{Name: "game", Port: 7000},
+		{Name: "query", Port: 7001},
+	}
+
+	testCases := []struct {
+		name        string
+		template    string
+		ports       []*extpb.GameServerPort
+		want        string
+		wantErr     bool
+		wantInvalid bool
+	}{
+		{
+			name:     "it should use the first port for the default template",
+			template: extensions.DefaultConnectionTemplate,
+			ports:    ports,
+			want:     "10.0.0.1:7000",
+		},
+		{
+			name:     "it should use the named port",
+			template: "{address}:{port:query}",
+			ports:    ports,
+			want:     "10.0.0.1:7001",
+		},
+		{
+			name:     "it should render all named ports as JSON",
+			template: `{"address":"{address}","ports":{ports}}`,
+			ports:    ports,
+			want:     `{"address":"10.0.0.1","ports":{"game":7000,"query":7001}}`,
+		},
+		{
+			name:     "it should render only the address",
+			template: "{address}",
+			want:     "10.0.0.1",
+		},
+		{
+			name:     "it should return error if the named port does not exist",
+			template: "{address}:{port:voice}",
+			ports:    ports,
+			wantErr:  true,
+		},
+		{
+			name:     "it should return error if the GameServer has no ports",
+			template: extensions.DefaultConnectionTemplate,
+			wantErr:  true,
+		},
+		{
+			name:        "it should return error for unknown placeholders",
+			template:    "{host}:{port}",
+			ports:       ports,
+			wantErr:     true,
+			wantInvalid: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.wantInvalid, ValidateConnectionTemplate(tc.template) != nil)
+
+			got, err := FormatConnection(tc.template, "10.0.0.1", tc.ports)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFormatConnection_SameForBothAllocators(t *testing.T) {
+	templates := []string{
+		extensions.DefaultConnectionTemplate,
+		"{address}:{port:query}",
+		"{ports}",
+	}
+
+	gs := &GameServer{
+		Name:      "gameserver-1",
+		Namespace: "default",
+		Status: &GameServerStatus{
+			Address: "10.0.0.1",
+			Ports: []GameServerStatusPort{
+				{Name: "game", Port: 7000},
+				{Name: "query", Port: 7001},
+			},
+		},
+	}
+
+	resp := &pb_agones.AllocationResponse{
+		GameServerName: "gameserver-1",
+		Address:        "10.0.0.1",
+		Ports: []*pb_agones.AllocationResponse_GameServerStatusPort{
+			{Name: "game", Port: 7000},
+			{Name: "query", Port: 7001},
+		},
+	}
+
+	for _, template := range templates {
+		t.Run(template, func(t *testing.T) {
+			discoverGroup := assignmentGroupWithTemplate(t, template)
+			assignment, err := AssignmentFromGameServer(gs, nil)
+			require.NoError(t, err)
+			require.NoError(t, SetGameServerAssignment(discoverGroup, assignment))
+
+			agonesGroup := assignmentGroupWithTemplate(t, template)
+			require.NoError(t, SetGameServerAssignment(agonesGroup, AssignmentFromAllocationResponse("default", resp, nil)))
+
+			require.NotEmpty(t, discoverGroup.Assignment.Connection)
+			require.Equal(t, discoverGroup.Assignment.Connection, agonesGroup.Assignment.Connection)
+		})
+	}
+}
+
+func assignmentGroupWithTemplate(t *testing.T, template string) *pb.AssignmentGroup {
+	group := &pb.AssignmentGroup{
+		TicketIds:  generateTicketsIds(1),
+		Assignment: &pb.Assignment{},
+	}
+
+	require.NoError(t, extensions.Connection.Set(group.Assignment, &extpb.ConnectionFormat{Template: template}))
+	return group
+}
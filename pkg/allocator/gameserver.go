@@ -1,18 +1,34 @@
 package allocator
 
+import "time"
+
+// GameServer is a GameServer returned by Octops Discover. The metadata fields are named by Octops Discover, i.e.
+// resource_version, the status is the Agones GameServer status with its own names, i.e. nodeName.
 type GameServer struct {
 	UID             string            `json:"uid,omitempty"`
 	Name            string            `json:"name,omitempty"`
 	Namespace       string            `json:"namespace,omitempty"`
 	ResourceVersion string            `json:"resource_version,omitempty"`
 	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
 	Status          *GameServerStatus `json:"status,omitempty"`
 }
 
+// GameServerStatus mirrors the fields of the Agones GameServer status used by the allocators
 type GameServerStatus struct {
-	State   string        `json:"state,omitempty"`
-	Address string        `json:"address,omitempty"`
-	Players *PlayerStatus `json:"players,omitempty"`
+	State         string                   `json:"state,omitempty"`
+	Address       string                   `json:"address,omitempty"`
+	Ports         []GameServerStatusPort   `json:"ports,omitempty"`
+	NodeName      string                   `json:"nodeName,omitempty"`
+	ReservedUntil *time.Time               `json:"reservedUntil,omitempty"`
+	Players       *PlayerStatus            `json:"players,omitempty"`
+	Counters      map[string]CounterStatus `json:"counters,omitempty"`
+	Lists         map[string]ListStatus    `json:"lists,omitempty"`
+}
+
+type GameServerStatusPort struct {
+	Name string `json:"name,omitempty"`
+	Port int32  `json:"port"`
 }
 
 type PlayerStatus struct {
@@ -20,3 +36,54 @@ type PlayerStatus struct {
 	Capacity int64    `json:"capacity"`
 	IDs      []string `json:"ids"`
 }
+
+// CounterStatus is the status of an Agones Counter
+type CounterStatus struct {
+	Count    int64 `json:"count"`
+	Capacity int64 `json:"capacity"`
+}
+
+// ListStatus is the status of an Agones List
+type ListStatus struct {
+	Capacity int64    `json:"capacity"`
+	Values   []string `json:"values"`
+}
+
+// Available returns how much the Counter can still be incremented
+func (c CounterStatus) Available() int64 {
+	return c.Capacity - c.Count
+}
+
+// Available returns how many values can still be added to the List
+func (l ListStatus) Available() int64 {
+	return l.Capacity - int64(len(l.Values))
+}
+
+// Counter returns the Agones Counter with the given name
+func (gs *GameServer) Counter(name string) (CounterStatus, bool) {
+	if gs.Status == nil {
+		return CounterStatus{}, false
+	}
+
+	counter, ok := gs.Status.Counters[name]
+	return counter, ok
+}
+
+// List returns the Agones List with the given name
+func (gs *GameServer) List(name string) (ListStatus, bool) {
+	if gs.Status == nil {
+		return ListStatus{}, false
+	}
+
+	list, ok := gs.Status.Lists[name]
+	return list, ok
+}
+
+// IsReserved returns true if the GameServer is Reserved until a time after now
+func (gs *GameServer) IsReserved(now time.Time) bool {
+	if gs.Status == nil || gs.Status.ReservedUntil == nil {
+		return false
+	}
+
+	return gs.Status.ReservedUntil.After(now)
+}
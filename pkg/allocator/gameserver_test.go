@@ -0,0 +1,120 @@
+package allocator
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseGameServersResponse(t *testing.T) {
+	reservedUntil := time.Date(2020, 10, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		resp    string
+		check   func(t *testing.T, gs *GameServer)
+		wantErr bool
+	}{
+		{
+			name: "it should parse metadata",
+			resp: `{"data":[{"uid":"5b1a","name":"gameserver-1","namespace":"default","resource_version":"123",
+				"labels":{"region":"us-east-1"},"annotations":{"agones.dev/sdk-version":"1.33.0"}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				require.Equal(t, "5b1a", gs.UID)
+				require.Equal(t, "gameserver-1", gs.Name)
+				require.Equal(t, "default", gs.Namespace)
+				require.Equal(t, "123", gs.ResourceVersion)
+				require.Equal(t, map[string]string{"region": "us-east-1"}, gs.Labels)
+				require.Equal(t, map[string]string{"agones.dev/sdk-version": "1.33.0"}, gs.Annotations)
+			},
+		},
+		{
+			name: "it should parse state, address and node name",
+			resp: `{"data":[{"status":{"state":"Ready","address":"10.0.0.1","nodeName":"node-1"}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				require.Equal(t, "Ready", gs.Status.State)
+				require.Equal(t, "10.0.0.1", gs.Status.Address)
+				require.Equal(t, "node-1", gs.Status.NodeName)
+			},
+		},
+		{
+			name: "it should parse ports",
+			resp: `{"data":[{"status":{"ports":[{"name":"game","port":7000},{"name":"query","port":7001}]}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				require.Equal(t, []GameServerStatusPort{{Name: "game", Port: 7000}, {Name: "query", Port: 7001}}, gs.Status.Ports)
+			},
+		},
+		{
+			name: "it should parse reservedUntil",
+			resp: `{"data":[{"status":{"state":"Reserved","reservedUntil":"2020-10-01T10:00:00Z"}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				require.NotNil(t, gs.Status.ReservedUntil)
+				require.True(t, reservedUntil.Equal(*gs.Status.ReservedUntil))
+				require.True(t, gs.IsReserved(reservedUntil.Add(-time.Minute)))
+				require.False(t, gs.IsReserved(reservedUntil.Add(time.Minute)))
+			},
+		},
+		{
+			name: "it should parse players",
+			resp: `{"data":[{"status":{"players":{"count":2,"capacity":10,"ids":["p1","p2"]}}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				require.Equal(t, &PlayerStatus{Count: 2, Capacity: 10, IDs: []string{"p1", "p2"}}, gs.Status.Players)
+			},
+		},
+		{
+			name: "it should parse counters",
+			resp: `{"data":[{"status":{"counters":{"players":{"count":3,"capacity":10}}}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				counter, ok := gs.Counter("players")
+				require.True(t, ok)
+				require.Equal(t, CounterStatus{Count: 3, Capacity: 10}, counter)
+				require.EqualValues(t, 7, counter.Available())
+
+				_, ok = gs.Counter("rooms")
+				require.False(t, ok)
+			},
+		},
+		{
+			name: "it should parse lists",
+			resp: `{"data":[{"status":{"lists":{"players":{"capacity":4,"values":["p1"]}}}}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				list, ok := gs.List("players")
+				require.True(t, ok)
+				require.Equal(t, ListStatus{Capacity: 4, Values: []string{"p1"}}, list)
+				require.EqualValues(t, 3, list.Available())
+
+				_, ok = gs.List("rooms")
+				require.False(t, ok)
+			},
+		},
+		{
+			name: "it should not fail for GameServers without status",
+			resp: `{"data":[{"name":"gameserver-1"}]}`,
+			check: func(t *testing.T, gs *GameServer) {
+				require.Nil(t, gs.Status)
+				_, ok := gs.Counter("players")
+				require.False(t, ok)
+				require.False(t, gs.IsReserved(reservedUntil))
+			},
+		},
+		{
+			name:    "it should return error for invalid response",
+			resp:    `{"data":[`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gameservers, err := ParseGameServersResponse([]byte(tc.resp))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, gameservers, 1)
+			tc.check(t, gameservers[0])
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
 	"github.com/sirupsen/logrus"
 	"open-match.dev/open-match/pkg/pb"
 )
@@ -45,6 +46,11 @@ type GameServerDiscoveryClient interface {
 	FindGameServers(ctx context.Context, filters map[string]string) ([]byte, error)
 }
 
+// GameServerLookup returns the current state of an allocated GameServer. Agones is the source of truth of its capacity.
+type GameServerLookup interface {
+	GetGameServer(ctx context.Context, name string, filter *extensions.AllocatorFilterExtension) (*GameServer, error)
+}
+
 func NewAllocatorService(service GameServerAllocator) *AllocatorService {
 	return &AllocatorService{
 		runtime.Logger().WithField("component", "agones_allocator"),
@@ -3,16 +3,25 @@ package app
 import (
 	"context"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
 	"github.com/Octops/agones-discover-openmatch/pkg/frontend"
 	"github.com/Octops/agones-discover-openmatch/pkg/simulators/players"
 	"github.com/sirupsen/logrus"
+	"os"
+	"time"
 )
 
-func RunPlayerSimulator(logger *logrus.Entry, ctx context.Context, interval string, playersPool int) error {
-	ctx, cancel := context.WithCancel(context.Background())
+// Simulator creates tickets until the context is cancelled
+type Simulator interface {
+	Run(ctx context.Context) error
+}
+
+// RunPlayerSimulator runs the scenario set on the config or, if not set, creates the players pool on every interval
+func RunPlayerSimulator(logger *logrus.Entry, ctx context.Context, omConfig config.OpenMatchConnConfig, simulateConfig config.SimulateConfig) error {
+	ctx, cancel := context.WithCancel(ctx)
 	runtime.SetupSignal(cancel)
 
-	conn, err := frontend.FrontEndConn()
+	conn, err := frontend.FrontEndConn(omConfig)
 	if err != nil {
 		logger.Fatal(err)
 	}
@@ -22,16 +31,70 @@ func RunPlayerSimulator(logger *logrus.Entry, ctx context.Context, interval stri
 		logger.Fatal(err)
 	}
 
-	simulator, err := players.NewTimeIntervalPlayerSimulator(interval, playersPool, feService.CreateTicket)
+	var connect players.ConnectFunc
+	if simulateConfig.Connect {
+		connect = players.DialConnect("tcp", simulateConfig.ConnectTimeout)
+	}
+
+	tracker, err := players.NewAssignmentTracker(feService, simulateConfig.AssignmentMode, simulateConfig.PollInterval, simulateConfig.Patience, connect)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	creator := players.NewTicketCreator(feService.CreateTicket, simulateConfig.Workers, simulateConfig.Rate, simulateConfig.Burst, simulateConfig.RequestTimeout)
+	simulator, err := newSimulator(simulateConfig, creator, tracker)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
+	start := time.Now()
 	if err := simulator.Run(ctx); err != nil {
 		logger.Fatal(err)
 	}
 
 	defer conn.Close()
 
+	// Players still waiting give up when their patience runs out or are timed out on interrupt
+	tracker.Wait()
+	logger.Infof("players summary: %s", tracker.Summary())
+
+	stats := creator.Stats()
+	logger.Infof("tickets summary: created=%d failed=%d", stats.Created, stats.Failed)
+
+	report := players.NewReport(tracker.Results(), time.Since(start))
+	if err := report.WriteTable(os.Stdout); err != nil {
+		logger.Error(err)
+	}
+
+	if len(simulateConfig.ReportFile) > 0 {
+		if err := report.WriteJSONFile(simulateConfig.ReportFile); err != nil {
+			return err
+		}
+		logger.Infof("report written to %s", simulateConfig.ReportFile)
+	}
+
 	return nil
 }
+
+func newSimulator(cfg config.SimulateConfig, creator *players.TicketCreator, tracker *players.AssignmentTracker) (Simulator, error) {
+	if len(cfg.Scenario) == 0 {
+		simulator, err := players.NewTimeIntervalPlayerSimulator(cfg.Interval.String(), cfg.PlayersPool, creator)
+		if err != nil {
+			return nil, err
+		}
+		simulator.Tracker = tracker
+		return simulator, nil
+	}
+
+	scenario, err := players.LoadScenario(cfg.Scenario)
+	if err != nil {
+		return nil, err
+	}
+
+	simulator, err := players.NewScenarioPlayerSimulator(scenario, creator)
+	if err != nil {
+		return nil, err
+	}
+	simulator.Tracker = tracker
+	return simulator, nil
+}
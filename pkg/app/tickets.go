@@ -0,0 +1,44 @@
+package app
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/Octops/agones-discover-openmatch/pkg/frontend"
+	"github.com/Octops/agones-discover-openmatch/pkg/tickets"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// NewTicketsManager connects to the FrontEnd and QueryService. The returned func closes the connections.
+func NewTicketsManager(logger *logrus.Entry, omConfig config.OpenMatchConnConfig, workers int) (*tickets.Manager, func(), error) {
+	creds, err := omConfig.DialOption()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feConn, err := frontend.FrontEndConn(omConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	feService, err := frontend.NewFrontEndService(feConn)
+	if err != nil {
+		feConn.Close()
+		return nil, nil, err
+	}
+
+	logger.Infof("connecting to OpenMatch QueryService: %s (tls: %t)", omConfig.QueryService, omConfig.TLSEnabled)
+	queryConn, err := grpc.Dial(omConfig.QueryService, creds)
+	if err != nil {
+		feConn.Close()
+		return nil, nil, errors.Wrapf(err, "error dialing QueryService on %s", omConfig.QueryService)
+	}
+
+	closeConns := func() {
+		queryConn.Close()
+		feConn.Close()
+	}
+
+	return tickets.NewManager(logger, pb.NewQueryServiceClient(queryConn), feService, workers), closeConns, nil
+}
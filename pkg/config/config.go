@@ -0,0 +1,394 @@
+package config
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// Config is the configuration shared by the director, mmf and simulate commands.
+// Every key can be set on the config file, as an env var, i.e. DIRECTOR_INTERVAL for director.interval, or as a flag.
+type Config struct {
+	Verbose       bool                `mapstructure:"verbose"`
+	OpenMatch     OpenMatchConnConfig `mapstructure:"openmatch"`
+	Director      DirectorConfig      `mapstructure:"director"`
+	MatchFunction MatchFunctionConfig `mapstructure:"mmf"`
+	Simulate      SimulateConfig      `mapstructure:"simulate"`
+}
+
+type DirectorConfig struct {
+	Interval time.Duration `mapstructure:"interval"`
+	// ProfileIntervals overrides the Interval by profile, i.e. world_based_profile_Dune_us-east-1=10s
+	ProfileIntervals   []string              `mapstructure:"profile_intervals"`
+	MaxConcurrency     int                   `mapstructure:"max_concurrency"`
+	Jitter             time.Duration         `mapstructure:"jitter"`
+	FetchTimeout       time.Duration         `mapstructure:"fetch_timeout"`
+	DrainTimeout       time.Duration         `mapstructure:"drain_timeout"`
+	AllocationWorkers  int                   `mapstructure:"allocation_workers"`
+	ConnectionTemplate string                `mapstructure:"connection_template"`
+	Mode               string                `mapstructure:"mode"`
+	Capacity           CapacityConfig        `mapstructure:"capacity"`
+	OctopsDiscoverURL  string                `mapstructure:"octops_discover_url" redact:"url"`
+	AgonesAllocator    AgonesAllocatorConfig `mapstructure:"agones_allocator"`
+	Election           ElectionConfig        `mapstructure:"election"`
+	Backfill           BackfillConfig        `mapstructure:"backfill"`
+}
+
+type CapacityConfig struct {
+	Source  string `mapstructure:"source"`
+	Key     string `mapstructure:"key"`
+	Unknown string `mapstructure:"unknown"`
+	Assume  int64  `mapstructure:"assume"`
+}
+
+type AgonesAllocatorConfig struct {
+	KeyFile      string `mapstructure:"key_file" redact:"true"`
+	CertFile     string `mapstructure:"cert_file"`
+	CaCertFile   string `mapstructure:"ca_cert_file"`
+	Host         string `mapstructure:"host"`
+	Port         int    `mapstructure:"port"`
+	Namespace    string `mapstructure:"namespace"`
+	MultiCluster bool   `mapstructure:"multicluster"`
+}
+
+// ElectionConfig sets how multiple director replicas split the profiles
+type ElectionConfig struct {
+	// Mode is none, leader or shard
+	Mode   string `mapstructure:"mode"`
+	Shards int    `mapstructure:"shards"`
+	// Backend of the lock, kubernetes or memory. Memory only works for a single process.
+	Backend   string `mapstructure:"backend"`
+	LeaseName string `mapstructure:"lease_name"`
+	// Namespace of the lease, the pod namespace is used if empty
+	Namespace string `mapstructure:"namespace"`
+	// Identity of the replica, the hostname is used if empty
+	Identity      string        `mapstructure:"identity"`
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+	// RenewDeadline is the time a replica keeps its shard without renewing the lease, it must be shorter than the lease
+	RenewDeadline time.Duration `mapstructure:"renew_deadline"`
+	RetryPeriod   time.Duration `mapstructure:"retry_period"`
+}
+
+// BackfillConfig sets how the director keeps the backfills of the GameServers with free slots.
+// It requires the discover mode, Octops Discover reports the capacity of the allocated GameServers.
+type BackfillConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval between the updates of the open slots, it must be shorter than the Open Match backfill lock timeout
+	Interval time.Duration `mapstructure:"interval"`
+	// PendingTimeout is the time the players assigned to a backfill have to be counted by Agones
+	PendingTimeout time.Duration `mapstructure:"pending_timeout"`
+}
+
+// MatchFunctionConfig sets the gRPC server of the mmf command
+type MatchFunctionConfig struct {
+	// GracefulStopTimeout is the time the Run streams in progress have to finish on shutdown
+	GracefulStopTimeout time.Duration `mapstructure:"graceful_stop_timeout"`
+	// MaxRecvMsgSize and MaxSendMsgSize are in bytes, zero uses the gRPC defaults
+	MaxRecvMsgSize int             `mapstructure:"max_recv_msg_size"`
+	MaxSendMsgSize int             `mapstructure:"max_send_msg_size"`
+	Reflection     bool            `mapstructure:"reflection"`
+	Keepalive      KeepaliveConfig `mapstructure:"keepalive"`
+	// Backfill matches tickets into the backfills of the GameServers with open slots and creates backfills for new matches with room left
+	Backfill bool `mapstructure:"backfill"`
+	// Function is the name of the registered match function, empty uses backfill if Backfill is set and player_capacity otherwise
+	Function string `mapstructure:"function"`
+	// PlayerCapacity is the player capacity of the GameServers passed to the match function
+	PlayerCapacity int `mapstructure:"player_capacity"`
+	// RecordDir turns on the recording mode, the profile and pools of every Run are written to a file on it for the replay command
+	RecordDir string `mapstructure:"record_dir"`
+}
+
+// FunctionName returns the name of the registered match function to serve
+func (m MatchFunctionConfig) FunctionName() string {
+	if len(m.Function) > 0 {
+		return m.Function
+	}
+
+	if m.Backfill {
+		return "backfill"
+	}
+
+	return "player_capacity"
+}
+
+// KeepaliveConfig sets the keepalive pings of the server and the pings accepted from the clients
+type KeepaliveConfig struct {
+	// Time is the idle time before the server pings the client
+	Time time.Duration `mapstructure:"time"`
+	// Timeout is the time the server waits for the ping ack before closing the connection
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MinTime is the shortest interval between client pings, faster clients are disconnected
+	MinTime             time.Duration `mapstructure:"min_time"`
+	PermitWithoutStream bool          `mapstructure:"permit_without_stream"`
+}
+
+type SimulateConfig struct {
+	Interval    time.Duration `mapstructure:"interval"`
+	PlayersPool int           `mapstructure:"players_pool"`
+	// Scenario is the file of the scenario to run. Interval and PlayersPool are ignored when it is set.
+	Scenario string `mapstructure:"scenario"`
+	// Patience is how long a player waits for a match before deleting the ticket, zero waits until interrupted
+	Patience time.Duration `mapstructure:"patience"`
+	// AssignmentMode is watch to stream the assignments or poll to get the tickets every PollInterval
+	AssignmentMode string        `mapstructure:"assignment_mode"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	// Connect dials the address of the assignment once the player is matched
+	Connect        bool          `mapstructure:"connect"`
+	ConnectTimeout time.Duration `mapstructure:"connect_timeout"`
+	// ReportFile is the file the JSON report is written to at the end of the simulation, empty only prints the table
+	ReportFile string `mapstructure:"report_file"`
+	// Workers is the number of tickets created concurrently
+	Workers int `mapstructure:"workers"`
+	// Rate is the limit of tickets created per second, zero is unlimited. Burst tickets can be created at once.
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+	// RequestTimeout is the timeout of every CreateTicket call
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+}
+
+// Default returns the values used when a key is not set by any source
+func Default() *Config {
+	return &Config{
+		OpenMatch: OpenMatchConnConfig{
+			MatchFunctionPort: 8091,
+		},
+		Director: DirectorConfig{
+			Interval:           5 * time.Second,
+			FetchTimeout:       time.Second,
+			DrainTimeout:       10 * time.Second,
+			AllocationWorkers:  4,
+			ConnectionTemplate: extensions.DefaultConnectionTemplate,
+			Mode:               "discover",
+			Capacity: CapacityConfig{
+				Source:  "players",
+				Unknown: "allow",
+			},
+			OctopsDiscoverURL: "http://localhost:8081",
+			AgonesAllocator: AgonesAllocatorConfig{
+				Host:      "0.0.0.0",
+				Port:      443,
+				Namespace: "default",
+			},
+			Election: ElectionConfig{
+				Mode:          "none",
+				Shards:        1,
+				Backend:       "kubernetes",
+				LeaseName:     "agones-openmatch-director",
+				LeaseDuration: 15 * time.Second,
+				RenewDeadline: 10 * time.Second,
+				RetryPeriod:   2 * time.Second,
+			},
+			Backfill: BackfillConfig{
+				Interval:       5 * time.Second,
+				PendingTimeout: 30 * time.Second,
+			},
+		},
+		MatchFunction: MatchFunctionConfig{
+			GracefulStopTimeout: 10 * time.Second,
+			PlayerCapacity:      10,
+			Keepalive: KeepaliveConfig{
+				Time:                30 * time.Second,
+				Timeout:             10 * time.Second,
+				MinTime:             10 * time.Second,
+				PermitWithoutStream: true,
+			},
+		},
+		Simulate: SimulateConfig{
+			Interval:       5 * time.Second,
+			PlayersPool:    10,
+			Patience:       60 * time.Second,
+			AssignmentMode: "watch",
+			PollInterval:   time.Second,
+			ConnectTimeout: 2 * time.Second,
+			Workers:        4,
+			Burst:          1,
+			RequestTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// ValidateDirector checks the settings used by the director command
+func (c *Config) ValidateDirector() error {
+	if err := c.OpenMatch.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.OpenMatch.BackEnd) == 0 {
+		return errors.New("openmatch.backend_addr is required")
+	}
+
+	if len(c.OpenMatch.MatchFunctionHost) == 0 || c.OpenMatch.MatchFunctionPort <= 0 {
+		return errors.New("openmatch.match_function_host and openmatch.match_function_port are required")
+	}
+
+	d := c.Director
+	if d.Interval <= 0 {
+		return errors.New("director.interval must be higher than zero")
+	}
+
+	if d.MaxConcurrency < 0 || d.Jitter < 0 || d.FetchTimeout < 0 || d.DrainTimeout < 0 {
+		return errors.New("director.max_concurrency, director.jitter, director.fetch_timeout and director.drain_timeout can't be lower than zero")
+	}
+
+	if d.AllocationWorkers < 1 {
+		return errors.New("director.allocation_workers must be at least 1")
+	}
+
+	if err := d.Election.Validate(); err != nil {
+		return err
+	}
+
+	switch d.Mode {
+	case "discover":
+		if len(d.OctopsDiscoverURL) == 0 {
+			return errors.New("director.octops_discover_url is required on discover mode")
+		}
+	case "agones":
+		if len(d.AgonesAllocator.Host) == 0 || d.AgonesAllocator.Port <= 0 {
+			return errors.New("director.agones_allocator.host and director.agones_allocator.port are required on agones mode")
+		}
+	default:
+		return errors.Errorf("director.mode %q is invalid, it should be discover or agones", d.Mode)
+	}
+
+	if d.Backfill.Enabled {
+		if d.Mode != "discover" {
+			return errors.New("director.backfill.enabled requires the discover mode")
+		}
+
+		if len(c.OpenMatch.FrontEnd) == 0 {
+			return errors.New("openmatch.frontend_addr is required when director.backfill.enabled is set")
+		}
+
+		if d.Backfill.Interval <= 0 {
+			return errors.New("director.backfill.interval must be higher than zero")
+		}
+
+		if d.Backfill.PendingTimeout <= 0 {
+			return errors.New("director.backfill.pending_timeout must be higher than zero")
+		}
+	}
+
+	return nil
+}
+
+func (e ElectionConfig) Validate() error {
+	switch e.Mode {
+	case "none":
+		return nil
+	case "leader", "shard":
+	default:
+		return errors.Errorf("director.election.mode %q is invalid, it should be none, leader or shard", e.Mode)
+	}
+
+	if e.Mode == "shard" && e.Shards < 1 {
+		return errors.New("director.election.shards must be at least 1")
+	}
+
+	if e.Backend != "kubernetes" && e.Backend != "memory" {
+		return errors.Errorf("director.election.backend %q is invalid, it should be kubernetes or memory", e.Backend)
+	}
+
+	if len(e.LeaseName) == 0 {
+		return errors.New("director.election.lease_name is required")
+	}
+
+	if e.RenewDeadline <= 0 || e.RenewDeadline >= e.LeaseDuration {
+		return errors.New("director.election.renew_deadline must be higher than zero and lower than director.election.lease_duration")
+	}
+
+	if e.RetryPeriod <= 0 || e.RetryPeriod >= e.RenewDeadline {
+		return errors.New("director.election.retry_period must be higher than zero and lower than director.election.renew_deadline")
+	}
+
+	return nil
+}
+
+// ValidateMatchFunction checks the settings used by the mmf command
+func (c *Config) ValidateMatchFunction() error {
+	if err := c.OpenMatch.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.OpenMatch.QueryService) == 0 {
+		return errors.New("openmatch.query_service_addr is required")
+	}
+
+	if c.OpenMatch.MatchFunctionPort <= 0 {
+		return errors.New("openmatch.match_function_port must be higher than zero")
+	}
+
+	m := c.MatchFunction
+	if m.GracefulStopTimeout < 0 || m.MaxRecvMsgSize < 0 || m.MaxSendMsgSize < 0 {
+		return errors.New("mmf.graceful_stop_timeout, mmf.max_recv_msg_size and mmf.max_send_msg_size can't be lower than zero")
+	}
+
+	if m.Keepalive.Time < 0 || m.Keepalive.Timeout < 0 || m.Keepalive.MinTime < 0 {
+		return errors.New("mmf.keepalive.time, mmf.keepalive.timeout and mmf.keepalive.min_time can't be lower than zero")
+	}
+
+	if m.PlayerCapacity <= 0 {
+		return errors.New("mmf.player_capacity must be higher than zero")
+	}
+
+	return nil
+}
+
+// ValidateTickets checks the settings used by the tickets commands
+func (c *Config) ValidateTickets() error {
+	if err := c.OpenMatch.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.OpenMatch.FrontEnd) == 0 || len(c.OpenMatch.QueryService) == 0 {
+		return errors.New("openmatch.frontend_addr and openmatch.query_service_addr are required")
+	}
+
+	return nil
+}
+
+// ValidateSimulate checks the settings used by the player simulate command
+func (c *Config) ValidateSimulate() error {
+	if err := c.OpenMatch.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.OpenMatch.FrontEnd) == 0 {
+		return errors.New("openmatch.frontend_addr is required")
+	}
+
+	s := c.Simulate
+	if s.AssignmentMode != "watch" && s.AssignmentMode != "poll" {
+		return errors.Errorf("simulate.assignment_mode %q is invalid, it should be watch or poll", s.AssignmentMode)
+	}
+
+	if s.Patience < 0 {
+		return errors.New("simulate.patience can't be lower than zero")
+	}
+
+	if s.PollInterval <= 0 || s.ConnectTimeout <= 0 {
+		return errors.New("simulate.poll_interval and simulate.connect_timeout must be higher than zero")
+	}
+
+	if s.Workers <= 0 || s.Burst <= 0 || s.RequestTimeout <= 0 {
+		return errors.New("simulate.workers, simulate.burst and simulate.request_timeout must be higher than zero")
+	}
+
+	if s.Rate < 0 {
+		return errors.New("simulate.rate can't be lower than zero")
+	}
+
+	if len(c.Simulate.Scenario) > 0 {
+		return nil
+	}
+
+	if c.Simulate.Interval <= 0 {
+		return errors.New("simulate.interval must be higher than zero")
+	}
+
+	if c.Simulate.PlayersPool <= 0 {
+		return errors.New("simulate.players_pool must be higher than zero")
+	}
+
+	return nil
+}
@@ -1,24 +1,103 @@
 package config
 
 import (
-	"github.com/kelseyhightower/envconfig"
-	"log"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 )
 
+// OpenMatchConnConfig holds the addresses of the Open Match services and the TLS settings used to connect to them.
+// The keys are read from the openmatch section of the config file and from the OPENMATCH_* env vars.
 type OpenMatchConnConfig struct {
-	FrontEnd          string `envconfig:"frontend_addr"`
-	BackEnd           string `envconfig:"backend_addr"`
-	QueryService      string `envconfig:"query_service_addr"`
-	MatchFunctionHost string `envconfig:"match_function_host"`
-	MatchFunctionPort int32  `envconfig:"match_function_port"`
+	FrontEnd          string `mapstructure:"frontend_addr"`
+	BackEnd           string `mapstructure:"backend_addr"`
+	QueryService      string `mapstructure:"query_service_addr"`
+	MatchFunctionHost string `mapstructure:"match_function_host"`
+	MatchFunctionPort int32  `mapstructure:"match_function_port"`
+
+	// TLSEnabled turns on TLS for the Frontend, Backend and QueryService connections
+	TLSEnabled bool `mapstructure:"tls_enabled"`
+	// TLSCaCertFile is the CA used to verify the Open Match services. The system pool is used if empty.
+	TLSCaCertFile string `mapstructure:"tls_ca_cert_file"`
+	// TLSCertFile and TLSKeyFile are the client certificate presented to Open Match for mTLS
+	TLSCertFile   string `mapstructure:"tls_cert_file"`
+	TLSKeyFile    string `mapstructure:"tls_key_file" redact:"true"`
+	TLSServerName string `mapstructure:"tls_server_name"`
+
+	// MatchFunctionTLSCertFile and MatchFunctionTLSKeyFile turn on TLS for the match function listener
+	MatchFunctionTLSCertFile string `mapstructure:"match_function_tls_cert_file"`
+	MatchFunctionTLSKeyFile  string `mapstructure:"match_function_tls_key_file" redact:"true"`
+	// MatchFunctionTLSClientCAFile makes the match function listener require client certificates signed by this CA
+	MatchFunctionTLSClientCAFile string `mapstructure:"match_function_tls_client_ca_file"`
+}
+
+// Validate checks the TLS settings. The addresses are checked by the commands that use them.
+func (c OpenMatchConnConfig) Validate() error {
+	if len(c.TLSCertFile) > 0 != (len(c.TLSKeyFile) > 0) {
+		return errors.New("openmatch.tls_cert_file and openmatch.tls_key_file must be set together")
+	}
+
+	if len(c.MatchFunctionTLSCertFile) > 0 != (len(c.MatchFunctionTLSKeyFile) > 0) {
+		return errors.New("openmatch.match_function_tls_cert_file and openmatch.match_function_tls_key_file must be set together")
+	}
+
+	if len(c.MatchFunctionTLSClientCAFile) > 0 && len(c.MatchFunctionTLSCertFile) == 0 {
+		return errors.New("openmatch.match_function_tls_client_ca_file requires the match function TLS certificate")
+	}
+
+	return nil
 }
 
-func OpenMatch() OpenMatchConnConfig {
-	var config OpenMatchConnConfig
-	err := envconfig.Process("openmatch", &config)
+// DialOption returns the transport credentials used to connect to the Open Match services
+func (c OpenMatchConnConfig) DialOption() (grpc.DialOption, error) {
+	if !c.TLSEnabled {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+
+	tlsConfig, err := ClientTLSConfig(c.TLSCaCertFile, c.TLSCertFile, c.TLSKeyFile, c.TLSServerName)
 	if err != nil {
-		log.Fatal(err.Error())
+		return nil, errors.Wrap(err, "failed to load Open Match client TLS config")
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
+
+// MatchFunctionServerOptions returns the options of the match function gRPC server. No option is returned if TLS is not set.
+func (c OpenMatchConnConfig) MatchFunctionServerOptions() ([]grpc.ServerOption, error) {
+	if len(c.MatchFunctionTLSCertFile) == 0 && len(c.MatchFunctionTLSKeyFile) == 0 {
+		return nil, nil
+	}
+
+	tlsConfig, err := ServerTLSConfig(c.MatchFunctionTLSCertFile, c.MatchFunctionTLSKeyFile, c.MatchFunctionTLSClientCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load match function server TLS config")
+	}
+
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}, nil
+}
+
+// ServerOptions returns the keepalive and message size options of the match function gRPC server
+func (c MatchFunctionConfig) ServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    c.Keepalive.Time,
+			Timeout: c.Keepalive.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.Keepalive.MinTime,
+			PermitWithoutStream: c.Keepalive.PermitWithoutStream,
+		}),
+	}
+
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
 	}
 
-	return config
+	return opts
 }
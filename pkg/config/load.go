@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultConfigName is the config file searched on the home directory when no file is set
+	DefaultConfigName = ".agones-discover-openmatch"
+
+	redacted = "<redacted>"
+)
+
+// Load reads the config file, then the env vars and then the flags. A source overrides the values set by the previous ones.
+// Bindings maps config keys to flag names, i.e. {"director.interval": "interval"}. Only the flags set by the user override the other sources.
+// If the file is empty the DefaultConfigName is searched on the home directory and ignored if it doesn't exist.
+func Load(file string, flags *pflag.FlagSet, bindings map[string]string) (*Config, error) {
+	v := viper.New()
+
+	walk(Default(), func(key string, field reflect.StructField, value reflect.Value) {
+		v.SetDefault(key, value.Interface())
+	})
+
+	if err := readConfigFile(v, file); err != nil {
+		return nil, err
+	}
+
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for key, name := range bindings {
+		flag := flags.Lookup(name)
+		if flag == nil {
+			return nil, errors.Errorf("flag %s bound to %s does not exist", name, key)
+		}
+
+		if err := v.BindPFlag(key, flag); err != nil {
+			return nil, errors.Wrapf(err, "failed to bind flag %s", name)
+		}
+	}
+
+	config := &Config{}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, errors.Wrap(err, "failed to parse config")
+	}
+
+	return config, nil
+}
+
+func readConfigFile(v *viper.Viper, file string) error {
+	if len(file) > 0 {
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return errors.Wrapf(err, "failed to read config file %s", file)
+		}
+
+		return nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil
+	}
+
+	v.AddConfigPath(home)
+	v.SetConfigName(DefaultConfigName)
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read config file %s", v.ConfigFileUsed())
+	}
+
+	return nil
+}
+
+// Print writes the config as YAML. Secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	out := map[string]interface{}{}
+
+	walk(c, func(key string, field reflect.StructField, value reflect.Value) {
+		parts := strings.Split(key, ".")
+		section := out
+		for _, p := range parts[:len(parts)-1] {
+			if _, ok := section[p]; !ok {
+				section[p] = map[string]interface{}{}
+			}
+			section = section[p].(map[string]interface{})
+		}
+
+		section[parts[len(parts)-1]] = printable(field, value)
+	})
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(out); err != nil {
+		return errors.Wrap(err, "failed to print config")
+	}
+
+	return encoder.Close()
+}
+
+func printable(field reflect.StructField, value reflect.Value) interface{} {
+	switch field.Tag.Get("redact") {
+	case "true":
+		if !value.IsZero() {
+			return redacted
+		}
+	case "url":
+		if u, err := url.Parse(value.String()); err == nil {
+			return u.Redacted()
+		}
+	}
+
+	if d, ok := value.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	return value.Interface()
+}
+
+// walk calls fn for every leaf field of the config with its dotted mapstructure key
+func walk(config interface{}, fn func(key string, field reflect.StructField, value reflect.Value)) {
+	walkStruct("", reflect.Indirect(reflect.ValueOf(config)), fn)
+}
+
+func walkStruct(prefix string, v reflect.Value, fn func(key string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if len(name) == 0 {
+			name = strings.ToLower(field.Name)
+		}
+
+		key := name
+		if len(prefix) > 0 {
+			key = fmt.Sprintf("%s.%s", prefix, name)
+		}
+
+		value := v.Field(i)
+		if value.Kind() == reflect.Struct {
+			walkStruct(key, value, fn)
+			continue
+		}
+
+		fn(key, field, value)
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"os"
+)
+
+// ClientTLSConfig builds the TLS config of a gRPC client. The CA is optional, the system pool is used if it is empty.
+// The client certificate is only presented, for mTLS, if both the cert and key files are set.
+func ClientTLSConfig(caCertFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if len(caCertFile) > 0 {
+		pool, err := loadCertPool(caCertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(certFile) > 0 || len(keyFile) > 0 {
+		cert, err := loadKeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// ServerTLSConfig builds the TLS config of a gRPC server. If the client CA is set clients must present a certificate signed by it.
+func ServerTLSConfig(certFile, keyFile, clientCaCertFile string) (*tls.Config, error) {
+	cert, err := loadKeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if len(clientCaCertFile) > 0 {
+		pool, err := loadCertPool(clientCaCertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
+func loadKeyPair(certFile, keyFile string) (tls.Certificate, error) {
+	if len(certFile) == 0 || len(keyFile) == 0 {
+		return tls.Certificate{}, errors.New("both the certificate and the key files must be set")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, errors.Wrapf(err, "failed to load key pair %s and %s", certFile, keyFile)
+	}
+
+	return cert, nil
+}
+
+func loadCertPool(caCertFile string) (*x509.CertPool, error) {
+	ca, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", caCertFile)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.Errorf("only PEM format is accepted for CA %s", caCertFile)
+	}
+
+	return pool, nil
+}
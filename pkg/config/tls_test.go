@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenMatchConnConfig_TLS(t *testing.T) {
+	certs := generateCertificates(t)
+
+	testCases := []struct {
+		name    string
+		server  OpenMatchConnConfig
+		client  OpenMatchConnConfig
+		wantErr bool
+	}{
+		{
+			name: "it should connect with TLS",
+			server: OpenMatchConnConfig{
+				MatchFunctionTLSCertFile: certs.serverCert,
+				MatchFunctionTLSKeyFile:  certs.serverKey,
+			},
+			client: OpenMatchConnConfig{
+				TLSEnabled:    true,
+				TLSCaCertFile: certs.ca,
+			},
+		},
+		{
+			name: "it should connect with mTLS",
+			server: OpenMatchConnConfig{
+				MatchFunctionTLSCertFile:     certs.serverCert,
+				MatchFunctionTLSKeyFile:      certs.serverKey,
+				MatchFunctionTLSClientCAFile: certs.ca,
+			},
+			client: OpenMatchConnConfig{
+				TLSEnabled:    true,
+				TLSCaCertFile: certs.ca,
+				TLSCertFile:   certs.clientCert,
+				TLSKeyFile:    certs.clientKey,
+			},
+		},
+		{
+			name: "it should fail if the client does not present a certificate for mTLS",
+			server: OpenMatchConnConfig{
+				MatchFunctionTLSCertFile:     certs.serverCert,
+				MatchFunctionTLSKeyFile:      certs.serverKey,
+				MatchFunctionTLSClientCAFile: certs.ca,
+			},
+			client: OpenMatchConnConfig{
+				TLSEnabled:    true,
+				TLSCaCertFile: certs.ca,
+			},
+			wantErr: true,
+		},
+		{
+			name: "it should fail if the client does not trust the server CA",
+			server: OpenMatchConnConfig{
+				MatchFunctionTLSCertFile: certs.serverCert,
+				MatchFunctionTLSKeyFile:  certs.serverKey,
+			},
+			client: OpenMatchConnConfig{
+				TLSEnabled: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "it should fail if the client does not use TLS",
+			server: OpenMatchConnConfig{
+				MatchFunctionTLSCertFile: certs.serverCert,
+				MatchFunctionTLSKeyFile:  certs.serverKey,
+			},
+			client:  OpenMatchConnConfig{},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := tc.server.MatchFunctionServerOptions()
+			require.NoError(t, err)
+			require.Len(t, opts, 1)
+
+			addr := serveHealth(t, opts...)
+
+			creds, err := tc.client.DialOption()
+			require.NoError(t, err)
+
+			conn, err := grpc.Dial(addr, creds)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestOpenMatchConnConfig_MatchFunctionServerOptions(t *testing.T) {
+	opts, err := OpenMatchConnConfig{}.MatchFunctionServerOptions()
+	require.NoError(t, err)
+	require.Empty(t, opts)
+
+	_, err = OpenMatchConnConfig{MatchFunctionTLSCertFile: "cert.pem"}.MatchFunctionServerOptions()
+	require.Error(t, err)
+}
+
+func TestClientTLSConfig(t *testing.T) {
+	certs := generateCertificates(t)
+
+	tlsConfig, err := ClientTLSConfig(certs.ca, certs.clientCert, certs.clientKey, "openmatch")
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig.RootCAs)
+	require.Len(t, tlsConfig.Certificates, 1)
+	require.Equal(t, "openmatch", tlsConfig.ServerName)
+
+	_, err = ClientTLSConfig(certs.ca, certs.clientCert, "", "")
+	require.Error(t, err)
+
+	_, err = ClientTLSConfig(certs.clientKey, "", "", "")
+	require.Error(t, err)
+}
+
+type certificates struct {
+	ca         string
+	serverCert string
+	serverKey  string
+	clientCert string
+	clientKey  string
+}
+
+func serveHealth(t *testing.T, opts ...grpc.ServerOption) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(opts...)
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+
+	return ln.Addr().String()
+}
+
+// generateCertificates writes a self-signed CA and a server and client certificates signed by it
+func generateCertificates(t *testing.T) certificates {
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	certs := certificates{ca: writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER)}
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			DNSNames:     []string{"localhost"},
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+
+		return writePEM(t, dir, name+".pem", "CERTIFICATE", der), writePEM(t, dir, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+	}
+
+	certs.serverCert, certs.serverKey = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	certs.clientCert, certs.clientKey = issue("client", 3, x509.ExtKeyUsageClientAuth)
+
+	return certs
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	require.NoError(t, err)
+
+	return path
+}
@@ -4,66 +4,368 @@ import (
 	"context"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"math/rand"
 	"open-match.dev/open-match/pkg/pb"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type GenerateProfilesFunc func() ([]*pb.MatchProfile, error)
 
-type FetchMatchesFunc func(ctx context.Context, profile *pb.MatchProfile) ([]*pb.Match, error)
+// FetchMatchesFunc sends the matches of the profile to the channel as they are fetched. It must not close the channel.
+type FetchMatchesFunc func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error
 
-type AssignFunc func(ctx context.Context, matches []*pb.Match) error
+// AssignFunc assigns the matches received from the channel until it is closed
+type AssignFunc func(ctx context.Context, matches <-chan *pb.Match) error
 
 type DirectorFunc func(ctx context.Context, profilesFunc GenerateProfilesFunc, matchesFunc FetchMatchesFunc, assignFunc AssignFunc) error
 
+// OverrunFunc is called when a profile tick is skipped because the previous run of the same profile is still running
+type OverrunFunc func(profile string)
+
+// States of the run of a profile
+const (
+	profileIdle int32 = iota
+	// profileWaiting runs are waiting for a MaxConcurrency slot
+	profileWaiting
+	profileRunning
+)
+
+// DrainFunc is called once the in-flight runs are drained after the context is cancelled
+type DrainFunc func(report DrainReport)
+
+// defaultDrainTimeout is used when the Scheduler has no DrainTimeout set
+const defaultDrainTimeout = time.Second
+
+// drainGracePeriod is the time runs have to release their tickets once the drain deadline is reached
+const drainGracePeriod = time.Second
+
+type drainDeadlineKey struct{}
+
+// WithDrainDeadline bounds the drain of the Scheduler running with the context, i.e. by the expiry of the lease of an
+// elected replica. The deadline is read when the drain starts and overrides a longer DrainTimeout.
+func WithDrainDeadline(ctx context.Context, deadline func() time.Time) context.Context {
+	return context.WithValue(ctx, drainDeadlineKey{}, deadline)
+}
+
+// DrainDeadline returns the current drain deadline of the context set with WithDrainDeadline
+func DrainDeadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(drainDeadlineKey{}).(func() time.Time)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return deadline(), true
+}
+
+// DrainReport is the outcome of the matches in flight when the director stopped
+type DrainReport struct {
+	// Completed matches were assigned during the drain
+	Completed int64 `json:"completed"`
+	// Released matches failed during the drain and had their tickets released
+	Released int64 `json:"released"`
+	// Abandoned matches were fetched but not handled before the drain deadline.
+	// Their tickets return to the pool after the Open Match pending release timeout.
+	Abandoned int64 `json:"abandoned"`
+	// TimedOut is true if the in-flight runs didn't finish before the drain deadline
+	TimedOut bool `json:"timed_out"`
+}
+
+// Scheduler runs fetch and assign for every profile on its own interval.
+// A slow profile only skips its own ticks, other profiles keep running on time.
+type Scheduler struct {
+	// Interval between runs of the profiles without an entry on ProfileIntervals
+	Interval time.Duration
+	// ProfileIntervals overrides the Interval by profile name
+	ProfileIntervals map[string]time.Duration
+	// MaxConcurrency bounds the number of profiles running at the same time. Zero means unbounded.
+	MaxConcurrency int
+	// Jitter is the upper bound of the random delay added to the first run of each profile
+	Jitter time.Duration
+	// OnOverrun is called for every skipped tick
+	OnOverrun OverrunFunc
+	// DrainTimeout bounds the time in-flight runs have to assign their matches after the context is cancelled.
+	// Fetching stops right away. Zero uses one second.
+	DrainTimeout time.Duration
+	// OnDrain is called with the report of the drain
+	OnDrain DrainFunc
+	// Stats receives the profile runs, tick overruns and throttles
+	Stats *Stats
+
+	statsOnce sync.Once
+}
+
 func Run(interval string) DirectorFunc {
 	return func(ctx context.Context, profilesFunc GenerateProfilesFunc, matchesFunc FetchMatchesFunc, assignFunc AssignFunc) error {
-		logger := runtime.Logger().WithField("component", "director")
-
 		duration, err := validateInterval(interval)
 		if err != nil {
 			return err
 		}
-		logger.Infof("fetching matches interval set to %s", interval)
 
-		profiles, err := profilesFunc()
-		if err != nil {
-			return errors.Wrap(err, "failed to generate profiles")
+		scheduler := &Scheduler{Interval: duration}
+		return scheduler.Run(ctx, profilesFunc, matchesFunc, assignFunc)
+	}
+}
+
+// Run implements the DirectorFunc
+func (s *Scheduler) Run(ctx context.Context, profilesFunc GenerateProfilesFunc, matchesFunc FetchMatchesFunc, assignFunc AssignFunc) error {
+	logger := runtime.Logger().WithField("component", "director")
+
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	// Run is called for every shard held by an elected replica, they share the Stats
+	s.statsOnce.Do(func() {
+		if s.Stats == nil {
+			s.Stats = NewStats()
 		}
+	})
+
+	logger.WithFields(logrus.Fields{
+		"max_concurrency": s.MaxConcurrency,
+		"jitter":          s.Jitter,
+	}).Infof("fetching matches interval set to %s", s.Interval)
+
+	profiles, err := profilesFunc()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate profiles")
+	}
+
+	var semaphore chan struct{}
+	if s.MaxConcurrency > 0 {
+		semaphore = make(chan struct{}, s.MaxConcurrency)
+	}
+
+	// Matches already fetched are assigned with ctxDrain, it is only cancelled once the drain deadline is reached
+	ctxDrain, cancelDrain := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelDrain()
+
+	var loops, inFlight sync.WaitGroup
+	for _, p := range profiles {
+		loops.Add(1)
+		go func(p *pb.MatchProfile) {
+			defer loops.Done()
+			s.runProfile(ctx, ctxDrain, logger, p, semaphore, &inFlight, matchesFunc, assignFunc)
+		}(p)
+	}
+
+	<-ctx.Done()
+	logger.Info("stopping director, draining in-flight matches")
+	before := s.Stats.Snapshot()
+	loops.Wait()
+
+	report := s.drain(ctx, &inFlight, cancelDrain, before)
+	logger.WithFields(logrus.Fields{
+		"completed": report.Completed,
+		"released":  report.Released,
+		"abandoned": report.Abandoned,
+		"timed_out": report.TimedOut,
+	}).Info("director drained")
+
+	if s.OnDrain != nil {
+		s.OnDrain(report)
+	}
+
+	return nil
+}
+
+// drain waits for the in-flight runs up to the DrainTimeout. Runs still going on after the deadline get their
+// assign context cancelled, so they release the tickets of their matches, and are given one more second to do it.
+// A deadline set with WithDrainDeadline shortens both so the drain ends by then.
+func (s *Scheduler) drain(ctx context.Context, inFlight *sync.WaitGroup, cancel context.CancelFunc, before StatsSnapshot) DrainReport {
+	timeout := s.DrainTimeout
+	if timeout == 0 {
+		timeout = defaultDrainTimeout
+	}
+
+	grace := drainGracePeriod
+	if deadline, ok := DrainDeadline(ctx); ok {
+		left := time.Until(deadline)
+		grace = min(grace, max(left, 0))
+		timeout = min(timeout, max(left-grace, 0))
+	}
+
+	report := DrainReport{}
+	if !waitTimeout(inFlight, timeout) {
+		report.TimedOut = true
+		cancel()
+		waitTimeout(inFlight, grace)
+	}
+
+	after := s.Stats.Snapshot()
+	report.Completed = after.MatchesAssigned - before.MatchesAssigned
+	report.Released = after.MatchesFailed - before.MatchesFailed
+	// Matches dropped before the drain belong to runs that had already finished
+	report.Abandoned = after.MatchesFetched - after.MatchesAssigned - after.MatchesFailed - before.MatchesDropped
+	if report.Abandoned < 0 {
+		report.Abandoned = 0
+	}
+
+	return report
+}
+
+func (s *Scheduler) runProfile(ctx, ctxDrain context.Context, logger *logrus.Entry, p *pb.MatchProfile, semaphore chan struct{}, inFlight *sync.WaitGroup, matchesFunc FetchMatchesFunc, assignFunc AssignFunc) {
+	interval := s.intervalFor(p.GetName())
+	start := interval
+	if s.Jitter > 0 {
+		start += time.Duration(rand.Int63n(int64(s.Jitter)))
+	}
+
+	timer := time.NewTimer(start)
+	defer timer.Stop()
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time = timer.C
 
-		ticker := time.NewTicker(duration)
-		for {
-			select {
-			case <-ticker.C:
-				var wg sync.WaitGroup
-
-				for _, p := range profiles {
-					wg.Add(1)
-					go func(wg *sync.WaitGroup, p *pb.MatchProfile) {
-						defer wg.Done()
-						matches, err := matchesFunc(ctx, p)
-						if err != nil {
-							logger.Error(errors.Wrap(err, "failed to fetch matches"))
-						}
-
-						if err := assignFunc(ctx, matches); err != nil {
-							logger.Error(errors.Wrap(err, "failed to assign matches"))
-						}
-					}(&wg, p)
+	var state atomic.Int32
+	for {
+		select {
+		case <-tick:
+			if ticker == nil {
+				ticker = time.NewTicker(interval)
+				defer ticker.Stop()
+				tick = ticker.C
+			}
+
+			// The run waiting for a MaxConcurrency slot is not late, the tick is throttled instead of overrun
+			if !state.CompareAndSwap(profileIdle, profileWaiting) {
+				if state.Load() == profileWaiting {
+					s.Stats.AddTickThrottles(1)
+					logger.Debugf("profile %s is waiting for a run slot, tick skipped", p.GetName())
+					continue
+				}
+
+				s.Stats.AddTickOverruns(1)
+				logger.Warnf("profile %s is still running, tick skipped", p.GetName())
+				if s.OnOverrun != nil {
+					s.OnOverrun(p.GetName())
 				}
-				wg.Wait()
-			case <-ctx.Done():
-				logger.Info("stopping director")
-				ticker.Stop()
-				timeout, cancel := context.WithTimeout(ctx, time.Second)
-				defer cancel()
-
-				<-timeout.Done()
-				return nil
+				continue
 			}
+
+			inFlight.Add(1)
+			go func() {
+				defer inFlight.Done()
+				defer state.Store(profileIdle)
+
+				if semaphore != nil {
+					select {
+					case semaphore <- struct{}{}:
+						defer func() { <-semaphore }()
+					case <-ctx.Done():
+						return
+					}
+				}
+				state.Store(profileRunning)
+
+				s.Stats.AddProfileRuns(1)
+				s.runFetchAndAssign(ctx, ctxDrain, logger, p, matchesFunc, assignFunc)
+			}()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runFetchAndAssign assigns the matches while they are still being fetched. Fetching stops when ctx is cancelled,
+// the matches already fetched are assigned until ctxAssign is cancelled.
+func (s *Scheduler) runFetchAndAssign(ctx, ctxAssign context.Context, logger *logrus.Entry, p *pb.MatchProfile, matchesFunc FetchMatchesFunc, assignFunc AssignFunc) {
+	fetched := make(chan *pb.Match)
+	errFetch := make(chan error, 1)
+
+	go func() {
+		defer close(fetched)
+		errFetch <- matchesFunc(ctx, p, fetched)
+	}()
+
+	// Every match is counted as it is handed over, so the drain knows the matches left without outcome
+	matches := make(chan *pb.Match)
+	go func() {
+		defer close(matches)
+		for match := range fetched {
+			s.Stats.AddMatchesFetched(1)
+			matches <- match
+		}
+	}()
+
+	if err := assignFunc(ctxAssign, matches); err != nil {
+		logger.Error(errors.Wrap(err, "failed to assign matches"))
+	}
+
+	// The fetch must not block on matches the assign func didn't read
+	var dropped int
+	for range matches {
+		dropped++
+	}
+
+	if dropped > 0 {
+		s.Stats.AddMatchesDropped(dropped)
+		logger.Warnf("profile %s dropped %d matches not read by the assign func", p.GetName(), dropped)
+	}
+
+	if err := <-errFetch; err != nil {
+		logger.Error(errors.Wrap(err, "failed to fetch matches"))
+	}
+}
+
+func (s *Scheduler) intervalFor(profile string) time.Duration {
+	if interval, ok := s.ProfileIntervals[profile]; ok {
+		return interval
+	}
+
+	return s.Interval
+}
+
+func (s *Scheduler) validate() error {
+	if s.Interval <= 0 {
+		return errors.New("director interval must be higher than zero")
+	}
+
+	for profile, interval := range s.ProfileIntervals {
+		if interval <= 0 {
+			return errors.Errorf("director interval for profile %s must be higher than zero", profile)
 		}
 	}
+
+	if s.MaxConcurrency < 0 {
+		return errors.New("director max concurrency can't be lower than zero")
+	}
+
+	if s.Jitter < 0 {
+		return errors.New("director jitter can't be lower than zero")
+	}
+
+	if s.DrainTimeout < 0 {
+		return errors.New("director drain timeout can't be lower than zero")
+	}
+
+	return nil
+}
+
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+	}
+
+	// Runs already done are not timed out, even with a zero timeout
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
 }
 
 func validateInterval(interval string) (time.Duration, error) {
@@ -73,3 +375,23 @@ func validateInterval(interval string) (time.Duration, error) {
 	}
 	return duration, err
 }
+
+// ParseProfileIntervals parses the intervals by profile name in the name=duration format, i.e. world_based_profile_Dune_us-east-1=10s
+func ParseProfileIntervals(intervals []string) (map[string]time.Duration, error) {
+	durations := map[string]time.Duration{}
+	for _, entry := range intervals {
+		profile, interval, ok := strings.Cut(entry, "=")
+		if !ok || len(profile) == 0 {
+			return nil, errors.Errorf("profile interval %q should be in the name=duration format", entry)
+		}
+
+		duration, err := validateInterval(interval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "profile %s", profile)
+		}
+
+		durations[profile] = duration
+	}
+
+	return durations, nil
+}
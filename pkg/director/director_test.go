@@ -0,0 +1,421 @@
+package director
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	t.Run("it should skip ticks only for the profile still running", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		release := make(chan struct{})
+		runs := map[string]*atomic.Int64{"slow": {}, "fast": {}}
+		var overruns sync.Map
+
+		scheduler := &Scheduler{
+			Interval: 10 * time.Millisecond,
+			OnOverrun: func(profile string) {
+				overruns.Store(profile, true)
+			},
+		}
+
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			runs[profile.GetName()].Add(1)
+			if profile.GetName() == "slow" {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			}
+			return nil
+		}
+
+		done := runScheduler(ctx, scheduler, profiles("slow", "fast"), matches)
+
+		require.Eventually(t, func() bool {
+			return runs["fast"].Load() >= 5
+		}, time.Second, 5*time.Millisecond)
+
+		_, slowOverrun := overruns.Load("slow")
+		_, fastOverrun := overruns.Load("fast")
+		require.True(t, slowOverrun)
+		require.False(t, fastOverrun)
+		require.Equal(t, int64(1), runs["slow"].Load())
+		require.Greater(t, scheduler.Stats.Snapshot().TickOverruns, int64(0))
+
+		close(release)
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("it should bound the number of profiles running at the same time", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var running, maxRunning atomic.Int64
+		scheduler := &Scheduler{
+			Interval:       5 * time.Millisecond,
+			MaxConcurrency: 2,
+		}
+
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+
+			for {
+				max := maxRunning.Load()
+				if current <= max || maxRunning.CompareAndSwap(max, current) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}
+
+		done := runScheduler(ctx, scheduler, profiles("a", "b", "c", "d", "e"), matches)
+
+		require.Eventually(t, func() bool {
+			return scheduler.Stats.Snapshot().ProfileRuns >= 10
+		}, 2*time.Second, 5*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+		require.Equal(t, int64(2), maxRunning.Load())
+	})
+
+	t.Run("it should not count the ticks waiting for a run slot as overruns", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		release := make(chan struct{})
+		var overruns sync.Map
+		scheduler := &Scheduler{
+			Interval:       5 * time.Millisecond,
+			MaxConcurrency: 1,
+			OnOverrun: func(profile string) {
+				overruns.Store(profile, true)
+			},
+		}
+
+		var started atomic.Value
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			started.CompareAndSwap(nil, profile.GetName())
+			if started.Load() == profile.GetName() {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+			}
+			return nil
+		}
+
+		done := runScheduler(ctx, scheduler, profiles("a", "b"), matches)
+
+		require.Eventually(t, func() bool {
+			return scheduler.Stats.Snapshot().TickThrottles >= 3
+		}, time.Second, 5*time.Millisecond)
+
+		// The profile holding the slot overruns, the one waiting for it doesn't
+		blocked := "b"
+		if started.Load() == "b" {
+			blocked = "a"
+		}
+		_, blockedOverrun := overruns.Load(blocked)
+		require.False(t, blockedOverrun)
+		_, runningOverrun := overruns.Load(started.Load())
+		require.True(t, runningOverrun)
+
+		close(release)
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("it should use the interval of the profile", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		runs := map[string]*atomic.Int64{"default": {}, "custom": {}}
+		scheduler := &Scheduler{
+			Interval:         time.Hour,
+			ProfileIntervals: map[string]time.Duration{"custom": 5 * time.Millisecond},
+			Jitter:           5 * time.Millisecond,
+		}
+
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			runs[profile.GetName()].Add(1)
+			return nil
+		}
+
+		done := runScheduler(ctx, scheduler, profiles("default", "custom"), matches)
+
+		require.Eventually(t, func() bool {
+			return runs["custom"].Load() >= 3
+		}, time.Second, 5*time.Millisecond)
+		require.Equal(t, int64(0), runs["default"].Load())
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("it should assign matches while they are still being fetched", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		assigned := make(chan string)
+		fetchDone := make(chan struct{})
+
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			matches <- &pb.Match{MatchId: "m1"}
+			<-fetchDone
+			return nil
+		}
+
+		assign := func(ctx context.Context, matches <-chan *pb.Match) error {
+			for match := range matches {
+				select {
+				case assigned <- match.GetMatchId():
+				case <-ctx.Done():
+				}
+			}
+			return nil
+		}
+
+		scheduler := &Scheduler{Interval: 5 * time.Millisecond, Stats: NewStats()}
+		done := make(chan error, 1)
+		go func() {
+			done <- scheduler.Run(ctx, profiles("profile"), matches, assign)
+		}()
+
+		select {
+		case id := <-assigned:
+			require.Equal(t, "m1", id)
+		case <-time.After(time.Second):
+			require.Fail(t, "match was not assigned before the fetch finished")
+		}
+
+		close(fetchDone)
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("it should assign the in-flight matches before stopping", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		started := make(chan struct{})
+		allocate := make(chan struct{})
+		var once sync.Once
+
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			matches <- &pb.Match{MatchId: "m1"}
+			matches <- &pb.Match{MatchId: "m2"}
+			<-ctx.Done()
+			return nil
+		}
+
+		scheduler := &Scheduler{Interval: 5 * time.Millisecond, DrainTimeout: time.Second, Stats: NewStats()}
+		assign := func(ctx context.Context, matches <-chan *pb.Match) error {
+			for range matches {
+				once.Do(func() { close(started) })
+				<-allocate
+				if ctx.Err() != nil {
+					scheduler.Stats.AddMatchesFailed(1)
+					continue
+				}
+				scheduler.Stats.AddMatchesAssigned(1)
+			}
+			return nil
+		}
+
+		reports := make(chan DrainReport, 1)
+		scheduler.OnDrain = func(report DrainReport) { reports <- report }
+
+		done := make(chan error, 1)
+		go func() {
+			done <- scheduler.Run(ctx, profiles("profile"), matches, assign)
+		}()
+
+		<-started
+		cancel()
+		// Give the scheduler time to start the drain before the matches are allocated
+		time.Sleep(20 * time.Millisecond)
+		close(allocate)
+		require.NoError(t, <-done)
+
+		report := <-reports
+		require.Equal(t, DrainReport{Completed: 2}, report)
+		require.Equal(t, int64(2), scheduler.Stats.Snapshot().MatchesFetched)
+	})
+
+	t.Run("it should cancel the in-flight matches at the drain deadline", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		started := make(chan struct{})
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			matches <- &pb.Match{MatchId: "m1"}
+			matches <- &pb.Match{MatchId: "m2"}
+			return nil
+		}
+
+		scheduler := &Scheduler{Interval: 5 * time.Millisecond, DrainTimeout: 20 * time.Millisecond, Stats: NewStats()}
+		assign := func(ctx context.Context, matches <-chan *pb.Match) error {
+			// The first match is stuck until the drain deadline, the second is never read
+			<-matches
+			close(started)
+			<-ctx.Done()
+			scheduler.Stats.AddMatchesFailed(1)
+			return ctx.Err()
+		}
+
+		reports := make(chan DrainReport, 1)
+		scheduler.OnDrain = func(report DrainReport) { reports <- report }
+
+		done := make(chan error, 1)
+		go func() {
+			done <- scheduler.Run(ctx, profiles("profile"), matches, assign)
+		}()
+
+		<-started
+		cancel()
+		require.NoError(t, <-done)
+
+		report := <-reports
+		require.Equal(t, DrainReport{Released: 1, Abandoned: 1, TimedOut: true}, report)
+	})
+
+	t.Run("it should end the drain by the deadline of the context", func(t *testing.T) {
+		deadline := time.Now().Add(time.Hour)
+		ctx, cancel := context.WithCancel(WithDrainDeadline(context.Background(), func() time.Time { return deadline }))
+		defer cancel()
+
+		started := make(chan struct{})
+		matches := func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
+			matches <- &pb.Match{MatchId: "m1"}
+			return nil
+		}
+
+		scheduler := &Scheduler{Interval: 5 * time.Millisecond, DrainTimeout: time.Minute, Stats: NewStats()}
+		assign := func(ctx context.Context, matches <-chan *pb.Match) error {
+			<-matches
+			close(started)
+			<-ctx.Done()
+			scheduler.Stats.AddMatchesFailed(1)
+			return ctx.Err()
+		}
+
+		reports := make(chan DrainReport, 1)
+		scheduler.OnDrain = func(report DrainReport) { reports <- report }
+
+		done := make(chan error, 1)
+		go func() {
+			done <- scheduler.Run(ctx, profiles("profile"), matches, assign)
+		}()
+
+		<-started
+		// The deadline is read when the drain starts, i.e. the lease of the replica was renewed for the last time
+		deadline = time.Now().Add(50 * time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+		require.False(t, time.Now().After(deadline.Add(10*time.Millisecond)), "the drain ends by the deadline")
+
+		report := <-reports
+		require.Equal(t, DrainReport{Released: 1, TimedOut: true}, report)
+	})
+}
+
+func TestScheduler_validate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		scheduler *Scheduler
+		wantErr   bool
+	}{
+		{
+			name:      "it should accept a valid scheduler",
+			scheduler: &Scheduler{Interval: time.Second, MaxConcurrency: 1, Jitter: time.Second},
+		},
+		{
+			name:      "it should return error for zero interval",
+			scheduler: &Scheduler{},
+			wantErr:   true,
+		},
+		{
+			name:      "it should return error for zero profile interval",
+			scheduler: &Scheduler{Interval: time.Second, ProfileIntervals: map[string]time.Duration{"profile": 0}},
+			wantErr:   true,
+		},
+		{
+			name:      "it should return error for negative max concurrency",
+			scheduler: &Scheduler{Interval: time.Second, MaxConcurrency: -1},
+			wantErr:   true,
+		},
+		{
+			name:      "it should return error for negative jitter",
+			scheduler: &Scheduler{Interval: time.Second, Jitter: -time.Second},
+			wantErr:   true,
+		},
+		{
+			name:      "it should return error for negative drain timeout",
+			scheduler: &Scheduler{Interval: time.Second, DrainTimeout: -time.Second},
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.scheduler.validate()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParseProfileIntervals(t *testing.T) {
+	intervals, err := ParseProfileIntervals([]string{"world_based_profile_Dune_us-east-1=10s", "profile=1m"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Duration{"world_based_profile_Dune_us-east-1": 10 * time.Second, "profile": time.Minute}, intervals)
+
+	_, err = ParseProfileIntervals([]string{"profile=10"})
+	require.Error(t, err)
+
+	_, err = ParseProfileIntervals([]string{"profile"})
+	require.Error(t, err)
+}
+
+func profiles(names ...string) GenerateProfilesFunc {
+	return func() ([]*pb.MatchProfile, error) {
+		var profiles []*pb.MatchProfile
+		for _, name := range names {
+			profiles = append(profiles, &pb.MatchProfile{Name: name})
+		}
+		return profiles, nil
+	}
+}
+
+func runScheduler(ctx context.Context, scheduler *Scheduler, profilesFunc GenerateProfilesFunc, matchesFunc FetchMatchesFunc) <-chan error {
+	scheduler.Stats = NewStats()
+	assign := func(ctx context.Context, matches <-chan *pb.Match) error {
+		for range matches {
+		}
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- scheduler.Run(ctx, profilesFunc, matchesFunc, assign)
+	}()
+
+	return done
+}
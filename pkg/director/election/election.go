@@ -0,0 +1,336 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"hash/fnv"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Shard is the slice of the profiles owned by a replica
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Owns returns true if the profile belongs to the shard. Profiles are spread with rendezvous hashing,
+// so changing the number of shards only moves the profiles of the shards added or removed.
+func (s Shard) Owns(profile string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+
+	var owner int
+	var highest uint64
+	for i := 0; i < s.Count; i++ {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d", profile, i)
+		if weight := mix(h.Sum64()); i == 0 || weight > highest {
+			owner, highest = i, weight
+		}
+	}
+
+	return owner == s.Index
+}
+
+// mix spreads the bits of the hash, FNV alone gives similar weights to keys that only differ in the last bytes
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Elector competes with the other replicas for the shard leases and runs the director while it holds one.
+// With a single shard it works as leader election.
+type Elector struct {
+	Lock Lock
+	// Name of the lease, shards use Name-<index>
+	Name string
+	// Identity of this replica, usually the pod name
+	Identity string
+	// Shards is the number of shards the profiles are split into. Zero or one means leader election.
+	Shards int
+	// LeaseDuration is the time a lease is valid without being renewed
+	LeaseDuration time.Duration
+	// RenewDeadline is the time the holder keeps running the shard without renewing the lease. It is shorter than
+	// the LeaseDuration so the shard is stopped, and its drain done, before another replica can take over the lease.
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval between tries to acquire or renew the lease
+	RetryPeriod time.Duration
+}
+
+// RunFunc runs the director for the shard until the context is cancelled
+type RunFunc func(ctx context.Context, shard Shard) error
+
+// Elect wraps the director so it only runs for the profiles of the shard held by this replica
+func Elect(elector *Elector, run director.DirectorFunc) director.DirectorFunc {
+	return func(ctx context.Context, profilesFunc director.GenerateProfilesFunc, matchesFunc director.FetchMatchesFunc, assignFunc director.AssignFunc) error {
+		return elector.Run(ctx, func(ctx context.Context, shard Shard) error {
+			return run(ctx, ShardProfiles(shard, profilesFunc), matchesFunc, assignFunc)
+		})
+	}
+}
+
+// ShardProfiles filters the profiles owned by the shard
+func ShardProfiles(shard Shard, profilesFunc director.GenerateProfilesFunc) director.GenerateProfilesFunc {
+	return func() ([]*pb.MatchProfile, error) {
+		profiles, err := profilesFunc()
+		if err != nil {
+			return nil, err
+		}
+
+		var owned []*pb.MatchProfile
+		for _, p := range profiles {
+			if shard.Owns(p.GetName()) {
+				owned = append(owned, p)
+			}
+		}
+
+		return owned, nil
+	}
+}
+
+func (e *Elector) Validate() error {
+	if e.Lock == nil {
+		return errors.New("election lock can't be nil")
+	}
+
+	if len(e.Name) == 0 || len(e.Identity) == 0 {
+		return errors.New("election name and identity are required")
+	}
+
+	if e.LeaseDuration <= 0 || e.RenewDeadline <= 0 || e.RetryPeriod <= 0 {
+		return errors.New("election lease duration, renew deadline and retry period must be higher than zero")
+	}
+
+	if e.RetryPeriod >= e.RenewDeadline || e.RenewDeadline >= e.LeaseDuration {
+		return errors.New("election retry period must be lower than the renew deadline and the renew deadline lower than the lease duration")
+	}
+
+	return nil
+}
+
+// Run blocks until the context is cancelled. It calls run every time a shard lease is acquired and cancels the
+// context passed to it when the lease is lost. The lease is released when run returns. A replica keeps trying the
+// leases of the shards it doesn't hold and runs every shard it gets, so the shards of a replica that stopped are taken
+// over by the others. Once it holds a shard, it waits for a LeaseDuration before taking more so the replicas starting
+// together get one each.
+func (e *Elector) Run(ctx context.Context, run RunFunc) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	logger := runtime.Logger().WithFields(logrus.Fields{
+		"component": "election",
+		"identity":  e.Identity,
+	})
+
+	shards := e.Shards
+	if shards < 1 {
+		shards = 1
+	}
+
+	ctxRun, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mux sync.Mutex
+	held := map[int]bool{}
+	errs := make(chan error, shards)
+
+	ticker := time.NewTicker(e.RetryPeriod)
+	defer ticker.Stop()
+
+	var holding time.Time
+	for {
+		mux.Lock()
+		skip := map[int]bool{}
+		for index := range held {
+			skip[index] = true
+		}
+		mux.Unlock()
+
+		if len(skip) == 0 {
+			holding = time.Time{}
+		}
+
+		limit := 1
+		if !holding.IsZero() && time.Since(holding) >= e.LeaseDuration {
+			limit = shards
+		}
+
+		if len(skip) == 0 || limit > 1 {
+			for _, acquired := range e.acquire(ctxRun, logger, shards, skip, limit) {
+				if holding.IsZero() {
+					holding = acquired.at
+				}
+
+				mux.Lock()
+				held[acquired.shard.Index] = true
+				mux.Unlock()
+
+				wg.Add(1)
+				go func(acquired acquiredShard) {
+					defer wg.Done()
+					err := e.hold(ctxRun, logger, acquired.shard, acquired.at, run)
+
+					mux.Lock()
+					delete(held, acquired.shard.Index)
+					mux.Unlock()
+
+					if err != nil {
+						errs <- err
+					}
+				}(acquired)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case err := <-errs:
+			cancel()
+			wg.Wait()
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+type acquiredShard struct {
+	shard Shard
+	at    time.Time
+}
+
+// acquire tries the shards not skipped starting from one based on the identity, so replicas don't compete for the
+// same lease, until limit shards are acquired
+func (e *Elector) acquire(ctx context.Context, logger *logrus.Entry, shards int, skip map[int]bool, limit int) []acquiredShard {
+	h := fnv.New32a()
+	h.Write([]byte(e.Identity))
+	start := int(h.Sum32() % uint32(shards))
+
+	var acquired []acquiredShard
+	for i := 0; i < shards && len(acquired) < limit; i++ {
+		shard := Shard{Index: (start + i) % shards, Count: shards}
+		if skip[shard.Index] {
+			continue
+		}
+
+		at := time.Now()
+		ok, err := e.Lock.TryAcquire(ctx, e.leaseName(shard), e.Identity, e.LeaseDuration)
+		if err != nil {
+			logger.Warn(errors.Wrapf(err, "failed to acquire lease %s", e.leaseName(shard)).Error())
+			continue
+		}
+
+		if ok {
+			acquired = append(acquired, acquiredShard{shard: shard, at: at})
+		}
+	}
+
+	return acquired
+}
+
+// hold runs the shard while the lease is renewed. The lease is counted from the start of the call that acquired or
+// renewed it, so the drain deadline is never later than the expiry seen by the other replicas.
+func (e *Elector) hold(ctx context.Context, logger *logrus.Entry, shard Shard, acquired time.Time, run RunFunc) error {
+	name := e.leaseName(shard)
+	logger.Infof("acquired lease %s, running shard %d of %d", name, shard.Index, shard.Count)
+
+	var renewed atomic.Int64
+	renewed.Store(acquired.UnixNano())
+
+	// The drain of the director must end before the lease can be taken over by another replica
+	ctxShard, cancel := context.WithCancel(director.WithDrainDeadline(ctx, func() time.Time {
+		return time.Unix(0, renewed.Load()).Add(e.LeaseDuration)
+	}))
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- run(ctxShard, shard)
+	}()
+
+	ticker := time.NewTicker(e.RetryPeriod)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(time.Until(acquired.Add(e.RenewDeadline)))
+	defer deadline.Stop()
+
+	var err error
+loop:
+	for {
+		select {
+		case err = <-done:
+			break loop
+		case <-deadline.C:
+			logger.Warnf("failed to renew lease %s within %s, stopping shard %d", name, e.RenewDeadline, shard.Index)
+			cancel()
+			err = <-done
+			break loop
+		case <-ticker.C:
+			// The lease is still renewed while the shard drains after the context is cancelled
+			start := time.Now()
+			ok, errRenew := e.renew(ctx, name)
+			if errRenew != nil {
+				logger.Warn(errors.Wrapf(errRenew, "failed to renew lease %s", name).Error())
+				continue
+			}
+
+			if !ok {
+				logger.Warnf("lost lease %s, stopping shard %d", name, shard.Index)
+				cancel()
+				err = <-done
+				break loop
+			}
+
+			renewed.Store(start.UnixNano())
+			if !deadline.Stop() {
+				select {
+				case <-deadline.C:
+				default:
+				}
+			}
+			deadline.Reset(time.Until(start.Add(e.RenewDeadline)))
+		}
+	}
+
+	ctxRelease, cancelRelease := context.WithTimeout(context.Background(), e.RetryPeriod)
+	defer cancelRelease()
+
+	if errRelease := e.Lock.Release(ctxRelease, name, e.Identity); errRelease != nil {
+		logger.Warn(errors.Wrapf(errRelease, "failed to release lease %s", name).Error())
+	}
+
+	if err != nil && ctx.Err() == nil {
+		return errors.Wrapf(err, "shard %d stopped", shard.Index)
+	}
+
+	return nil
+}
+
+func (e *Elector) renew(ctx context.Context, name string) (bool, error) {
+	ctxRenew, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.RetryPeriod)
+	defer cancel()
+
+	return e.Lock.TryAcquire(ctxRenew, name, e.Identity, e.LeaseDuration)
+}
+
+func (e *Elector) leaseName(shard Shard) string {
+	if shard.Count <= 1 {
+		return e.Name
+	}
+
+	return fmt.Sprintf("%s-%d", e.Name, shard.Index)
+}
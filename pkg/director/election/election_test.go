@@ -0,0 +1,347 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShard_Owns(t *testing.T) {
+	var profiles []string
+	for i := 0; i < 100; i++ {
+		profiles = append(profiles, fmt.Sprintf("world_based_profile_%d", i))
+	}
+
+	t.Run("it should own every profile with a single shard", func(t *testing.T) {
+		for _, p := range profiles {
+			require.True(t, Shard{Index: 0, Count: 1}.Owns(p))
+		}
+	})
+
+	t.Run("it should assign every profile to exactly one shard", func(t *testing.T) {
+		owned := map[int]int{}
+		for _, p := range profiles {
+			var owners int
+			for i := 0; i < 3; i++ {
+				if (Shard{Index: i, Count: 3}).Owns(p) {
+					owners++
+					owned[i]++
+				}
+			}
+			require.Equal(t, 1, owners)
+		}
+
+		for i := 0; i < 3; i++ {
+			require.Greater(t, owned[i], 10)
+		}
+	})
+
+	t.Run("it should only move profiles of the removed shard", func(t *testing.T) {
+		for _, p := range profiles {
+			if (Shard{Index: 0, Count: 3}).Owns(p) {
+				require.True(t, Shard{Index: 0, Count: 4}.Owns(p) || Shard{Index: 3, Count: 4}.Owns(p))
+			}
+		}
+	})
+}
+
+func TestMemoryLock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lock := NewMemoryLock()
+	lock.now = func() time.Time { return now }
+
+	ok, err := lock.TryAcquire(ctx, "lease", "replica-1", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = lock.TryAcquire(ctx, "lease", "replica-2", time.Second)
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, "replica-1", lock.Holder("lease"))
+
+	now = now.Add(2 * time.Second)
+	ok, err = lock.TryAcquire(ctx, "lease", "replica-2", time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "replica-2", lock.Holder("lease"))
+
+	require.NoError(t, lock.Release(ctx, "lease", "replica-1"))
+	require.Equal(t, "replica-2", lock.Holder("lease"))
+
+	require.NoError(t, lock.Release(ctx, "lease", "replica-2"))
+	require.Empty(t, lock.Holder("lease"))
+}
+
+func TestElector_Run(t *testing.T) {
+	t.Run("it should run a single leader and fail over when it stops", func(t *testing.T) {
+		lock := NewMemoryLock()
+		running := &runningShards{shards: map[string]Shard{}}
+
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		defer cancel2()
+
+		done1 := runElector(ctx1, newElector(lock, "replica-1", 1), running)
+		require.Eventually(t, func() bool { return running.count() == 1 }, time.Second, 5*time.Millisecond)
+
+		done2 := runElector(ctx2, newElector(lock, "replica-2", 1), running)
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, 1, running.count())
+		require.Contains(t, running.identities(), "replica-1")
+
+		cancel1()
+		require.NoError(t, <-done1)
+
+		require.Eventually(t, func() bool {
+			ids := running.identities()
+			return len(ids) == 1 && ids[0] == "replica-2"
+		}, time.Second, 5*time.Millisecond)
+
+		cancel2()
+		require.NoError(t, <-done2)
+	})
+
+	t.Run("it should run a different shard on every replica", func(t *testing.T) {
+		lock := NewMemoryLock()
+		running := &runningShards{shards: map[string]Shard{}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var done []<-chan error
+		for _, id := range []string{"replica-1", "replica-2", "replica-3"} {
+			done = append(done, runElector(ctx, newElector(lock, id, 2), running))
+		}
+
+		require.Eventually(t, func() bool { return running.count() == 2 }, time.Second, 5*time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, 2, running.count())
+		require.ElementsMatch(t, []int{0, 1}, running.indexes())
+
+		cancel()
+		for _, d := range done {
+			require.NoError(t, <-d)
+		}
+	})
+
+	t.Run("it should take over the shard of a replica that stopped", func(t *testing.T) {
+		lock := NewMemoryLock()
+		running := &runningShards{shards: map[string]Shard{}}
+
+		ctx1, cancel1 := context.WithCancel(context.Background())
+		ctx2, cancel2 := context.WithCancel(context.Background())
+		defer cancel2()
+
+		done1 := runElector(ctx1, newElector(lock, "replica-1", 2), running)
+		done2 := runElector(ctx2, newElector(lock, "replica-2", 2), running)
+		require.Eventually(t, func() bool { return running.count() == 2 }, time.Second, 5*time.Millisecond)
+		require.ElementsMatch(t, []string{"replica-1", "replica-2"}, running.identities())
+
+		cancel1()
+		require.NoError(t, <-done1)
+
+		require.Eventually(t, func() bool {
+			ids := running.identities()
+			return running.count() == 2 && len(ids) == 1 && ids[0] == "replica-2"
+		}, time.Second, 5*time.Millisecond)
+		require.ElementsMatch(t, []int{0, 1}, running.indexes())
+
+		cancel2()
+		require.NoError(t, <-done2)
+	})
+
+	t.Run("it should run every shard on a single replica", func(t *testing.T) {
+		running := &runningShards{shards: map[string]Shard{}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := runElector(ctx, newElector(NewMemoryLock(), "replica-1", 3), running)
+		require.Eventually(t, func() bool { return running.count() == 3 }, time.Second, 5*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+		require.Zero(t, running.count())
+	})
+
+	t.Run("it should stop the shard when the lease is lost", func(t *testing.T) {
+		lock := NewMemoryLock()
+		running := &runningShards{shards: map[string]Shard{}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := runElector(ctx, newElector(lock, "replica-1", 1), running)
+		require.Eventually(t, func() bool { return running.count() == 1 }, time.Second, 5*time.Millisecond)
+
+		// Another replica takes over the lease, i.e. after a network partition
+		lock.mux.Lock()
+		lock.leases["director"] = memoryLease{holder: "replica-2", expires: time.Now().Add(time.Hour)}
+		lock.mux.Unlock()
+
+		require.Eventually(t, func() bool { return running.count() == 0 }, time.Second, 5*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("it should stop the shard at the renew deadline before the lease expires", func(t *testing.T) {
+		lock := &failingLock{MemoryLock: NewMemoryLock()}
+		elector := newElector(lock, "replica-1", 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		acquired := make(chan time.Time, 1)
+		stopped := make(chan time.Time, 1)
+		drainDeadline := make(chan time.Time, 1)
+		go elector.Run(ctx, func(ctx context.Context, shard Shard) error {
+			acquired <- time.Now()
+			// The renew calls fail from now on, i.e. the API server is not reachable
+			lock.failing.Store(true)
+			<-ctx.Done()
+			stopped <- time.Now()
+			deadline, _ := director.DrainDeadline(ctx)
+			drainDeadline <- deadline
+			return nil
+		})
+
+		start := <-acquired
+		end := <-stopped
+		require.GreaterOrEqual(t, end.Sub(start), elector.RenewDeadline-elector.RetryPeriod)
+		require.Less(t, end.Sub(start), elector.LeaseDuration)
+		require.False(t, (<-drainDeadline).After(start.Add(elector.LeaseDuration)), "the drain ends before the lease expires")
+	})
+
+	t.Run("it should return the error of the director", func(t *testing.T) {
+		elector := newElector(NewMemoryLock(), "replica-1", 1)
+		err := elector.Run(context.Background(), func(ctx context.Context, shard Shard) error {
+			return fmt.Errorf("invalid interval")
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestElect(t *testing.T) {
+	profiles := func() ([]*pb.MatchProfile, error) {
+		var profiles []*pb.MatchProfile
+		for i := 0; i < 20; i++ {
+			profiles = append(profiles, &pb.MatchProfile{Name: fmt.Sprintf("profile_%d", i)})
+		}
+		return profiles, nil
+	}
+
+	shard := Shard{Index: 1, Count: 3}
+	owned, err := ShardProfiles(shard, profiles)()
+	require.NoError(t, err)
+	require.NotEmpty(t, owned)
+	for _, p := range owned {
+		require.True(t, shard.Owns(p.GetName()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []*pb.MatchProfile
+	run := func(ctx context.Context, profilesFunc director.GenerateProfilesFunc, _ director.FetchMatchesFunc, _ director.AssignFunc) error {
+		got, err = profilesFunc()
+		cancel()
+		<-ctx.Done()
+		return err
+	}
+
+	err = Elect(newElector(NewMemoryLock(), "replica-1", 1), run)(ctx, profiles, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 20)
+}
+
+// failingLock fails to renew the leases once failing is set
+type failingLock struct {
+	*MemoryLock
+	failing atomic.Bool
+}
+
+func (l *failingLock) TryAcquire(ctx context.Context, name, holder string, duration time.Duration) (bool, error) {
+	if l.failing.Load() {
+		return false, errors.New("lease api unavailable")
+	}
+
+	return l.MemoryLock.TryAcquire(ctx, name, holder, duration)
+}
+
+type runningShards struct {
+	mux    sync.Mutex
+	shards map[string]Shard
+}
+
+func (r *runningShards) count() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return len(r.shards)
+}
+
+// identities returns the replicas running at least one shard
+func (r *runningShards) identities() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	seen := map[string]bool{}
+	var ids []string
+	for key := range r.shards {
+		id := strings.Split(key, "/")[0]
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (r *runningShards) indexes() []int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var indexes []int
+	for _, s := range r.shards {
+		indexes = append(indexes, s.Index)
+	}
+	return indexes
+}
+
+func newElector(lock Lock, identity string, shards int) *Elector {
+	return &Elector{
+		Lock:          lock,
+		Name:          "director",
+		Identity:      identity,
+		Shards:        shards,
+		LeaseDuration: 100 * time.Millisecond,
+		RenewDeadline: 60 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	}
+}
+
+func runElector(ctx context.Context, elector *Elector, running *runningShards) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- elector.Run(ctx, func(ctx context.Context, shard Shard) error {
+			key := fmt.Sprintf("%s/%d", elector.Identity, shard.Index)
+			running.mux.Lock()
+			running.shards[key] = shard
+			running.mux.Unlock()
+
+			<-ctx.Done()
+
+			running.mux.Lock()
+			delete(running.shards, key)
+			running.mux.Unlock()
+			return nil
+		})
+	}()
+
+	return done
+}
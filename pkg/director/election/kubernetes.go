@@ -0,0 +1,307 @@
+package election
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	microTimeFormat   = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// KubernetesLock keeps the leases as coordination.k8s.io/v1 Lease objects. It talks to the API server through its
+// REST API, the service account of the pod needs get, create and update permissions on leases.
+// Like client-go, the expiry of a lease held by another replica is counted from the local time the lock last saw the
+// lease change, so clock skew between the nodes doesn't move the takeover.
+type KubernetesLock struct {
+	baseURL   string
+	token     string
+	namespace string
+	client    *http.Client
+	now       func() time.Time
+
+	mux      sync.Mutex
+	observed map[string]observedLease
+}
+
+// observedLease is the last version of a lease seen by this replica and the local time it was seen
+type observedLease struct {
+	version string
+	at      time.Time
+}
+
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       *string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds *int32  `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          *string `json:"acquireTime,omitempty"`
+	RenewTime            *string `json:"renewTime,omitempty"`
+	LeaseTransitions     *int32  `json:"leaseTransitions,omitempty"`
+}
+
+// NewKubernetesLock creates a lock for the API server on baseURL, i.e. https://kubernetes.default.svc
+func NewKubernetesLock(baseURL, token, namespace string, client *http.Client) *KubernetesLock {
+	return &KubernetesLock{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		token:     token,
+		namespace: namespace,
+		client:    client,
+		now:       time.Now,
+		observed:  map[string]observedLease{},
+	}
+}
+
+// NewKubernetesLockInCluster creates a lock using the service account of the pod.
+// The namespace of the pod is used if namespace is empty.
+func NewKubernetesLockInCluster(namespace string) (*KubernetesLock, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, errors.New("KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set, the director is not running on Kubernetes")
+	}
+
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read service account token")
+	}
+
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read service account CA")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("only PEM format is accepted for the service account CA")
+	}
+
+	if len(namespace) == 0 {
+		ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read service account namespace")
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	baseURL := "https://" + net.JoinHostPort(host, port)
+	return NewKubernetesLock(baseURL, strings.TrimSpace(string(token)), namespace, client), nil
+}
+
+func (l *KubernetesLock) TryAcquire(ctx context.Context, name, holder string, duration time.Duration) (bool, error) {
+	current, err := l.get(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	now := l.now()
+	if current == nil {
+		created := newLease(name, holder, duration, now)
+		return l.write(ctx, http.MethodPost, l.url(""), created)
+	}
+
+	spec := current.Spec
+	currentHolder := stringValue(spec.HolderIdentity)
+	if len(currentHolder) > 0 && currentHolder != holder && !l.expired(current, now) {
+		return false, nil
+	}
+
+	updated := newLease(name, holder, duration, now)
+	updated.Metadata.ResourceVersion = current.Metadata.ResourceVersion
+	transitions := int32Value(spec.LeaseTransitions)
+	if currentHolder == holder {
+		updated.Spec.AcquireTime = spec.AcquireTime
+	} else {
+		transitions++
+	}
+	updated.Spec.LeaseTransitions = &transitions
+
+	return l.write(ctx, http.MethodPut, l.url(name), updated)
+}
+
+func (l *KubernetesLock) Release(ctx context.Context, name, holder string) error {
+	current, err := l.get(ctx, name)
+	if err != nil || current == nil {
+		return err
+	}
+
+	if stringValue(current.Spec.HolderIdentity) != holder {
+		return nil
+	}
+
+	empty := ""
+	current.Spec.HolderIdentity = &empty
+	current.Spec.RenewTime = nil
+	current.Spec.AcquireTime = nil
+
+	if _, err := l.write(ctx, http.MethodPut, l.url(name), current); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (l *KubernetesLock) get(ctx context.Context, name string) (*lease, error) {
+	resp, err := l.do(ctx, http.MethodGet, l.url(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		current := &lease{}
+		if err := json.NewDecoder(resp.Body).Decode(current); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode lease %s", name)
+		}
+		return current, nil
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, responseError(resp, "get", name)
+	}
+}
+
+// write creates or updates the lease. A conflict means another replica changed the lease first.
+func (l *KubernetesLock) write(ctx context.Context, method, url string, obj *lease) (bool, error) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to encode lease %s", obj.Metadata.Name)
+	}
+
+	resp, err := l.do(ctx, method, url, body)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return true, nil
+	case http.StatusConflict:
+		return false, nil
+	default:
+		return false, responseError(resp, "write", obj.Metadata.Name)
+	}
+}
+
+func (l *KubernetesLock) do(ctx context.Context, method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if len(l.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+l.token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to %s %s", method, url)
+	}
+
+	return resp, nil
+}
+
+func (l *KubernetesLock) url(name string) string {
+	url := fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", l.baseURL, l.namespace)
+	if len(name) > 0 {
+		url += "/" + name
+	}
+
+	return url
+}
+
+func newLease(name, holder string, duration time.Duration, now time.Time) *lease {
+	// Rounded up, a shorter lease could be taken over before the RenewDeadline of the holder
+	seconds := int32(math.Ceil(duration.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	timestamp := now.UTC().Format(microTimeFormat)
+
+	return &lease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata:   leaseMetadata{Name: name},
+		Spec: leaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &seconds,
+			AcquireTime:          &timestamp,
+			RenewTime:            &timestamp,
+		},
+	}
+}
+
+// expired returns true if the lease didn't change for its duration since this replica first saw its current version.
+// The renewTime written by the holder is only used to detect changes, it comes from the clock of another node.
+func (l *KubernetesLock) expired(current *lease, now time.Time) bool {
+	if current.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+
+	version := current.Metadata.ResourceVersion + "/" + stringValue(current.Spec.RenewTime)
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	observed, ok := l.observed[current.Metadata.Name]
+	if !ok || observed.version != version {
+		observed = observedLease{version: version, at: now}
+		l.observed[current.Metadata.Name] = observed
+	}
+
+	return now.After(observed.at.Add(time.Duration(*current.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+func responseError(resp *http.Response, action, name string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.Errorf("failed to %s lease %s: %s %s", action, name, resp.Status, strings.TrimSpace(string(body)))
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func int32Value(i *int32) int32 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
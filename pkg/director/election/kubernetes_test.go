@@ -0,0 +1,221 @@
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKubernetesLock(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("it should create and renew the lease", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		lock := NewKubernetesLock(api.server.URL, "token", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		created := api.lease("director")
+		require.Equal(t, "replica-1", *created.Spec.HolderIdentity)
+		require.Equal(t, int32(15), *created.Spec.LeaseDurationSeconds)
+
+		ok, err = lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		renewed := api.lease("director")
+		require.Equal(t, *created.Spec.AcquireTime, *renewed.Spec.AcquireTime)
+		require.Equal(t, int32(0), int32Value(renewed.Spec.LeaseTransitions))
+		require.Equal(t, "Bearer token", api.authorization)
+	})
+
+	t.Run("it should not acquire a lease held by another replica", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		lock := NewKubernetesLock(api.server.URL, "", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = lock.TryAcquire(ctx, "director", "replica-2", 15*time.Second)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, "replica-1", *api.lease("director").Spec.HolderIdentity)
+	})
+
+	t.Run("it should take over an expired lease", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		lock := NewKubernetesLock(api.server.URL, "", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		// The renew time is ahead of the local clock, i.e. the node of the holder is skewed
+		api.update("director", func(l *lease) {
+			renew := time.Now().Add(time.Hour).UTC().Format(microTimeFormat)
+			l.Spec.RenewTime = &renew
+		})
+
+		ok, err = lock.TryAcquire(ctx, "director", "replica-2", 15*time.Second)
+		require.NoError(t, err)
+		require.False(t, ok, "the expiry counts from the time this replica saw the lease change")
+
+		now := time.Now()
+		lock.now = func() time.Time { return now.Add(16 * time.Second) }
+		ok, err = lock.TryAcquire(ctx, "director", "replica-2", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		current := api.lease("director")
+		require.Equal(t, "replica-2", *current.Spec.HolderIdentity)
+		require.Equal(t, int32(1), *current.Spec.LeaseTransitions)
+	})
+
+	t.Run("it should round the lease duration up to whole seconds", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		lock := NewKubernetesLock(api.server.URL, "", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 1900*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, int32(2), *api.lease("director").Spec.LeaseDurationSeconds)
+	})
+
+	t.Run("it should not acquire the lease on a conflict", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		lock := NewKubernetesLock(api.server.URL, "", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		api.conflict = true
+		ok, err = lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("it should release the lease of the holder only", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		lock := NewKubernetesLock(api.server.URL, "", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.NoError(t, lock.Release(ctx, "director", "replica-2"))
+		require.Equal(t, "replica-1", *api.lease("director").Spec.HolderIdentity)
+
+		require.NoError(t, lock.Release(ctx, "director", "replica-1"))
+		require.Empty(t, stringValue(api.lease("director").Spec.HolderIdentity))
+
+		ok, err = lock.TryAcquire(ctx, "director", "replica-2", 15*time.Second)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("it should return the error of the API server", func(t *testing.T) {
+		api := newFakeLeaseAPI(t)
+		api.status = http.StatusForbidden
+		lock := NewKubernetesLock(api.server.URL, "", "default", api.server.Client())
+
+		ok, err := lock.TryAcquire(ctx, "director", "replica-1", 15*time.Second)
+		require.Error(t, err)
+		require.False(t, ok)
+	})
+}
+
+// fakeLeaseAPI serves the Lease endpoints of the API server for a single namespace
+type fakeLeaseAPI struct {
+	mux           sync.Mutex
+	server        *httptest.Server
+	leases        map[string]*lease
+	version       int
+	conflict      bool
+	status        int
+	authorization string
+}
+
+func newFakeLeaseAPI(t *testing.T) *fakeLeaseAPI {
+	api := &fakeLeaseAPI{leases: map[string]*lease{}}
+	api.server = httptest.NewServer(http.HandlerFunc(api.handle))
+	t.Cleanup(api.server.Close)
+
+	return api
+}
+
+func (a *fakeLeaseAPI) handle(w http.ResponseWriter, r *http.Request) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	a.authorization = r.Header.Get("Authorization")
+	if a.status != 0 {
+		w.WriteHeader(a.status)
+		return
+	}
+
+	const prefix = "/apis/coordination.k8s.io/v1/namespaces/default/leases"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		current, ok := a.leases[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(current)
+	case http.MethodPost, http.MethodPut:
+		obj := &lease{}
+		if err := json.NewDecoder(r.Body).Decode(obj); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		current, exists := a.leases[obj.Metadata.Name]
+		if a.conflict || (r.Method == http.MethodPost && exists) ||
+			(r.Method == http.MethodPut && (!exists || current.Metadata.ResourceVersion != obj.Metadata.ResourceVersion)) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+
+		a.version++
+		obj.Metadata.ResourceVersion = strconv.Itoa(a.version)
+		a.leases[obj.Metadata.Name] = obj
+
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(obj)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *fakeLeaseAPI) lease(name string) lease {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	return *a.leases[name]
+}
+
+func (a *fakeLeaseAPI) update(name string, update func(l *lease)) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	update(a.leases[name])
+}
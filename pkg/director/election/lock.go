@@ -0,0 +1,71 @@
+package election
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Lock is the backend that keeps the leases held by the director replicas
+type Lock interface {
+	// TryAcquire acquires the lease for the holder or renews it if the holder already owns it.
+	// It returns false if the lease is held by another holder and has not expired.
+	TryAcquire(ctx context.Context, name, holder string, duration time.Duration) (bool, error)
+	// Release gives up the lease if it is owned by the holder
+	Release(ctx context.Context, name, holder string) error
+}
+
+type memoryLease struct {
+	holder  string
+	expires time.Time
+}
+
+// MemoryLock keeps the leases in memory. It is only useful for replicas running in the same process, i.e. tests.
+type MemoryLock struct {
+	mux    sync.Mutex
+	leases map[string]memoryLease
+	now    func() time.Time
+}
+
+func NewMemoryLock() *MemoryLock {
+	return &MemoryLock{
+		leases: map[string]memoryLease{},
+		now:    time.Now,
+	}
+}
+
+func (l *MemoryLock) TryAcquire(ctx context.Context, name, holder string, duration time.Duration) (bool, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+	if lease, ok := l.leases[name]; ok && lease.holder != holder && now.Before(lease.expires) {
+		return false, nil
+	}
+
+	l.leases[name] = memoryLease{holder: holder, expires: now.Add(duration)}
+	return true, nil
+}
+
+func (l *MemoryLock) Release(ctx context.Context, name, holder string) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if lease, ok := l.leases[name]; ok && lease.holder == holder {
+		delete(l.leases, name)
+	}
+
+	return nil
+}
+
+// Holder returns the current holder of the lease, empty if it is free or expired
+func (l *MemoryLock) Holder(name string) string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if lease, ok := l.leases[name]; ok && l.now().Before(lease.expires) {
+		return lease.holder
+	}
+
+	return ""
+}
@@ -0,0 +1,384 @@
+package openmatch
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"time"
+)
+
+// BackfillService is the subset of the Open Match FrontendServiceClient used to keep the backfills of the GameServers
+type BackfillService interface {
+	AcknowledgeBackfill(ctx context.Context, in *pb.AcknowledgeBackfillRequest, opts ...grpc.CallOption) (*pb.AcknowledgeBackfillResponse, error)
+	UpdateBackfill(ctx context.Context, in *pb.UpdateBackfillRequest, opts ...grpc.CallOption) (*pb.Backfill, error)
+	DeleteBackfill(ctx context.Context, in *pb.DeleteBackfillRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+// Backfills keeps the backfills of the allocated GameServers with free slots. The director acknowledges them on behalf
+// of the GameServers, which assigns the tickets matched into them, and updates their open slots from the capacity
+// reported by Agones. A backfill is deleted once its GameServer is full or gone.
+type Backfills struct {
+	Service BackfillService
+	Lookup  allocator.GameServerLookup
+	// Interval between reconciles of the backfills. Open Match deletes the backfills not acknowledged in time.
+	Interval time.Duration
+	// PendingTimeout is the time the players assigned to a backfill have to show up on the Agones count. Players not
+	// counted by then are considered gone and their slots are open again.
+	PendingTimeout time.Duration
+
+	mux     sync.Mutex
+	tracked map[string]*trackedBackfill
+}
+
+type trackedBackfill struct {
+	backfill   *pb.Backfill
+	gameserver *extpb.GameServerAssignment
+	// pending are the players assigned that Agones doesn't count yet, oldest first
+	pending []pendingPlayers
+	// available is the capacity Agones reported on the previous reconcile, if known
+	available      int64
+	availableKnown bool
+}
+
+type pendingPlayers struct {
+	count    int64
+	assigned time.Time
+}
+
+func NewBackfills(service BackfillService, lookup allocator.GameServerLookup, interval, pendingTimeout time.Duration) (*Backfills, error) {
+	if interval <= 0 {
+		return nil, errors.New("backfill interval must be higher than zero")
+	}
+
+	if pendingTimeout <= 0 {
+		return nil, errors.New("backfill pending timeout must be higher than zero")
+	}
+
+	return &Backfills{
+		Service:        service,
+		Lookup:         lookup,
+		Interval:       interval,
+		PendingTimeout: pendingTimeout,
+		tracked:        map[string]*trackedBackfill{},
+	}, nil
+}
+
+// Bind binds the backfill created by Open Match for a new match to the GameServer allocated for it.
+// The backfill is deleted if no GameServer was allocated.
+func (b *Backfills) Bind(ctx context.Context, backfill *pb.Backfill, groups []*pb.AssignmentGroup) error {
+	if len(backfill.GetId()) == 0 {
+		return errors.New("backfill id is not set")
+	}
+
+	var gs *extpb.GameServerAssignment
+	var players int64
+	for _, group := range groups {
+		if len(group.GetAssignment().GetConnection()) == 0 {
+			continue
+		}
+
+		assignment, err := extensions.GetGameServer(group.Assignment)
+		if err != nil {
+			return errors.Wrapf(err, "backfill %s", backfill.GetId())
+		}
+
+		gs = assignment
+		players += extensions.GetPlayers(group.Assignment, int64(len(group.TicketIds)))
+	}
+
+	if gs == nil || len(gs.GetName()) == 0 {
+		return b.delete(ctx, backfill.GetId())
+	}
+
+	bound := proto.Clone(backfill).(*pb.Backfill)
+	if err := extensions.GameServer.Set(bound, &extpb.GameServerAssignment{
+		Name:      gs.GetName(),
+		Namespace: gs.GetNamespace(),
+		Address:   gs.GetAddress(),
+		Ports:     gs.GetPorts(),
+		Region:    gs.GetRegion(),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to set gameserver extension on backfill %s", backfill.GetId())
+	}
+
+	tracked := &trackedBackfill{backfill: bound, gameserver: gs}
+	tracked.addPending(players, time.Now())
+
+	b.mux.Lock()
+	b.tracked[bound.GetId()] = tracked
+	b.mux.Unlock()
+
+	return b.reconcile(ctx, bound.GetId())
+}
+
+// Acknowledge assigns the tickets matched into the backfill to its GameServer. It returns the number of tickets assigned.
+func (b *Backfills) Acknowledge(ctx context.Context, backfill *pb.Backfill) (int, error) {
+	tracked, err := b.track(backfill)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mux.Lock()
+	current := tracked.backfill
+	b.mux.Unlock()
+
+	assignment, err := backfillAssignment(current, tracked.gameserver)
+	if err != nil {
+		return 0, errors.Wrapf(err, "backfill %s", backfill.GetId())
+	}
+
+	resp, err := b.Service.AcknowledgeBackfill(ctx, &pb.AcknowledgeBackfillRequest{
+		BackfillId: backfill.GetId(),
+		Assignment: assignment,
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to acknowledge backfill %s", backfill.GetId())
+	}
+
+	b.mux.Lock()
+	tracked.addPending(extensions.CountPlayers(resp.GetTickets()...), time.Now())
+	if resp.GetBackfill() != nil {
+		tracked.backfill = resp.GetBackfill()
+	}
+	b.mux.Unlock()
+
+	return len(resp.GetTickets()), nil
+}
+
+// Reconcile acknowledges every backfill and sets its open slots to the capacity Agones reports for its GameServer
+func (b *Backfills) Reconcile(ctx context.Context) error {
+	ids := b.ids()
+
+	var failed int
+	for _, id := range ids {
+		if err := b.reconcile(ctx, id); err != nil {
+			failed++
+			runtime.Logger().WithField("component", "backfill").Warn(err.Error())
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to reconcile %d of %d backfills", failed, len(ids))
+	}
+
+	return nil
+}
+
+// Run reconciles the backfills every Interval until the context is cancelled. The backfills left are deleted on exit,
+// no one keeps them once the director is gone.
+func (b *Backfills) Run(ctx context.Context) {
+	logger := runtime.Logger().WithFields(logrus.Fields{
+		"component": "director",
+		"command":   "backfill",
+	})
+
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.Reconcile(ctx); err != nil {
+				logger.Warn(err.Error())
+			}
+		case <-ctx.Done():
+			ctxDelete, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+			defer cancel()
+
+			for _, id := range b.ids() {
+				if err := b.delete(ctxDelete, id); err != nil {
+					logger.Warn(err.Error())
+				}
+			}
+			return
+		}
+	}
+}
+
+// Len returns the number of backfills kept
+func (b *Backfills) Len() int {
+	return len(b.ids())
+}
+
+func (b *Backfills) reconcile(ctx context.Context, id string) error {
+	b.mux.Lock()
+	tracked, ok := b.tracked[id]
+	var current *pb.Backfill
+	if ok {
+		current = tracked.backfill
+	}
+	b.mux.Unlock()
+	if !ok {
+		return nil
+	}
+
+	filter, err := extensions.GetFilter(current)
+	if err != nil {
+		return errors.Wrapf(err, "backfill %s does not have a valid filter extension", id)
+	}
+
+	policy, err := extensions.GetCapacityPolicy(current)
+	if err != nil {
+		return errors.Wrapf(err, "backfill %s does not have a valid capacity extension", id)
+	}
+
+	gs, err := b.Lookup.GetGameServer(ctx, tracked.gameserver.GetName(), filter)
+	if err == allocator.ErrGameServersNotFound {
+		return b.delete(ctx, id)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get gameserver %s of backfill %s", tracked.gameserver.GetName(), id)
+	}
+
+	// Tickets associated with the backfill are released by the update, they must be assigned first
+	if _, err := b.Acknowledge(ctx, current); err != nil {
+		return err
+	}
+
+	available, known := allocator.AvailableCapacity(gs, policy)
+
+	b.mux.Lock()
+	pending := tracked.settle(available, known, time.Now(), b.PendingTimeout)
+	backfill := proto.Clone(tracked.backfill).(*pb.Backfill)
+	b.mux.Unlock()
+
+	openSlots := extensions.GetOpenSlots(backfill)
+	if known {
+		openSlots = available - pending
+	}
+
+	if openSlots <= 0 {
+		return b.delete(ctx, id)
+	}
+
+	if err := extensions.OpenSlots.Set(backfill, &wrappers.Int64Value{Value: openSlots}); err != nil {
+		return err
+	}
+
+	updated, err := b.Service.UpdateBackfill(ctx, &pb.UpdateBackfillRequest{Backfill: backfill})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update backfill %s", id)
+	}
+
+	b.mux.Lock()
+	tracked.backfill = updated
+	b.mux.Unlock()
+
+	return nil
+}
+
+func (t *trackedBackfill) addPending(count int64, now time.Time) {
+	if count > 0 {
+		t.pending = append(t.pending, pendingPlayers{count: count, assigned: now})
+	}
+}
+
+// settle drops the pending players Agones counted since the previous reconcile, the room taken on the GameServer,
+// and the ones that didn't show up within the timeout. It returns the players still pending.
+func (t *trackedBackfill) settle(available int64, known bool, now time.Time, timeout time.Duration) int64 {
+	if known {
+		if t.availableKnown && available < t.available {
+			counted := t.available - available
+			for len(t.pending) > 0 && counted > 0 {
+				settled := min(counted, t.pending[0].count)
+				t.pending[0].count -= settled
+				counted -= settled
+				if t.pending[0].count == 0 {
+					t.pending = t.pending[1:]
+				}
+			}
+		}
+		t.available, t.availableKnown = available, true
+	}
+
+	var pending int64
+	kept := t.pending[:0]
+	for _, p := range t.pending {
+		if now.Sub(p.assigned) < timeout {
+			kept = append(kept, p)
+			pending += p.count
+		}
+	}
+	t.pending = kept
+
+	return pending
+}
+
+// track returns the backfill kept for the id. Backfills bound by another director are kept from the gameserver extension.
+func (b *Backfills) track(backfill *pb.Backfill) (*trackedBackfill, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if tracked, ok := b.tracked[backfill.GetId()]; ok {
+		return tracked, nil
+	}
+
+	gs, err := extensions.GetGameServer(backfill)
+	if err != nil {
+		return nil, errors.Wrapf(err, "backfill %s does not have a valid gameserver extension", backfill.GetId())
+	}
+
+	if len(gs.GetName()) == 0 {
+		return nil, errors.Errorf("backfill %s is not bound to a gameserver", backfill.GetId())
+	}
+
+	tracked := &trackedBackfill{backfill: backfill, gameserver: gs}
+	b.tracked[backfill.GetId()] = tracked
+	return tracked, nil
+}
+
+func (b *Backfills) delete(ctx context.Context, id string) error {
+	b.mux.Lock()
+	delete(b.tracked, id)
+	b.mux.Unlock()
+
+	if _, err := b.Service.DeleteBackfill(ctx, &pb.DeleteBackfillRequest{BackfillId: id}); err != nil {
+		return errors.Wrapf(err, "failed to delete backfill %s", id)
+	}
+
+	runtime.Logger().WithField("component", "backfill").Debugf("backfill %s deleted", id)
+	return nil
+}
+
+func (b *Backfills) ids() []string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	ids := make([]string, 0, len(b.tracked))
+	for id := range b.tracked {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// backfillAssignment builds the assignment of the tickets matched into the backfill of the GameServer
+func backfillAssignment(backfill *pb.Backfill, gs *extpb.GameServerAssignment) (*pb.Assignment, error) {
+	template, err := extensions.GetConnectionTemplate(backfill)
+	if err != nil {
+		return nil, errors.Wrap(err, "the backfill does not have a valid connection extension")
+	}
+
+	connection, err := allocator.FormatConnection(template, gs.GetAddress(), gs.GetPorts())
+	if err != nil {
+		return nil, errors.Wrapf(err, "gameserver %s", gs.GetName())
+	}
+
+	assignment := &pb.Assignment{Connection: connection}
+	if err := extensions.GameServer.Set(assignment, gs); err != nil {
+		return nil, err
+	}
+
+	return assignment, nil
+}
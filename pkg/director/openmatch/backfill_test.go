@@ -0,0 +1,269 @@
+package openmatch
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackfills_Bind(t *testing.T) {
+	t.Run("it should bind the backfill to the gameserver and set the open slots reported by Agones", func(t *testing.T) {
+		service := newFakeBackfillService()
+		lookup := &fakeLookup{gameservers: map[string]*allocator.GameServer{"gs-1": gameServer("gs-1", 10, 2)}}
+		backfills, err := NewBackfills(service, lookup, time.Second, time.Minute)
+		require.NoError(t, err)
+
+		groups := []*pb.AssignmentGroup{assignedGroup(t, "gs-1", 3), {TicketIds: []string{"t9"}, Assignment: &pb.Assignment{}}}
+		require.NoError(t, backfills.Bind(context.Background(), newTestBackfill(t, "b1", 7), groups))
+
+		require.Equal(t, 1, backfills.Len())
+		require.Equal(t, []string{"ack:b1", "update:b1"}, service.calls)
+		require.Equal(t, "10.0.0.1:7777", service.acks[0].GetConnection())
+		require.Equal(t, int64(5), extensions.GetOpenSlots(service.updated["b1"]), "8 available on the gameserver minus the 3 players just assigned")
+
+		gs, err := extensions.GetGameServer(service.updated["b1"])
+		require.NoError(t, err)
+		require.Equal(t, "gs-1", gs.GetName())
+	})
+
+	t.Run("it should delete the backfill if no gameserver was allocated", func(t *testing.T) {
+		service := newFakeBackfillService()
+		backfills, err := NewBackfills(service, &fakeLookup{}, time.Second, time.Minute)
+		require.NoError(t, err)
+
+		groups := []*pb.AssignmentGroup{{TicketIds: []string{"t1"}, Assignment: &pb.Assignment{}}}
+		require.NoError(t, backfills.Bind(context.Background(), newTestBackfill(t, "b1", 7), groups))
+
+		require.Equal(t, 0, backfills.Len())
+		require.Equal(t, []string{"delete:b1"}, service.calls)
+	})
+}
+
+func TestBackfills_Reconcile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		gameserver *allocator.GameServer
+		wantCalls  []string
+		wantSlots  int64
+	}{
+		{
+			name:       "it should update the open slots from the gameserver capacity",
+			gameserver: gameServer("gs-1", 10, 6),
+			wantCalls:  []string{"ack:b1", "update:b1"},
+			wantSlots:  4,
+		},
+		{
+			name:       "it should delete the backfill when the gameserver is full",
+			gameserver: gameServer("gs-1", 10, 10),
+			wantCalls:  []string{"ack:b1", "delete:b1"},
+		},
+		{
+			name:      "it should delete the backfill when the gameserver is gone",
+			wantCalls: []string{"delete:b1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeBackfillService()
+			lookup := &fakeLookup{gameservers: map[string]*allocator.GameServer{}}
+			if tc.gameserver != nil {
+				lookup.gameservers[tc.gameserver.Name] = tc.gameserver
+			}
+
+			backfills, err := NewBackfills(service, lookup, time.Second, time.Minute)
+			require.NoError(t, err)
+
+			backfill := boundBackfill(t, "b1", "gs-1")
+			_, err = backfills.track(backfill)
+			require.NoError(t, err)
+
+			require.NoError(t, backfills.Reconcile(context.Background()))
+			require.Equal(t, tc.wantCalls, service.calls)
+			if tc.wantSlots > 0 {
+				require.Equal(t, tc.wantSlots, extensions.GetOpenSlots(service.updated["b1"]))
+				require.Equal(t, 1, backfills.Len())
+			} else {
+				require.Equal(t, 0, backfills.Len())
+			}
+		})
+	}
+}
+
+func TestAssignTickets_Backfill(t *testing.T) {
+	service := newFakeBackfillService()
+	service.assigned = []*pb.Ticket{{Id: "t1"}, {Id: "t2"}}
+	backfills, err := NewBackfills(service, &fakeLookup{}, time.Second, time.Minute)
+	require.NoError(t, err)
+
+	backend := &fakeBackend{}
+	gsAllocator := &fakeAllocator{}
+	stats := director.NewStats()
+
+	matches := make(chan *pb.Match, 1)
+	matches <- &pb.Match{MatchId: "m1", Backfill: boundBackfill(t, "b1", "gs-1"), Tickets: []*pb.Ticket{{Id: "t1"}, {Id: "t2"}}}
+	close(matches)
+
+	err = AssignTickets(backend, allocator.NewAllocatorService(gsAllocator), stats, 1, backfills)(context.Background(), matches)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(1), stats.Snapshot().MatchesAssigned)
+	require.Equal(t, int64(0), gsAllocator.maxRunning.Load(), "matches into a backfill must not allocate a gameserver")
+	require.Equal(t, []string{"ack:b1"}, service.calls)
+	require.Empty(t, backend.released)
+}
+
+func TestBackfills_Reconcile_Pending(t *testing.T) {
+	t.Run("it should keep the assigned players pending until Agones counts them", func(t *testing.T) {
+		service := newFakeBackfillService()
+		lookup := &fakeLookup{gameservers: map[string]*allocator.GameServer{"gs-1": gameServer("gs-1", 10, 2)}}
+		backfills, err := NewBackfills(service, lookup, time.Second, time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, backfills.Bind(context.Background(), newTestBackfill(t, "b1", 7), []*pb.AssignmentGroup{assignedGroup(t, "gs-1", 3)}))
+		require.Equal(t, int64(5), extensions.GetOpenSlots(service.updated["b1"]))
+
+		// The players didn't connect yet, Agones still counts 2
+		require.NoError(t, backfills.Reconcile(context.Background()))
+		require.Equal(t, int64(5), extensions.GetOpenSlots(service.updated["b1"]), "the slots of the pending players are not open again")
+
+		lookup.gameservers["gs-1"] = gameServer("gs-1", 10, 4)
+		require.NoError(t, backfills.Reconcile(context.Background()))
+		require.Equal(t, int64(5), extensions.GetOpenSlots(service.updated["b1"]), "2 players counted, 1 still pending")
+
+		lookup.gameservers["gs-1"] = gameServer("gs-1", 10, 5)
+		require.NoError(t, backfills.Reconcile(context.Background()))
+		require.Equal(t, int64(5), extensions.GetOpenSlots(service.updated["b1"]))
+
+		lookup.gameservers["gs-1"] = gameServer("gs-1", 10, 3)
+		require.NoError(t, backfills.Reconcile(context.Background()))
+		require.Equal(t, int64(7), extensions.GetOpenSlots(service.updated["b1"]), "players leaving open their slots")
+	})
+
+	t.Run("it should open the slots of the players not counted within the timeout", func(t *testing.T) {
+		service := newFakeBackfillService()
+		lookup := &fakeLookup{gameservers: map[string]*allocator.GameServer{"gs-1": gameServer("gs-1", 10, 2)}}
+		backfills, err := NewBackfills(service, lookup, time.Second, 20*time.Millisecond)
+		require.NoError(t, err)
+
+		require.NoError(t, backfills.Bind(context.Background(), newTestBackfill(t, "b1", 7), []*pb.AssignmentGroup{assignedGroup(t, "gs-1", 3)}))
+		require.Equal(t, int64(5), extensions.GetOpenSlots(service.updated["b1"]))
+
+		time.Sleep(30 * time.Millisecond)
+		require.NoError(t, backfills.Reconcile(context.Background()))
+		require.Equal(t, int64(8), extensions.GetOpenSlots(service.updated["b1"]))
+	})
+}
+
+func TestNewBackfills(t *testing.T) {
+	_, err := NewBackfills(newFakeBackfillService(), &fakeLookup{}, 0, time.Minute)
+	require.Error(t, err)
+
+	_, err = NewBackfills(newFakeBackfillService(), &fakeLookup{}, time.Second, 0)
+	require.Error(t, err)
+}
+
+func newTestBackfill(t *testing.T, id string, openSlots int64) *pb.Backfill {
+	backfill := &pb.Backfill{Id: id}
+
+	filter := extensions.AllocatorFilterExtension{Labels: map[string]string{"world": "Dune"}}
+	require.NoError(t, extensions.Filter.Set(backfill, filter.Proto()))
+	require.NoError(t, extensions.Connection.Set(backfill, &extpb.ConnectionFormat{Template: "{address}:{port}"}))
+	require.NoError(t, extensions.OpenSlots.Set(backfill, &wrappers.Int64Value{Value: openSlots}))
+
+	return backfill
+}
+
+func boundBackfill(t *testing.T, id, gameserver string) *pb.Backfill {
+	backfill := newTestBackfill(t, id, 2)
+	require.NoError(t, extensions.GameServer.Set(backfill, &extpb.GameServerAssignment{
+		Name:    gameserver,
+		Address: "10.0.0.1",
+		Ports:   []*extpb.GameServerPort{{Name: "game", Port: 7777}},
+	}))
+
+	return backfill
+}
+
+func assignedGroup(t *testing.T, gameserver string, players int64) *pb.AssignmentGroup {
+	assignment := &pb.Assignment{Connection: "10.0.0.1:7777"}
+	require.NoError(t, extensions.GameServer.Set(assignment, &extpb.GameServerAssignment{
+		Name:    gameserver,
+		Address: "10.0.0.1",
+		Ports:   []*extpb.GameServerPort{{Name: "game", Port: 7777}},
+	}))
+	require.NoError(t, extensions.Players.Set(assignment, &wrappers.Int64Value{Value: players}))
+
+	return &pb.AssignmentGroup{TicketIds: []string{"t1", "t2"}, Assignment: assignment}
+}
+
+func gameServer(name string, capacity, count int64) *allocator.GameServer {
+	return &allocator.GameServer{
+		Name: name,
+		Status: &allocator.GameServerStatus{
+			Address: "10.0.0.1",
+			Players: &allocator.PlayerStatus{Capacity: capacity, Count: count},
+		},
+	}
+}
+
+type fakeLookup struct {
+	gameservers map[string]*allocator.GameServer
+}
+
+func (f *fakeLookup) GetGameServer(ctx context.Context, name string, filter *extensions.AllocatorFilterExtension) (*allocator.GameServer, error) {
+	if gs, ok := f.gameservers[name]; ok {
+		return gs, nil
+	}
+
+	return nil, allocator.ErrGameServersNotFound
+}
+
+type fakeBackfillService struct {
+	mux      sync.Mutex
+	calls    []string
+	acks     []*pb.Assignment
+	updated  map[string]*pb.Backfill
+	assigned []*pb.Ticket
+}
+
+func newFakeBackfillService() *fakeBackfillService {
+	return &fakeBackfillService{updated: map[string]*pb.Backfill{}}
+}
+
+func (f *fakeBackfillService) AcknowledgeBackfill(ctx context.Context, in *pb.AcknowledgeBackfillRequest, opts ...grpc.CallOption) (*pb.AcknowledgeBackfillResponse, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.calls = append(f.calls, "ack:"+in.GetBackfillId())
+	f.acks = append(f.acks, in.GetAssignment())
+	return &pb.AcknowledgeBackfillResponse{Tickets: f.assigned}, nil
+}
+
+func (f *fakeBackfillService) UpdateBackfill(ctx context.Context, in *pb.UpdateBackfillRequest, opts ...grpc.CallOption) (*pb.Backfill, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.calls = append(f.calls, "update:"+in.GetBackfill().GetId())
+	f.updated[in.GetBackfill().GetId()] = in.GetBackfill()
+	return proto.Clone(in.GetBackfill()).(*pb.Backfill), nil
+}
+
+func (f *fakeBackfillService) DeleteBackfill(ctx context.Context, in *pb.DeleteBackfillRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.calls = append(f.calls, "delete:"+in.GetBackfillId())
+	return &emptypb.Empty{}, nil
+}
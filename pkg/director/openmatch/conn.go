@@ -2,19 +2,35 @@ package openmatch
 
 import (
 	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 )
 
-func ConnFuncInsecure() (*grpc.ClientConn, error) {
-	conn, err := grpc.Dial(config.OpenMatch().BackEnd, grpc.WithInsecure())
-	if err != nil {
-		return nil, err
-	}
+// ConnFuncInsecure dials the Open Match Backend without TLS, ignoring the TLS settings of the OpenMatchConnConfig
+func ConnFuncInsecure(omConfig config.OpenMatchConnConfig) ConnFunc {
+	omConfig.TLSEnabled = false
+	return ConnFuncFromConfig(omConfig)
+}
 
-	return conn, nil
+// ConnFuncSecure dials the Open Match Backend using the TLS settings of the OpenMatchConnConfig
+func ConnFuncSecure(omConfig config.OpenMatchConnConfig) ConnFunc {
+	omConfig.TLSEnabled = true
+	return ConnFuncFromConfig(omConfig)
 }
 
-// TODO: Implement secure dialer
-func ConnFuncSecure() (*grpc.ClientConn, error) {
-	panic("implement me")
+// ConnFuncFromConfig dials the Open Match Backend with TLS only if it is enabled on the OpenMatchConnConfig
+func ConnFuncFromConfig(omConfig config.OpenMatchConnConfig) ConnFunc {
+	return func() (*grpc.ClientConn, error) {
+		creds, err := omConfig.DialOption()
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := grpc.Dial(omConfig.BackEnd, creds)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to dial Open Match Backend on %s", omConfig.BackEnd)
+		}
+
+		return conn, nil
+	}
 }
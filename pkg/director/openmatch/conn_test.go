@@ -0,0 +1,22 @@
+package openmatch
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConnFuncInsecure(t *testing.T) {
+	omConfig := config.OpenMatchConnConfig{BackEnd: "127.0.0.1:50505", TLSEnabled: true, TLSCaCertFile: "missing-ca.crt"}
+
+	t.Run("it should ignore the TLS settings", func(t *testing.T) {
+		conn, err := ConnFuncInsecure(omConfig)()
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+	})
+
+	t.Run("it should keep the TLS settings of the config", func(t *testing.T) {
+		_, err := ConnFuncFromConfig(omConfig)()
+		require.Error(t, err)
+	})
+}
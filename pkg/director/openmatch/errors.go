@@ -0,0 +1,55 @@
+package openmatch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchResult is the outcome of allocating and assigning a single match
+type MatchResult struct {
+	MatchID  string
+	Assigned int
+	Err      error
+}
+
+// AssignError summarizes the matches of a batch. It is only returned when at least one match failed.
+type AssignError struct {
+	Results []MatchResult
+}
+
+func (e *AssignError) Add(result MatchResult) {
+	e.Results = append(e.Results, result)
+}
+
+// Failed returns the number of matches that failed
+func (e *AssignError) Failed() int {
+	var failed int
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+// Failures returns the results of the matches that failed
+func (e *AssignError) Failures() []MatchResult {
+	var failures []MatchResult
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failures = append(failures, r)
+		}
+	}
+
+	return failures
+}
+
+func (e *AssignError) Error() string {
+	var causes []string
+	for _, r := range e.Failures() {
+		causes = append(causes, fmt.Sprintf("matchId %s: %s", r.MatchID, r.Err))
+	}
+
+	return fmt.Sprintf("%d of %d matches failed: %s", e.Failed(), len(e.Results), strings.Join(causes, ", "))
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
-	"github.com/Octops/agones-discover-openmatch/pkg/config"
 	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/Octops/agones-discover-openmatch/pkg/director/election"
 	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
@@ -15,26 +17,69 @@ import (
 	"math/rand"
 	"open-match.dev/open-match/pkg/pb"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// TeamArg is the ticket string arg used to split the tickets of a Match into teams
+	TeamArg = "team"
+
+	releaseTimeout = 5 * time.Second
+)
+
+type Fetcher interface {
+	FetchMatches(ctx context.Context, in *pb.FetchMatchesRequest, opts ...grpc.CallOption) (pb.BackendService_FetchMatchesClient, error)
+}
+
+// Backend is the subset of the Open Match BackendServiceClient used to assign and release tickets
+type Backend interface {
+	Assigner
+	Releaser
+}
+
 type Assigner interface {
 	AssignTickets(ctx context.Context, in *pb.AssignTicketsRequest, opts ...grpc.CallOption) (*pb.AssignTicketsResponse, error)
 }
 
+type Releaser interface {
+	ReleaseTickets(ctx context.Context, in *pb.ReleaseTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseTicketsResponse, error)
+	ReleaseAllTickets(ctx context.Context, in *pb.ReleaseAllTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseAllTicketsResponse, error)
+}
+
 type MatchFunctionServer struct {
 	HostName string
 	Port     int32
 }
 
-type FetchResponse struct {
-	Matches []*pb.Match
-	Err     error
+// ProfileOptions holds the settings the director attaches to every profile as extensions
+type ProfileOptions struct {
+	ConnectionTemplate string
+	CapacityPolicy     *extpb.CapacityPolicy
+}
+
+// DirectorOptions holds the settings of a director instance
+type DirectorOptions struct {
+	Profile ProfileOptions
+	// MatchFunction is the server Open Match calls to run the match function of the profiles
+	MatchFunction MatchFunctionServer
+	// FetchTimeout bounds every FetchMatches stream, zero means no deadline
+	FetchTimeout time.Duration
+	// AllocationWorkers is the number of matches of a profile allocated at the same time
+	AllocationWorkers int
+	// Election makes the director run only for the profiles of the shard held by this replica. Nil runs every profile.
+	Election *election.Elector
+	// Backfills keeps the backfills of the GameServers allocated for matches with free slots. Nil disables backfills.
+	Backfills *Backfills
 }
 
 type ConnFunc func() (*grpc.ClientConn, error)
 
-func RunDirector(ctx context.Context, logger *logrus.Entry, dial ConnFunc, interval string, allocatorService *allocator.AllocatorService) error {
+func RunDirector(ctx context.Context, logger *logrus.Entry, dial ConnFunc, scheduler *director.Scheduler, options DirectorOptions, allocatorService *allocator.AllocatorService) error {
+	if err := allocator.ValidateConnectionTemplate(options.Profile.ConnectionTemplate); err != nil {
+		return err
+	}
+
 	conn, err := dial()
 	if err != nil {
 		return errors.Wrap(err, "failed to connect to Open Match Backend")
@@ -43,15 +88,48 @@ func RunDirector(ctx context.Context, logger *logrus.Entry, dial ConnFunc, inter
 	defer conn.Close()
 	client := pb.NewBackendServiceClient(conn)
 
-	fetch := FetchMatches(client, MatchFunctionServer{
-		HostName: config.OpenMatch().MatchFunctionHost,
-		Port:     config.OpenMatch().MatchFunctionPort,
-	})
+	fetch := FetchMatches(client, options.MatchFunction, options.FetchTimeout)
+
+	stats := director.NewStats()
+	scheduler.Stats = stats
+	assign := AssignTickets(client, allocatorService, stats, options.AllocationWorkers, options.Backfills)
+	profiles := GenerateProfiles(options.Profile)
+
+	// Backfills are kept alive while the director runs and deleted once it stops
+	if options.Backfills != nil {
+		ctxBackfills, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			options.Backfills.Run(ctxBackfills)
+		}()
+
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	run := director.DirectorFunc(scheduler.Run)
+	if options.Election != nil {
+		run = election.Elect(options.Election, run)
+	}
+
+	err = run(ctx, profiles, fetch, assign)
 
-	assign := AssignTickets(client, allocatorService)
-	profiles := GenerateProfiles()
+	// Tickets pending release go back to the pool right away instead of waiting for the Open Match pending release timeout.
+	// Other replicas may be assigning tickets when the election is on, so they are left to the pending release timeout.
+	if options.Election == nil {
+		ctxRelease, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
 
-	if err := director.Run(interval)(ctx, profiles, fetch, assign); err != nil {
+		if _, errRelease := client.ReleaseAllTickets(ctxRelease, &pb.ReleaseAllTicketsRequest{}); errRelease != nil {
+			logger.Warn(errors.Wrap(errRelease, "failed to release all tickets").Error())
+		}
+	}
+
+	logger.Infof("director stopped, %s", stats.Snapshot())
+	if err != nil {
 		logger.Error(errors.Wrap(err, "error running director"))
 		return err
 	}
@@ -59,29 +137,43 @@ func RunDirector(ctx context.Context, logger *logrus.Entry, dial ConnFunc, inter
 	return nil
 }
 
-func FetchMatches(client pb.BackendServiceClient, matchFunctionServer MatchFunctionServer) director.FetchMatchesFunc {
-	return func(ctx context.Context, profile *pb.MatchProfile) ([]*pb.Match, error) {
+// FetchMatches streams the matches of the profile as they arrive from Open Match. The timeout bounds the
+// whole stream, zero means the stream is only bound by the context. Matches received before the deadline are kept.
+func FetchMatches(client Fetcher, matchFunctionServer MatchFunctionServer, timeout time.Duration) director.FetchMatchesFunc {
+	return func(ctx context.Context, profile *pb.MatchProfile, matches chan<- *pb.Match) error {
 		logger := runtime.Logger().WithFields(logrus.Fields{
 			"component": "director",
 			"command":   "fetch",
 		})
-		ctxFetch, cancel := context.WithTimeout(ctx, time.Second)
-		defer cancel()
 
-		fetchResponse := FetchResponse{}
-		go func(p *pb.MatchProfile) {
+		ctxFetch := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctxFetch, cancel = context.WithTimeout(ctx, timeout)
 			defer cancel()
-			if fetchResponse.Matches, fetchResponse.Err = fetchMatches(ctxFetch, client, profile, matchFunctionServer); fetchResponse.Err != nil {
-				logger.Error(errors.Wrap(fetchResponse.Err, "failed to fetch matches from Open Match Backend"))
+		}
+
+		// Sending is bound by the parent context so a match already received is never dropped because of the fetch deadline
+		send := func(match *pb.Match) error {
+			select {
+			case matches <- match:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
 			}
-		}(profile)
+		}
 
-		<-ctxFetch.Done()
-		return fetchResponse.Matches, fetchResponse.Err
+		fetched, err := fetchMatches(ctxFetch, client, profile, matchFunctionServer, send)
+		logger.Debugf("profile %s fetched %d matches", profile.GetName(), fetched)
+		if err != nil {
+			return errors.Wrap(err, "failed to fetch matches from Open Match Backend")
+		}
+
+		return nil
 	}
 }
 
-func fetchMatches(ctx context.Context, client pb.BackendServiceClient, profile *pb.MatchProfile, matchFunctionServer MatchFunctionServer) ([]*pb.Match, error) {
+func fetchMatches(ctx context.Context, client Fetcher, profile *pb.MatchProfile, matchFunctionServer MatchFunctionServer, send func(match *pb.Match) error) (int, error) {
 	req := &pb.FetchMatchesRequest{
 		Config: &pb.FunctionConfig{
 			Host: matchFunctionServer.HostName,
@@ -99,10 +191,10 @@ func fetchMatches(ctx context.Context, client pb.BackendServiceClient, profile *
 	logger.Infof("fetching matches for profile %s", profile.GetName())
 	stream, err := client.FetchMatches(ctx, req)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to fetch matches")
+		return 0, errors.Wrapf(err, "failed to fetch matches")
 	}
 
-	var result []*pb.Match
+	var fetched int
 	for {
 		resp, err := stream.Recv()
 		if err == io.EOF {
@@ -110,43 +202,217 @@ func fetchMatches(ctx context.Context, client pb.BackendServiceClient, profile *
 		}
 
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to receive matches from stream")
+			return fetched, errors.Wrapf(err, "failed to receive matches from stream")
 		}
 
-		result = append(result, resp.GetMatch())
+		if err := send(resp.GetMatch()); err != nil {
+			return fetched, errors.Wrapf(err, "failed to send match %s", resp.GetMatch().GetMatchId())
+		}
+		fetched++
 	}
 
-	return result, nil
+	return fetched, nil
 }
 
-func AssignTickets(client pb.BackendServiceClient, allocatorService *allocator.AllocatorService) director.AssignFunc {
-	return func(ctx context.Context, matches []*pb.Match) error {
+// AssignTickets allocates and assigns the matches using up to workers goroutines. A failed match doesn't stop the others,
+// the failures are returned as an *AssignError once every match is handled. Matches into existing backfills are
+// assigned by acknowledging the backfill, nil backfills allocate a GameServer for every match.
+func AssignTickets(backend Backend, allocatorService *allocator.AllocatorService, stats *director.Stats, workers int, backfills *Backfills) director.AssignFunc {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return func(ctx context.Context, matches <-chan *pb.Match) error {
 		logger := runtime.Logger().WithFields(logrus.Fields{
 			"component": "director",
 			"command":   "assign",
 		})
 
-		for _, match := range matches {
-			req := CreateAssignTicketRequestForMatch(match)
+		results := make(chan MatchResult)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for match := range matches {
+					results <- assignMatch(ctx, logger, backend, allocatorService, stats, backfills, match)
+				}
+			}()
+		}
 
-			err := allocatorService.Allocate(ctx, req)
-			if err != nil {
-				err := errors.Wrapf(err, "failed to allocate servers for match %v", match.GetMatchId())
-				logger.Error(err)
-				return err
-			}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
 
-			// assignTickets is a noop and should not compromise the whole allocation
-			assigned, err := assignTickets(ctx, req, client)
-			if err != nil {
-				logger.Warnf(errors.Wrapf(err, "failed assign ticket for matchId %s", match.MatchId).Error())
-			}
+		summary := &AssignError{}
+		for result := range results {
+			summary.Add(result)
+		}
 
-			logger.Debugf("matchId %s got %d assignments assigned", match.MatchId, assigned)
+		if summary.Failed() == 0 {
+			return nil
 		}
 
-		return nil
+		return summary
+	}
+}
+
+func assignMatch(ctx context.Context, logger *logrus.Entry, backend Backend, allocatorService *allocator.AllocatorService, stats *director.Stats, backfills *Backfills, match *pb.Match) MatchResult {
+	result := MatchResult{MatchID: match.GetMatchId()}
+
+	if backfills != nil && match.GetBackfill() != nil && !match.GetAllocateGameserver() {
+		return assignBackfillMatch(ctx, logger, backend, stats, backfills, match)
+	}
+
+	req, err := CreateAssignTicketRequestForMatch(match)
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to create assign request for match %v", match.GetMatchId())
+		logger.Error(result.Err)
+		releaseMatchTickets(ctx, logger, backend, stats, match.GetMatchId(), TicketIDs(match.GetTickets()))
+		stats.AddMatchesFailed(1)
+		return result
+	}
+
+	err = allocatorService.Allocate(ctx, req)
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to allocate servers for match %v", match.GetMatchId())
+		logger.Error(result.Err)
+		releaseMatchTickets(ctx, logger, backend, stats, match.GetMatchId(), TicketIDs(match.GetTickets()))
+		stats.AddMatchesFailed(1)
+		return result
+	}
+
+	req.Assignments, err = SplitAssignmentGroupsByTeam(req.Assignments, match.GetTickets())
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to split assignments by team for match %v", match.GetMatchId())
+		logger.Error(result.Err)
+		releaseMatchTickets(ctx, logger, backend, stats, match.GetMatchId(), TicketIDs(match.GetTickets()))
+		stats.AddMatchesFailed(1)
+		return result
+	}
+
+	// Tickets of groups without a GameServer return to the pool so they can be part of the next matches
+	releaseMatchTickets(ctx, logger, backend, stats, match.GetMatchId(), UnassignedTicketIDs(req.Assignments))
+
+	if len(AssignedTicketIDs(req.Assignments)) == 0 {
+		result.Err = errors.Errorf("no GameServer allocated for match %v, its tickets were released", match.GetMatchId())
+		logger.Warn(result.Err)
+		stats.AddMatchesFailed(1)
+		return result
+	}
+
+	// Tickets left without assignment by a failed call are released, otherwise they are stuck until the pending release timeout
+	result.Assigned, err = assignTickets(ctx, req, backend)
+	if err != nil && result.Assigned == 0 {
+		result.Err = errors.Wrapf(err, "failed to assign tickets for match %v", match.GetMatchId())
+		logger.Error(result.Err)
+		releaseMatchTickets(ctx, logger, backend, stats, match.GetMatchId(), AssignedTicketIDs(req.Assignments))
+		stats.AddMatchesFailed(1)
+		return result
+	}
+
+	if err != nil {
+		logger.Warn(errors.Wrapf(err, "failed to assign some tickets for matchId %s", match.MatchId).Error())
+	}
+
+	if backfills != nil && match.GetBackfill() != nil {
+		if err := backfills.Bind(ctx, match.GetBackfill(), req.Assignments); err != nil {
+			logger.Warn(errors.Wrapf(err, "failed to bind backfill for matchId %s", match.MatchId).Error())
+		}
+	}
+
+	stats.AddMatchesAssigned(1)
+	logger.Debugf("matchId %s got %d assignments assigned", match.MatchId, result.Assigned)
+	return result
+}
+
+// assignBackfillMatch assigns the tickets matched into an existing backfill to the GameServer of the backfill
+func assignBackfillMatch(ctx context.Context, logger *logrus.Entry, releaser Releaser, stats *director.Stats, backfills *Backfills, match *pb.Match) MatchResult {
+	result := MatchResult{MatchID: match.GetMatchId()}
+
+	var err error
+	result.Assigned, err = backfills.Acknowledge(ctx, match.GetBackfill())
+	if err != nil {
+		result.Err = errors.Wrapf(err, "failed to acknowledge backfill for match %v", match.GetMatchId())
+		logger.Error(result.Err)
+		releaseMatchTickets(ctx, logger, releaser, stats, match.GetMatchId(), TicketIDs(match.GetTickets()))
+		stats.AddMatchesFailed(1)
+		return result
+	}
+
+	stats.AddMatchesAssigned(1)
+	logger.Debugf("matchId %s got %d tickets assigned by backfill %s", match.MatchId, result.Assigned, match.GetBackfill().GetId())
+	return result
+}
+
+func releaseMatchTickets(ctx context.Context, logger *logrus.Entry, releaser Releaser, stats *director.Stats, matchID string, ticketIDs []string) {
+	if len(ticketIDs) == 0 {
+		return
+	}
+
+	released, err := releaseTickets(ctx, ticketIDs, releaser)
+	if err != nil {
+		stats.AddTicketReleaseErrors(len(ticketIDs))
+		logger.Warn(errors.Wrapf(err, "failed to release tickets for matchId %s", matchID).Error())
+		return
+	}
+
+	stats.AddTicketsReleased(released)
+	logger.Infof("matchId %s released %d tickets", matchID, released)
+}
+
+func releaseTickets(ctx context.Context, ticketIDs []string, releaser Releaser) (int, error) {
+	if len(ticketIDs) == 0 {
+		return 0, nil
+	}
+
+	// The tickets are released even if the assign was cancelled by the drain deadline
+	ctxRelease, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	if _, err := releaser.ReleaseTickets(ctxRelease, &pb.ReleaseTicketsRequest{TicketIds: ticketIDs}); err != nil {
+		return 0, errors.Wrap(err, "failed to release tickets with BackendServiceClient")
+	}
+
+	return len(ticketIDs), nil
+}
+
+// UnassignedTicketIDs returns the tickets of the groups that have no Connection set
+func UnassignedTicketIDs(groups []*pb.AssignmentGroup) []string {
+	var ticketIDs []string
+
+	for _, g := range groups {
+		if len(g.GetAssignment().GetConnection()) == 0 {
+			ticketIDs = append(ticketIDs, g.TicketIds...)
+		}
+	}
+
+	return ticketIDs
+}
+
+// AssignedTicketIDs returns the tickets of the groups that have a Connection set
+func AssignedTicketIDs(groups []*pb.AssignmentGroup) []string {
+	var ticketIDs []string
+
+	for _, g := range groups {
+		if len(g.GetAssignment().GetConnection()) > 0 {
+			ticketIDs = append(ticketIDs, g.TicketIds...)
+		}
 	}
+
+	return ticketIDs
+}
+
+// TicketIDs returns the ids of the tickets
+func TicketIDs(tickets []*pb.Ticket) []string {
+	var ticketIDs []string
+
+	for _, t := range tickets {
+		ticketIDs = append(ticketIDs, t.GetId())
+	}
+
+	return ticketIDs
 }
 
 func assignTickets(ctx context.Context, req *pb.AssignTicketsRequest, assigner Assigner) (int, error) {
@@ -159,15 +425,29 @@ func assignTickets(ctx context.Context, req *pb.AssignTicketsRequest, assigner A
 	req.Assignments = assignments
 	resp, err := assigner.AssignTickets(ctx, req)
 	if err != nil {
-		return len(assignments), errors.Wrapf(err, "failed to assign tickets with BackendServiceClient")
+		return 0, errors.Wrapf(err, "failed to assign tickets with BackendServiceClient")
 	}
 
 	if len(resp.Failures) > 0 {
 		causes := []string{}
+		failed := map[string]bool{}
 		for _, failure := range resp.Failures {
 			causes = append(causes, fmt.Sprintf("ticketID %s: %s", failure.TicketId, failure.Cause.String()))
+			failed[failure.TicketId] = true
+		}
+
+		// Failures are reported by ticket, a group is assigned while any of its tickets is
+		var assigned int
+		for _, g := range assignments {
+			for _, id := range g.TicketIds {
+				if !failed[id] {
+					assigned++
+					break
+				}
+			}
 		}
-		return len(assignments) - len(resp.Failures), errors.Errorf("total failed assignments %d: %s", len(resp.Failures), strings.Join(causes, ","))
+
+		return assigned, errors.Errorf("total failed assignments %d: %s", len(resp.Failures), strings.Join(causes, ","))
 	}
 
 	return len(assignments), nil
@@ -186,29 +466,105 @@ func CleanUpAssignmentsWithoutConnection(group []*pb.AssignmentGroup) []*pb.Assi
 	return cleanedGroup
 }
 
-func CreateAssignTicketRequestForMatch(match *pb.Match) *pb.AssignTicketsRequest {
-	var ticketIDs []string
+func CreateAssignTicketRequestForMatch(match *pb.Match) (*pb.AssignTicketsRequest, error) {
+	ticketIDs := TicketIDs(match.GetTickets())
+
+	assignment := &pb.Assignment{
+		// Extensions field is used by the allocator to extract the filter
+		Extensions: extensions.Clone(match.Extensions),
+	}
+
+	// The gameserver extension is completed by the allocator and read by the game frontend
+	if err := extensions.GameServer.Set(assignment, &extpb.GameServerAssignment{MatchId: match.GetMatchId()}); err != nil {
+		return nil, err
+	}
 
-	for _, t := range match.GetTickets() {
-		ticketIDs = append(ticketIDs, t.Id)
+	// Party tickets take the room of all their players on the GameServer
+	if err := extensions.Players.Set(assignment, &wrappers.Int64Value{Value: extensions.CountPlayers(match.GetTickets()...)}); err != nil {
+		return nil, err
 	}
 
 	req := &pb.AssignTicketsRequest{
 		Assignments: []*pb.AssignmentGroup{
 			{
-				TicketIds: ticketIDs,
-				Assignment: &pb.Assignment{
-					// Extensions field is used by the allocator to extract the filter
-					Extensions: match.Extensions,
-				},
+				TicketIds:  ticketIDs,
+				Assignment: assignment,
 			},
 		},
 	}
-	return req
+	return req, nil
+}
+
+// SplitAssignmentGroupsByTeam splits every group with a Connection set into one group per team, based on the "team"
+// string arg of the tickets. Groups whose tickets have no team are kept as they are.
+func SplitAssignmentGroupsByTeam(groups []*pb.AssignmentGroup, tickets []*pb.Ticket) ([]*pb.AssignmentGroup, error) {
+	teams := map[string]string{}
+	sizes := map[string]int64{}
+	for _, t := range tickets {
+		sizes[t.GetId()] = extensions.GetPartySize(t)
+		if team, ok := t.GetSearchFields().GetStringArgs()[TeamArg]; ok {
+			teams[t.GetId()] = team
+		}
+	}
+
+	if len(teams) == 0 {
+		return groups, nil
+	}
+
+	var result []*pb.AssignmentGroup
+	for _, group := range groups {
+		if len(group.GetAssignment().GetConnection()) == 0 {
+			result = append(result, group)
+			continue
+		}
+
+		var order []string
+		byTeam := map[string][]string{}
+		for _, id := range group.TicketIds {
+			team := teams[id]
+			if _, ok := byTeam[team]; !ok {
+				order = append(order, team)
+			}
+			byTeam[team] = append(byTeam[team], id)
+		}
+
+		for _, team := range order {
+			assignment := &pb.Assignment{
+				Connection: group.Assignment.Connection,
+				Extensions: extensions.Clone(group.Assignment.Extensions),
+			}
+
+			gs, err := extensions.GetGameServer(assignment)
+			if err != nil {
+				return nil, err
+			}
+
+			gs.Team = team
+			if err := extensions.GameServer.Set(assignment, gs); err != nil {
+				return nil, err
+			}
+
+			var players int64
+			for _, id := range byTeam[team] {
+				players += sizes[id]
+			}
+			if err := extensions.Players.Set(assignment, &wrappers.Int64Value{Value: players}); err != nil {
+				return nil, err
+			}
+
+			result = append(result, &pb.AssignmentGroup{
+				TicketIds:  byTeam[team],
+				Assignment: assignment,
+			})
+		}
+	}
+
+	return result, nil
 }
 
 // generateProfiles generates profiles for every world assigning region, latency and skill randomly
-func GenerateProfiles() director.GenerateProfilesFunc {
+// The options set how the allocators build the Connection and check the GameServers capacity for the Matches of every profile
+func GenerateProfiles(options ProfileOptions) director.GenerateProfilesFunc {
 	return func() ([]*pb.MatchProfile, error) {
 		var profiles []*pb.MatchProfile
 
@@ -263,8 +619,20 @@ func GenerateProfiles() director.GenerateProfilesFunc {
 					},
 				}
 
-				// Multiples Extensions: extensions.WithAny(filter.Any()).WithAny(foo.Any()).WithAny(bar.Any()).Extensions()
-				profile.Extensions = extensions.WithAny(filter.Any()).Extensions()
+				// Multiples Extensions: one Set call per registered key
+				if err := extensions.Filter.Set(profile, filter.Proto()); err != nil {
+					return nil, errors.Wrapf(err, "failed to build filter extension for profile %s", profile.Name)
+				}
+
+				if err := extensions.Connection.Set(profile, &extpb.ConnectionFormat{Template: options.ConnectionTemplate}); err != nil {
+					return nil, errors.Wrapf(err, "failed to build connection extension for profile %s", profile.Name)
+				}
+
+				if options.CapacityPolicy != nil {
+					if err := extensions.Capacity.Set(profile, options.CapacityPolicy); err != nil {
+						return nil, errors.Wrapf(err, "failed to build capacity extension for profile %s", profile.Name)
+					}
+				}
 				profiles = append(profiles, profile)
 			}
 		}
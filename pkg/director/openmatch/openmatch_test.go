@@ -3,11 +3,18 @@ package openmatch
 import (
 	"context"
 	"errors"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"io"
 	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestCleanUpAssignmentsWithoutConnection(t *testing.T) {
@@ -233,3 +240,430 @@ func (m *mockAssigner) AssignTickets(ctx context.Context, in *pb.AssignTicketsRe
 	args := m.Called(ctx, in)
 	return args.Get(0).(*pb.AssignTicketsResponse), args.Error(1)
 }
+
+func TestCreateAssignTicketRequestForMatch(t *testing.T) {
+	filter := extensions.AllocatorFilterExtension{
+		Labels: map[string]string{"region": "us-east-1"},
+	}
+
+	matchExtensions, err := filter.Any()
+	require.NoError(t, err)
+
+	match := &pb.Match{
+		MatchId:    "match-1",
+		Tickets:    []*pb.Ticket{{Id: "ticket-1"}, {Id: "ticket-2", SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{extensions.PartySizeArg: 3}}}},
+		Extensions: matchExtensions,
+	}
+
+	req, err := CreateAssignTicketRequestForMatch(match)
+	require.NoError(t, err)
+	require.Len(t, req.Assignments, 1)
+	require.Equal(t, []string{"ticket-1", "ticket-2"}, req.Assignments[0].TicketIds)
+
+	gs, err := extensions.GameServer.Get(req.Assignments[0].Assignment)
+	require.NoError(t, err)
+	require.Equal(t, "match-1", gs.MatchId)
+
+	_, err = extensions.GetFilter(req.Assignments[0].Assignment)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(4), extensions.GetPlayers(req.Assignments[0].Assignment, 0))
+
+	_, err = extensions.GameServer.Get(match)
+	require.True(t, extensions.IsNotFound(err), "the match extensions must not be changed")
+}
+
+func TestSplitAssignmentGroupsByTeam(t *testing.T) {
+	ticket := func(id, team string) *pb.Ticket {
+		ticket := &pb.Ticket{Id: id, SearchFields: &pb.SearchFields{StringArgs: map[string]string{}}}
+		if len(team) > 0 {
+			ticket.SearchFields.StringArgs[TeamArg] = team
+		}
+		return ticket
+	}
+
+	group := func(connection string, ids ...string) *pb.AssignmentGroup {
+		return &pb.AssignmentGroup{
+			TicketIds:  ids,
+			Assignment: &pb.Assignment{Connection: connection},
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		groups    []*pb.AssignmentGroup
+		tickets   []*pb.Ticket
+		wantTeams map[string][]string
+	}{
+		{
+			name:      "it should keep the group if tickets have no team",
+			groups:    []*pb.AssignmentGroup{group("10.0.0.1:7000", "t1", "t2")},
+			tickets:   []*pb.Ticket{ticket("t1", ""), ticket("t2", "")},
+			wantTeams: map[string][]string{"": {"t1", "t2"}},
+		},
+		{
+			name:    "it should split the group in two teams",
+			groups:  []*pb.AssignmentGroup{group("10.0.0.1:7000", "t1", "t2", "t3")},
+			tickets: []*pb.Ticket{ticket("t1", "red"), ticket("t2", "blue"), ticket("t3", "red")},
+			wantTeams: map[string][]string{
+				"red":  {"t1", "t3"},
+				"blue": {"t2"},
+			},
+		},
+		{
+			name:      "it should not split groups without connection",
+			groups:    []*pb.AssignmentGroup{group("", "t1", "t2")},
+			tickets:   []*pb.Ticket{ticket("t1", "red"), ticket("t2", "blue")},
+			wantTeams: map[string][]string{"": {"t1", "t2"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SplitAssignmentGroupsByTeam(tc.groups, tc.tickets)
+			require.NoError(t, err)
+			require.Len(t, got, len(tc.wantTeams))
+
+			for _, g := range got {
+				gs, err := extensions.GetGameServer(g.Assignment)
+				require.NoError(t, err)
+				require.Equal(t, tc.wantTeams[gs.Team], g.TicketIds)
+				require.Equal(t, tc.groups[0].Assignment.Connection, g.Assignment.Connection)
+				if len(gs.Team) > 0 {
+					require.Equal(t, int64(len(g.TicketIds)), extensions.GetPlayers(g.Assignment, 0))
+				}
+			}
+		})
+	}
+}
+
+func TestUnassignedTicketIDs(t *testing.T) {
+	groups := []*pb.AssignmentGroup{
+		{TicketIds: []string{"t1", "t2"}, Assignment: &pb.Assignment{Connection: "10.0.0.1:7000"}},
+		{TicketIds: []string{"t3"}, Assignment: &pb.Assignment{}},
+		{TicketIds: []string{"t4", "t5"}, Assignment: &pb.Assignment{}},
+	}
+
+	require.Equal(t, []string{"t3", "t4", "t5"}, UnassignedTicketIDs(groups))
+	require.Nil(t, UnassignedTicketIDs(groups[:1]))
+}
+
+func TestAssignTickets_releaseTickets(t *testing.T) {
+	testCases := []struct {
+		name         string
+		ticketIDs    []string
+		err          error
+		cancelled    bool
+		wantReleased int
+		wantCalls    int
+		wantErr      bool
+	}{
+		{
+			name:         "it should release the tickets",
+			ticketIDs:    []string{"t1", "t2"},
+			wantReleased: 2,
+			wantCalls:    1,
+		},
+		{
+			name:         "it should release the tickets after the context is cancelled",
+			ticketIDs:    []string{"t1"},
+			cancelled:    true,
+			wantReleased: 1,
+			wantCalls:    1,
+		},
+		{
+			name:      "it should not call Open Match without tickets",
+			ticketIDs: nil,
+			wantCalls: 0,
+		},
+		{
+			name:      "it should return error if Open Match fails",
+			ticketIDs: []string{"t1"},
+			err:       errors.New("unavailable"),
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			releaser := &mockReleaser{}
+			notDone := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil })
+			releaser.On("ReleaseTickets", notDone, &pb.ReleaseTicketsRequest{TicketIds: tc.ticketIDs}).Return(&pb.ReleaseTicketsResponse{}, tc.err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.cancelled {
+				cancel()
+			}
+			defer cancel()
+
+			got, err := releaseTickets(ctx, tc.ticketIDs, releaser)
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tc.wantReleased, got)
+			releaser.AssertNumberOfCalls(t, "ReleaseTickets", tc.wantCalls)
+		})
+	}
+}
+
+type mockReleaser struct {
+	mock.Mock
+}
+
+func (m *mockReleaser) ReleaseTickets(ctx context.Context, in *pb.ReleaseTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseTicketsResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*pb.ReleaseTicketsResponse), args.Error(1)
+}
+
+func (m *mockReleaser) ReleaseAllTickets(ctx context.Context, in *pb.ReleaseAllTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseAllTicketsResponse, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(*pb.ReleaseAllTicketsResponse), args.Error(1)
+}
+
+func TestFetchMatches(t *testing.T) {
+	testCases := []struct {
+		name        string
+		matches     []*pb.Match
+		block       bool
+		timeout     time.Duration
+		wantMatches []string
+		wantErr     bool
+	}{
+		{
+			name:        "it should stream every match",
+			matches:     []*pb.Match{{MatchId: "m1"}, {MatchId: "m2"}, {MatchId: "m3"}},
+			wantMatches: []string{"m1", "m2", "m3"},
+		},
+		{
+			name:        "it should keep the matches streamed before the deadline",
+			matches:     []*pb.Match{{MatchId: "m1"}, {MatchId: "m2"}},
+			block:       true,
+			timeout:     50 * time.Millisecond,
+			wantMatches: []string{"m1", "m2"},
+			wantErr:     true,
+		},
+		{
+			name:    "it should return error if the deadline is reached without matches",
+			block:   true,
+			timeout: 10 * time.Millisecond,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetcher := &fakeFetcher{matches: tc.matches, block: tc.block}
+			fetch := FetchMatches(fetcher, MatchFunctionServer{}, tc.timeout)
+
+			matches := make(chan *pb.Match)
+			errFetch := make(chan error, 1)
+			go func() {
+				defer close(matches)
+				errFetch <- fetch(context.Background(), &pb.MatchProfile{Name: "profile"}, matches)
+			}()
+
+			var got []string
+			for match := range matches {
+				got = append(got, match.GetMatchId())
+			}
+
+			err := <-errFetch
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tc.wantMatches, got)
+		})
+	}
+}
+
+type fakeFetcher struct {
+	matches []*pb.Match
+	block   bool
+}
+
+func (f *fakeFetcher) FetchMatches(ctx context.Context, in *pb.FetchMatchesRequest, opts ...grpc.CallOption) (pb.BackendService_FetchMatchesClient, error) {
+	return &fakeFetchStream{ctx: ctx, matches: f.matches, block: f.block}, nil
+}
+
+type fakeFetchStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	matches []*pb.Match
+	block   bool
+}
+
+func (s *fakeFetchStream) Recv() (*pb.FetchMatchesResponse, error) {
+	if len(s.matches) > 0 {
+		match := s.matches[0]
+		s.matches = s.matches[1:]
+		return &pb.FetchMatchesResponse{Match: match}, nil
+	}
+
+	if s.block {
+		<-s.ctx.Done()
+		return nil, s.ctx.Err()
+	}
+
+	return nil, io.EOF
+}
+
+func TestAssignTickets(t *testing.T) {
+	testCases := []struct {
+		name         string
+		workers      int
+		matches      []string
+		fail         map[string]bool
+		noGameServer map[string]bool
+		failAssign   map[string]bool
+		wantFailed   []string
+		wantAssigned int64
+		wantReleased []string
+	}{
+		{
+			name:         "it should assign every match",
+			workers:      2,
+			matches:      []string{"m1", "m2", "m3"},
+			wantAssigned: 3,
+		},
+		{
+			name:         "it should keep going past failed matches",
+			workers:      2,
+			matches:      []string{"m1", "m2", "m3", "m4"},
+			fail:         map[string]bool{"m2": true, "m4": true},
+			wantFailed:   []string{"m2", "m4"},
+			wantAssigned: 2,
+			wantReleased: []string{"m2-t1", "m4-t1"},
+		},
+		{
+			name:         "it should count a match without GameServer as failed",
+			workers:      2,
+			matches:      []string{"m1", "m2"},
+			noGameServer: map[string]bool{"m2": true},
+			wantFailed:   []string{"m2"},
+			wantAssigned: 1,
+			wantReleased: []string{"m2-t1"},
+		},
+		{
+			name:         "it should release the tickets of a match that failed to be assigned",
+			workers:      2,
+			matches:      []string{"m1", "m2"},
+			failAssign:   map[string]bool{"m1": true},
+			wantFailed:   []string{"m1"},
+			wantAssigned: 1,
+			wantReleased: []string{"m1-t1"},
+		},
+		{
+			name:         "it should use one worker if workers is not set",
+			matches:      []string{"m1", "m2"},
+			fail:         map[string]bool{"m1": true},
+			wantFailed:   []string{"m1"},
+			wantAssigned: 1,
+			wantReleased: []string{"m1-t1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := &fakeBackend{fail: tc.failAssign}
+			gsAllocator := &fakeAllocator{fail: tc.fail, noGameServer: tc.noGameServer}
+			stats := director.NewStats()
+
+			matches := make(chan *pb.Match, len(tc.matches))
+			for _, id := range tc.matches {
+				matches <- &pb.Match{MatchId: id, Tickets: []*pb.Ticket{{Id: id + "-t1"}}}
+			}
+			close(matches)
+
+			assign := AssignTickets(backend, allocator.NewAllocatorService(gsAllocator), stats, tc.workers, nil)
+			err := assign(context.Background(), matches)
+
+			if len(tc.wantFailed) == 0 {
+				require.NoError(t, err)
+			} else {
+				var assignErr *AssignError
+				require.ErrorAs(t, err, &assignErr)
+				require.Len(t, assignErr.Results, len(tc.matches))
+
+				var failed []string
+				for _, r := range assignErr.Failures() {
+					failed = append(failed, r.MatchID)
+				}
+				require.ElementsMatch(t, tc.wantFailed, failed)
+			}
+
+			require.Equal(t, tc.wantAssigned, stats.Snapshot().MatchesAssigned)
+			require.Equal(t, int64(len(tc.wantFailed)), stats.Snapshot().MatchesFailed)
+			require.ElementsMatch(t, tc.wantReleased, backend.released)
+			require.LessOrEqual(t, gsAllocator.maxRunning.Load(), int64(max(tc.workers, 1)))
+		})
+	}
+}
+
+type fakeAllocator struct {
+	fail                map[string]bool
+	noGameServer        map[string]bool
+	running, maxRunning atomic.Int64
+}
+
+func (f *fakeAllocator) Allocate(ctx context.Context, req *pb.AssignTicketsRequest) error {
+	current := f.running.Add(1)
+	defer f.running.Add(-1)
+	for {
+		max := f.maxRunning.Load()
+		if current <= max || f.maxRunning.CompareAndSwap(max, current) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	gs, err := extensions.GetGameServer(req.Assignments[0].Assignment)
+	if err != nil {
+		return err
+	}
+
+	if f.fail[gs.MatchId] {
+		return errors.New("no gameserver available")
+	}
+
+	// The group is left without Connection, as the discover allocator does when no GameServer has capacity
+	if f.noGameServer[gs.MatchId] {
+		return nil
+	}
+
+	req.Assignments[0].Assignment.Connection = "10.0.0.1:7000"
+	return nil
+}
+
+type fakeBackend struct {
+	mux      sync.Mutex
+	fail     map[string]bool
+	released []string
+}
+
+func (f *fakeBackend) AssignTickets(ctx context.Context, in *pb.AssignTicketsRequest, opts ...grpc.CallOption) (*pb.AssignTicketsResponse, error) {
+	for _, g := range in.Assignments {
+		if gs, err := extensions.GetGameServer(g.Assignment); err == nil && f.fail[gs.MatchId] {
+			return nil, errors.New("backend unavailable")
+		}
+	}
+
+	return &pb.AssignTicketsResponse{}, nil
+}
+
+func (f *fakeBackend) ReleaseTickets(ctx context.Context, in *pb.ReleaseTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseTicketsResponse, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.released = append(f.released, in.TicketIds...)
+	return &pb.ReleaseTicketsResponse{}, nil
+}
+
+func (f *fakeBackend) ReleaseAllTickets(ctx context.Context, in *pb.ReleaseAllTicketsRequest, opts ...grpc.CallOption) (*pb.ReleaseAllTicketsResponse, error) {
+	return &pb.ReleaseAllTicketsResponse{}, nil
+}
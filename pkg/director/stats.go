@@ -0,0 +1,96 @@
+package director
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Stats counts what happened to the tickets and matches handled by the director.
+// It is safe for concurrent use.
+type Stats struct {
+	ticketsReleased     atomic.Int64
+	ticketReleaseErrors atomic.Int64
+	profileRuns         atomic.Int64
+	tickOverruns        atomic.Int64
+	tickThrottles       atomic.Int64
+	matchesFetched      atomic.Int64
+	matchesDropped      atomic.Int64
+	matchesAssigned     atomic.Int64
+	matchesFailed       atomic.Int64
+}
+
+// StatsSnapshot is a point in time copy of the Stats
+type StatsSnapshot struct {
+	TicketsReleased     int64 `json:"tickets_released"`
+	TicketReleaseErrors int64 `json:"ticket_release_errors"`
+	ProfileRuns         int64 `json:"profile_runs"`
+	TickOverruns        int64 `json:"tick_overruns"`
+	TickThrottles       int64 `json:"tick_throttles"`
+	MatchesFetched      int64 `json:"matches_fetched"`
+	MatchesDropped      int64 `json:"matches_dropped"`
+	MatchesAssigned     int64 `json:"matches_assigned"`
+	MatchesFailed       int64 `json:"matches_failed"`
+}
+
+func NewStats() *Stats {
+	return &Stats{}
+}
+
+func (s *Stats) AddTicketsReleased(count int) {
+	s.ticketsReleased.Add(int64(count))
+}
+
+func (s *Stats) AddTicketReleaseErrors(count int) {
+	s.ticketReleaseErrors.Add(int64(count))
+}
+
+func (s *Stats) AddProfileRuns(count int) {
+	s.profileRuns.Add(int64(count))
+}
+
+// AddTickOverruns counts the ticks skipped because the profile was still running
+func (s *Stats) AddTickOverruns(count int) {
+	s.tickOverruns.Add(int64(count))
+}
+
+// AddTickThrottles counts the ticks skipped because the profile was waiting for a MaxConcurrency slot
+func (s *Stats) AddTickThrottles(count int) {
+	s.tickThrottles.Add(int64(count))
+}
+
+// AddMatchesFetched counts the matches handed over to the assign func
+func (s *Stats) AddMatchesFetched(count int) {
+	s.matchesFetched.Add(int64(count))
+}
+
+// AddMatchesDropped counts the matches fetched but not read by the assign func
+func (s *Stats) AddMatchesDropped(count int) {
+	s.matchesDropped.Add(int64(count))
+}
+
+func (s *Stats) AddMatchesAssigned(count int) {
+	s.matchesAssigned.Add(int64(count))
+}
+
+func (s *Stats) AddMatchesFailed(count int) {
+	s.matchesFailed.Add(int64(count))
+}
+
+func (s *Stats) Snapshot() StatsSnapshot {
+	return StatsSnapshot{
+		TicketsReleased:     s.ticketsReleased.Load(),
+		TicketReleaseErrors: s.ticketReleaseErrors.Load(),
+		ProfileRuns:         s.profileRuns.Load(),
+		TickOverruns:        s.tickOverruns.Load(),
+		TickThrottles:       s.tickThrottles.Load(),
+		MatchesFetched:      s.matchesFetched.Load(),
+		MatchesDropped:      s.matchesDropped.Load(),
+		MatchesAssigned:     s.matchesAssigned.Load(),
+		MatchesFailed:       s.matchesFailed.Load(),
+	}
+}
+
+func (s StatsSnapshot) String() string {
+	return fmt.Sprintf("profile runs: %d, tick overruns: %d, tick throttles: %d, matches fetched: %d, matches dropped: %d, matches assigned: %d, matches failed: %d, tickets released: %d, ticket release errors: %d",
+		s.ProfileRuns, s.TickOverruns, s.TickThrottles, s.MatchesFetched, s.MatchesDropped, s.MatchesAssigned, s.MatchesFailed, s.TicketsReleased, s.TicketReleaseErrors)
+}
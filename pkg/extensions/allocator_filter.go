@@ -1,23 +1,34 @@
 package extensions
 
 import (
-	"encoding/json"
 	"fmt"
-	"github.com/golang/protobuf/ptypes"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
 	"github.com/golang/protobuf/ptypes/any"
-	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/pkg/errors"
 	"strings"
 )
 
+// Filter is the extension used by the allocators to find GameServers for a Match
+var Filter = Register[*extpb.AllocatorFilter]("filter")
+
 type AllocatorFilterExtension struct {
 	Labels map[string]string `json:"labels"`
 	Fields map[string]string `json:"fields"`
 }
 
-func (f AllocatorFilterExtension) Any() map[string]*any.Any {
-	return map[string]*any.Any{
-		"filter": ToAny(f),
+func (f AllocatorFilterExtension) Any() (map[string]*any.Any, error) {
+	extensions := map[string]*any.Any{}
+	if err := Filter.SetTo(extensions, f.Proto()); err != nil {
+		return nil, errors.Wrap(err, "can't parse Filter to Any")
+	}
+
+	return extensions, nil
+}
+
+func (f AllocatorFilterExtension) Proto() *extpb.AllocatorFilter {
+	return &extpb.AllocatorFilter{
+		Labels: f.Labels,
+		Fields: f.Fields,
 	}
 }
 
@@ -29,34 +40,21 @@ func (f *AllocatorFilterExtension) Map() map[string]string {
 	return m
 }
 
-func ExtractFilterFromExtensions(extension map[string]*any.Any) (*AllocatorFilterExtension, error) {
-	if _, ok := extension["filter"]; !ok {
-		return nil, nil
-	}
-
-	filter, err := ToFilter(extension["filter"])
+// GetFilter returns the filter carried by the holder. It returns ErrExtensionNotFound if the filter is not set.
+func GetFilter(holder Holder) (*AllocatorFilterExtension, error) {
+	message, err := Filter.Get(holder)
 	if err != nil {
 		return nil, err
 	}
 
-	return filter, nil
+	return filterFromProto(message), nil
 }
 
-func ToFilter(obj *any.Any) (*AllocatorFilterExtension, error) {
-	var filter AllocatorFilterExtension
-
-	message := &wrappers.BytesValue{}
-	err := ptypes.UnmarshalAny(obj, message)
-	if err != nil {
-		return nil, errors.Wrap(err, "can't parse Any to Message")
-	}
-
-	err = json.Unmarshal(message.Value, &filter)
-	if err != nil {
-		return nil, errors.Wrap(err, "can't parse Any to Filter")
+func filterFromProto(message *extpb.AllocatorFilter) *AllocatorFilterExtension {
+	return &AllocatorFilterExtension{
+		Labels: message.GetLabels(),
+		Fields: message.GetFields(),
 	}
-
-	return &filter, nil
 }
 
 func joinMapValues(list map[string]string) string {
@@ -0,0 +1,20 @@
+package extensions
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// OpenSlots is the extension set on the Backfill with the number of players its GameServer can still take.
+// The director keeps it in sync with the capacity reported by Agones, the match function decreases it when it adds tickets.
+var OpenSlots = Register[*wrappers.Int64Value]("open_slots")
+
+// GetOpenSlots returns the open slots of the backfill or zero if it is not set
+func GetOpenSlots(backfill *pb.Backfill) int64 {
+	slots, err := OpenSlots.Get(backfill)
+	if err != nil {
+		return 0
+	}
+
+	return slots.GetValue()
+}
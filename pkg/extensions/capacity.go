@@ -0,0 +1,55 @@
+package extensions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Capacity is the extension set on the MatchProfile with the policy used to check the GameServers capacity
+var Capacity = Register[*extpb.CapacityPolicy]("capacity")
+
+// GetCapacityPolicy returns the capacity policy carried by the holder or the default policy if it is not set.
+// The default policy uses the Agones PlayerTracking status and allows GameServers with unknown capacity.
+func GetCapacityPolicy(holder Holder) (*extpb.CapacityPolicy, error) {
+	policy, err := Capacity.Get(holder)
+	if err != nil {
+		if IsNotFound(err) {
+			return &extpb.CapacityPolicy{}, nil
+		}
+
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ParseCapacityPolicy builds a capacity policy from its flags representation, i.e. source=counter key=players unknown=assume
+func ParseCapacityPolicy(source, key, unknown string, assumedCapacity int64) (*extpb.CapacityPolicy, error) {
+	sourceValue, ok := extpb.CapacityPolicy_Source_value[strings.ToUpper(source)]
+	if !ok {
+		return nil, errors.Errorf("capacity source %s is invalid, it should be one of players, counter or list", source)
+	}
+
+	unknownValue, ok := extpb.CapacityPolicy_Unknown_value[strings.ToUpper(unknown)]
+	if !ok {
+		return nil, errors.Errorf("unknown capacity policy %s is invalid, it should be one of allow, deny or assume", unknown)
+	}
+
+	policy := &extpb.CapacityPolicy{
+		Source:          extpb.CapacityPolicy_Source(sourceValue),
+		Key:             key,
+		Unknown:         extpb.CapacityPolicy_Unknown(unknownValue),
+		AssumedCapacity: assumedCapacity,
+	}
+
+	if policy.Source != extpb.CapacityPolicy_PLAYERS && len(policy.Key) == 0 {
+		return nil, errors.Errorf("capacity source %s requires a key", source)
+	}
+
+	if policy.Unknown == extpb.CapacityPolicy_ASSUME && policy.AssumedCapacity <= 0 {
+		return nil, errors.New("unknown capacity policy assume requires a capacity higher than zero")
+	}
+
+	return policy, nil
+}
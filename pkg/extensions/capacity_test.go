@@ -0,0 +1,40 @@
+package extensions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseCapacityPolicy(t *testing.T) {
+	testCases := []struct {
+		name    string
+		source  string
+		key     string
+		unknown string
+		assumed int64
+		want    *extpb.CapacityPolicy
+		wantErr bool
+	}{
+		{name: "it should parse the default policy", source: "players", unknown: "allow", want: &extpb.CapacityPolicy{}},
+		{name: "it should parse a counter policy", source: "counter", key: "players", unknown: "deny", want: &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_COUNTER, Key: "players", Unknown: extpb.CapacityPolicy_DENY}},
+		{name: "it should parse a list policy assuming capacity", source: "List", key: "players", unknown: "Assume", assumed: 10, want: &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_LIST, Key: "players", Unknown: extpb.CapacityPolicy_ASSUME, AssumedCapacity: 10}},
+		{name: "it should return error for invalid source", source: "sessions", unknown: "allow", wantErr: true},
+		{name: "it should return error for invalid unknown policy", source: "players", unknown: "maybe", wantErr: true},
+		{name: "it should return error for counter without key", source: "counter", unknown: "allow", wantErr: true},
+		{name: "it should return error for assume without capacity", source: "players", unknown: "assume", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseCapacityPolicy(tc.source, tc.key, tc.unknown, tc.assumed)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want.String(), got.String())
+		})
+	}
+}
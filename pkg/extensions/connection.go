@@ -0,0 +1,30 @@
+package extensions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+)
+
+const (
+	DefaultConnectionTemplate = "{address}:{port}"
+)
+
+// Connection is the extension set on the MatchProfile with the template used to build the Assignment Connection
+var Connection = Register[*extpb.ConnectionFormat]("connection")
+
+// GetConnectionTemplate returns the connection template carried by the holder or the DefaultConnectionTemplate if it is not set
+func GetConnectionTemplate(holder Holder) (string, error) {
+	format, err := Connection.Get(holder)
+	if err != nil {
+		if IsNotFound(err) {
+			return DefaultConnectionTemplate, nil
+		}
+
+		return "", err
+	}
+
+	if len(format.GetTemplate()) == 0 {
+		return DefaultConnectionTemplate, nil
+	}
+
+	return format.GetTemplate(), nil
+}
@@ -1,51 +1,72 @@
 package extensions
 
 import (
-	"encoding/json"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
-type Extension struct {
-	any map[string]*any.Any
-}
+var (
+	ErrExtensionIsNil = errors.New("extension can't be nil")
+)
 
-// Reference: https://stackoverflow.com/a/62585911
-func ToAny(value interface{}) *any.Any {
-	b, err := json.Marshal(value)
+// ToAny packs the message into a typed Any. The type URL carries the message full name so any
+// Open Match component that knows the message definition is able to decode it.
+func ToAny(message proto.Message) (*any.Any, error) {
+	if message == nil {
+		return nil, ErrExtensionIsNil
+	}
+
+	mAny, err := ptypes.MarshalAny(message)
 	if err != nil {
-		return nil
+		return nil, errors.Wrap(err, "can't parse Message to Any")
 	}
 
-	bValues := &wrappers.BytesValue{
-		Value: b,
+	return mAny, nil
+}
+
+// FromAny unpacks the Any into message. Besides the typed encoding produced by ToAny it also reads
+// the legacy encoding, a JSON document wrapped in a BytesValue, used by previous versions.
+// Reference: https://stackoverflow.com/a/62585911
+func FromAny(obj *any.Any, message proto.Message) error {
+	if obj == nil {
+		return ErrExtensionIsNil
 	}
 
-	mAny, err := ptypes.MarshalAny(bValues)
-	if err != nil {
+	if ptypes.Is(obj, &wrappers.BytesValue{}) {
+		legacy := &wrappers.BytesValue{}
+		if err := ptypes.UnmarshalAny(obj, legacy); err != nil {
+			return errors.Wrap(err, "can't parse Any to BytesValue")
+		}
+
+		opts := protojson.UnmarshalOptions{DiscardUnknown: true}
+		if err := opts.Unmarshal(legacy.Value, proto.MessageV2(message)); err != nil {
+			return errors.Wrapf(err, "can't parse legacy extension to %s", proto.MessageName(message))
+		}
+
 		return nil
 	}
 
-	return mAny
-}
+	if err := ptypes.UnmarshalAny(obj, message); err != nil {
+		return errors.Wrap(err, "can't parse Any to Message")
+	}
 
-func WithAny(anyMap map[string]*any.Any) Extension {
-	return Extension{any: anyMap}
+	return nil
 }
 
-func (ex Extension) WithAny(anyMap map[string]*any.Any) Extension {
-	if ex.any == nil {
-		ex.any = map[string]*any.Any{}
+// Clone returns a shallow copy of the extensions map. Useful when the same map is shared by Matches and Assignments.
+func Clone(extensions map[string]*any.Any) map[string]*any.Any {
+	if extensions == nil {
+		return nil
 	}
 
-	for k, v := range anyMap {
-		ex.any[k] = v
+	cloned := make(map[string]*any.Any, len(extensions))
+	for k, v := range extensions {
+		cloned[k] = v
 	}
 
-	return ex
-}
-
-func (ex Extension) Extensions() map[string]*any.Any {
-	return ex.any
+	return cloned
 }
@@ -0,0 +1,120 @@
+package extensions
+
+import (
+	"encoding/json"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestToAny(t *testing.T) {
+	t.Run("it should pack the filter as a typed Any", func(t *testing.T) {
+		filter := &extpb.AllocatorFilter{
+			Labels: map[string]string{"region": "us-east-1"},
+		}
+
+		got, err := ToAny(filter)
+		require.NoError(t, err)
+		require.Equal(t, "type.googleapis.com/octops.extensions.AllocatorFilter", got.TypeUrl)
+	})
+
+	t.Run("it should return error for nil message", func(t *testing.T) {
+		got, err := ToAny(nil)
+		require.Equal(t, ErrExtensionIsNil, err)
+		require.Nil(t, got)
+	})
+}
+
+func TestGetFilter_Encodings(t *testing.T) {
+	want := &AllocatorFilterExtension{
+		Labels: map[string]string{
+			"region": "us-east-1",
+			"world":  "Dune",
+		},
+		Fields: map[string]string{
+			"status.state": "Ready",
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		obj     func(t *testing.T) *any.Any
+		want    *AllocatorFilterExtension
+		wantErr bool
+	}{
+		{
+			name: "it should decode the typed encoding",
+			obj: func(t *testing.T) *any.Any {
+				obj, err := ToAny(want.Proto())
+				require.NoError(t, err)
+				return obj
+			},
+			want: want,
+		},
+		{
+			name: "it should decode the legacy BytesValue encoding",
+			obj: func(t *testing.T) *any.Any {
+				b, err := json.Marshal(want)
+				require.NoError(t, err)
+
+				obj, err := ptypes.MarshalAny(&wrappers.BytesValue{Value: b})
+				require.NoError(t, err)
+				return obj
+			},
+			want: want,
+		},
+		{
+			name: "it should decode the legacy BytesValue encoding with null fields",
+			obj: func(t *testing.T) *any.Any {
+				obj, err := ptypes.MarshalAny(&wrappers.BytesValue{Value: []byte(`{"labels":{"region":"us-east-1"},"fields":null}`)})
+				require.NoError(t, err)
+				return obj
+			},
+			want: &AllocatorFilterExtension{
+				Labels: map[string]string{"region": "us-east-1"},
+			},
+		},
+		{
+			name: "it should return error for a legacy encoding that is not valid JSON",
+			obj: func(t *testing.T) *any.Any {
+				obj, err := ptypes.MarshalAny(&wrappers.BytesValue{Value: []byte("not json")})
+				require.NoError(t, err)
+				return obj
+			},
+			wantErr: true,
+		},
+		{
+			name: "it should return error for an unexpected message type",
+			obj: func(t *testing.T) *any.Any {
+				obj, err := ptypes.MarshalAny(&wrappers.StringValue{Value: "filter"})
+				require.NoError(t, err)
+				return obj
+			},
+			wantErr: true,
+		},
+		{
+			name: "it should return error for nil Any",
+			obj: func(t *testing.T) *any.Any {
+				return nil
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GetFilter(&pb.Assignment{Extensions: map[string]*any.Any{Filter.Name: tc.obj(t)}})
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
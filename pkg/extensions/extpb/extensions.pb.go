@@ -0,0 +1,672 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        v3.21.12
+// source: extensions.proto
+
+package extpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CapacityPolicy_Source int32
+
+const (
+	// PLAYERS uses the Agones PlayerTracking status, Status.Players.Capacity - Status.Players.Count.
+	CapacityPolicy_PLAYERS CapacityPolicy_Source = 0
+	// COUNTER uses the Agones Counter named by key, Capacity - Count.
+	CapacityPolicy_COUNTER CapacityPolicy_Source = 1
+	// LIST uses the Agones List named by key, Capacity - len(Values).
+	CapacityPolicy_LIST CapacityPolicy_Source = 2
+)
+
+// Enum value maps for CapacityPolicy_Source.
+var (
+	CapacityPolicy_Source_name = map[int32]string{
+		0: "PLAYERS",
+		1: "COUNTER",
+		2: "LIST",
+	}
+	CapacityPolicy_Source_value = map[string]int32{
+		"PLAYERS": 0,
+		"COUNTER": 1,
+		"LIST":    2,
+	}
+)
+
+func (x CapacityPolicy_Source) Enum() *CapacityPolicy_Source {
+	p := new(CapacityPolicy_Source)
+	*p = x
+	return p
+}
+
+func (x CapacityPolicy_Source) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CapacityPolicy_Source) Descriptor() protoreflect.EnumDescriptor {
+	return file_extensions_proto_enumTypes[0].Descriptor()
+}
+
+func (CapacityPolicy_Source) Type() protoreflect.EnumType {
+	return &file_extensions_proto_enumTypes[0]
+}
+
+func (x CapacityPolicy_Source) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CapacityPolicy_Source.Descriptor instead.
+func (CapacityPolicy_Source) EnumDescriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{4, 0}
+}
+
+type CapacityPolicy_Unknown int32
+
+const (
+	// ALLOW accepts any number of tickets.
+	CapacityPolicy_ALLOW CapacityPolicy_Unknown = 0
+	// DENY rejects the GameServer.
+	CapacityPolicy_DENY CapacityPolicy_Unknown = 1
+	// ASSUME accepts up to assumed_capacity tickets.
+	CapacityPolicy_ASSUME CapacityPolicy_Unknown = 2
+)
+
+// Enum value maps for CapacityPolicy_Unknown.
+var (
+	CapacityPolicy_Unknown_name = map[int32]string{
+		0: "ALLOW",
+		1: "DENY",
+		2: "ASSUME",
+	}
+	CapacityPolicy_Unknown_value = map[string]int32{
+		"ALLOW":  0,
+		"DENY":   1,
+		"ASSUME": 2,
+	}
+)
+
+func (x CapacityPolicy_Unknown) Enum() *CapacityPolicy_Unknown {
+	p := new(CapacityPolicy_Unknown)
+	*p = x
+	return p
+}
+
+func (x CapacityPolicy_Unknown) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CapacityPolicy_Unknown) Descriptor() protoreflect.EnumDescriptor {
+	return file_extensions_proto_enumTypes[1].Descriptor()
+}
+
+func (CapacityPolicy_Unknown) Type() protoreflect.EnumType {
+	return &file_extensions_proto_enumTypes[1]
+}
+
+func (x CapacityPolicy_Unknown) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CapacityPolicy_Unknown.Descriptor instead.
+func (CapacityPolicy_Unknown) EnumDescriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{4, 1}
+}
+
+// AllocatorFilter carries the selectors used by the allocators to find GameServers for a Match.
+// It is attached to MatchProfiles, Matches and Assignments under the "filter" extension key.
+type AllocatorFilter struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Labels are matched against the GameServer labels.
+	Labels map[string]string `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Fields are matched against the GameServer fields, i.e. status.state=Ready.
+	Fields map[string]string `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *AllocatorFilter) Reset() {
+	*x = AllocatorFilter{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extensions_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AllocatorFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllocatorFilter) ProtoMessage() {}
+
+func (x *AllocatorFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_extensions_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllocatorFilter.ProtoReflect.Descriptor instead.
+func (*AllocatorFilter) Descriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AllocatorFilter) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *AllocatorFilter) GetFields() map[string]string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+// GameServerAssignment describes the GameServer allocated for a Match. The director attaches it to
+// every Assignment under the "gameserver" extension key, so game frontends can read it from the
+// Ticket assignment alongside the Connection.
+type GameServerAssignment struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Name of the allocated GameServer.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Namespace of the allocated GameServer.
+	Namespace string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Address of the node running the GameServer.
+	Address string `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	// Ports exposed by the GameServer.
+	Ports []*GameServerPort `protobuf:"bytes,4,rep,name=ports,proto3" json:"ports,omitempty"`
+	// MatchId of the Match the tickets were part of.
+	MatchId string `protobuf:"bytes,5,opt,name=match_id,json=matchId,proto3" json:"match_id,omitempty"`
+	// Team the tickets of this Assignment belong to. Set from the "team" string arg of the tickets.
+	Team string `protobuf:"bytes,6,opt,name=team,proto3" json:"team,omitempty"`
+	// Region of the GameServer, taken from its "region" label.
+	Region string `protobuf:"bytes,7,opt,name=region,proto3" json:"region,omitempty"`
+}
+
+func (x *GameServerAssignment) Reset() {
+	*x = GameServerAssignment{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extensions_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GameServerAssignment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameServerAssignment) ProtoMessage() {}
+
+func (x *GameServerAssignment) ProtoReflect() protoreflect.Message {
+	mi := &file_extensions_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameServerAssignment.ProtoReflect.Descriptor instead.
+func (*GameServerAssignment) Descriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GameServerAssignment) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GameServerAssignment) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GameServerAssignment) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *GameServerAssignment) GetPorts() []*GameServerPort {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *GameServerAssignment) GetMatchId() string {
+	if x != nil {
+		return x.MatchId
+	}
+	return ""
+}
+
+func (x *GameServerAssignment) GetTeam() string {
+	if x != nil {
+		return x.Team
+	}
+	return ""
+}
+
+func (x *GameServerAssignment) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+// GameServerPort is a named port exposed by a GameServer.
+type GameServerPort struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Port int32  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+}
+
+func (x *GameServerPort) Reset() {
+	*x = GameServerPort{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extensions_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GameServerPort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameServerPort) ProtoMessage() {}
+
+func (x *GameServerPort) ProtoReflect() protoreflect.Message {
+	mi := &file_extensions_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameServerPort.ProtoReflect.Descriptor instead.
+func (*GameServerPort) Descriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GameServerPort) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GameServerPort) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+// ConnectionFormat sets how the allocators build the Assignment Connection from the allocated GameServer.
+// The director attaches it to the MatchProfile under the "connection" extension key.
+type ConnectionFormat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Template of the Connection. Supported placeholders:
+	// {address} the GameServer address
+	// {port} the first GameServer port
+	// {port:<name>} the GameServer port with the given name, i.e. {port:game}
+	// {ports} a JSON object with all the named ports, i.e. {"game":7000,"query":7001}
+	Template string `protobuf:"bytes,1,opt,name=template,proto3" json:"template,omitempty"`
+}
+
+func (x *ConnectionFormat) Reset() {
+	*x = ConnectionFormat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extensions_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConnectionFormat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectionFormat) ProtoMessage() {}
+
+func (x *ConnectionFormat) ProtoReflect() protoreflect.Message {
+	mi := &file_extensions_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectionFormat.ProtoReflect.Descriptor instead.
+func (*ConnectionFormat) Descriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ConnectionFormat) GetTemplate() string {
+	if x != nil {
+		return x.Template
+	}
+	return ""
+}
+
+// CapacityPolicy sets how the allocators check if a GameServer has room for the tickets of a Match.
+// The director attaches it to the MatchProfile under the "capacity" extension key.
+type CapacityPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Source of the GameServer capacity.
+	Source CapacityPolicy_Source `protobuf:"varint,1,opt,name=source,proto3,enum=octops.extensions.CapacityPolicy_Source" json:"source,omitempty"`
+	// Key of the Counter or List, i.e. "players".
+	Key string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// Unknown sets what to do when the GameServer does not report the capacity for the source.
+	Unknown CapacityPolicy_Unknown `protobuf:"varint,3,opt,name=unknown,proto3,enum=octops.extensions.CapacityPolicy_Unknown" json:"unknown,omitempty"`
+	// AssumedCapacity is the room assumed for GameServers with unknown capacity when unknown is ASSUME.
+	AssumedCapacity int64 `protobuf:"varint,4,opt,name=assumed_capacity,json=assumedCapacity,proto3" json:"assumed_capacity,omitempty"`
+}
+
+func (x *CapacityPolicy) Reset() {
+	*x = CapacityPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extensions_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CapacityPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapacityPolicy) ProtoMessage() {}
+
+func (x *CapacityPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_extensions_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapacityPolicy.ProtoReflect.Descriptor instead.
+func (*CapacityPolicy) Descriptor() ([]byte, []int) {
+	return file_extensions_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CapacityPolicy) GetSource() CapacityPolicy_Source {
+	if x != nil {
+		return x.Source
+	}
+	return CapacityPolicy_PLAYERS
+}
+
+func (x *CapacityPolicy) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CapacityPolicy) GetUnknown() CapacityPolicy_Unknown {
+	if x != nil {
+		return x.Unknown
+	}
+	return CapacityPolicy_ALLOW
+}
+
+func (x *CapacityPolicy) GetAssumedCapacity() int64 {
+	if x != nil {
+		return x.AssumedCapacity
+	}
+	return 0
+}
+
+var File_extensions_proto protoreflect.FileDescriptor
+
+var file_extensions_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x11, 0x6f, 0x63, 0x74, 0x6f, 0x70, 0x73, 0x2e, 0x65, 0x78, 0x74, 0x65, 0x6e,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0x97, 0x02, 0x0a, 0x0f, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x61,
+	0x74, 0x6f, 0x72, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x46, 0x0a, 0x06, 0x6c, 0x61, 0x62,
+	0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x2e, 0x2e, 0x6f, 0x63, 0x74, 0x6f,
+	0x70, 0x73, 0x2e, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x41, 0x6c,
+	0x6c, 0x6f, 0x63, 0x61, 0x74, 0x6f, 0x72, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x2e, 0x4c, 0x61,
+	0x62, 0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c,
+	0x73, 0x12, 0x46, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x2e, 0x2e, 0x6f, 0x63, 0x74, 0x6f, 0x70, 0x73, 0x2e, 0x65, 0x78, 0x74, 0x65, 0x6e,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x41, 0x6c, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x6f, 0x72, 0x46,
+	0x69, 0x6c, 0x74, 0x65, 0x72, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x1a, 0x39, 0x0a, 0x0b, 0x4c, 0x61, 0x62,
+	0x65, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x1a, 0x39, 0x0a, 0x0b, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0xe2, 0x01, 0x0a, 0x14, 0x47, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x41, 0x73,
+	0x73, 0x69, 0x67, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1c, 0x0a, 0x09,
+	0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x09, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64,
+	0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x12, 0x37, 0x0a, 0x05, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x18, 0x04, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x6f, 0x63, 0x74, 0x6f, 0x70, 0x73, 0x2e, 0x65, 0x78, 0x74,
+	0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x47, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x50, 0x6f, 0x72, 0x74, 0x52, 0x05, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x12, 0x19, 0x0a,
+	0x08, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x5f, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x07, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x61, 0x6d,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x61, 0x6d, 0x12, 0x16, 0x0a, 0x06,
+	0x72, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65,
+	0x67, 0x69, 0x6f, 0x6e, 0x22, 0x38, 0x0a, 0x0e, 0x47, 0x61, 0x6d, 0x65, 0x53, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f,
+	0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x2e,
+	0x0a, 0x10, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x6f, 0x72, 0x6d,
+	0x61, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x74, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x22, 0xae,
+	0x02, 0x0a, 0x0e, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x12, 0x40, 0x0a, 0x06, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x28, 0x2e, 0x6f, 0x63, 0x74, 0x6f, 0x70, 0x73, 0x2e, 0x65, 0x78, 0x74, 0x65, 0x6e,
+	0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x50, 0x6f,
+	0x6c, 0x69, 0x63, 0x79, 0x2e, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x06, 0x73, 0x6f, 0x75,
+	0x72, 0x63, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x43, 0x0a, 0x07, 0x75, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x29, 0x2e, 0x6f, 0x63, 0x74, 0x6f, 0x70, 0x73, 0x2e,
+	0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2e, 0x43, 0x61, 0x70, 0x61, 0x63,
+	0x69, 0x74, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x2e, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77,
+	0x6e, 0x52, 0x07, 0x75, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x12, 0x29, 0x0a, 0x10, 0x61, 0x73,
+	0x73, 0x75, 0x6d, 0x65, 0x64, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0f, 0x61, 0x73, 0x73, 0x75, 0x6d, 0x65, 0x64, 0x43, 0x61, 0x70,
+	0x61, 0x63, 0x69, 0x74, 0x79, 0x22, 0x2c, 0x0a, 0x06, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12,
+	0x0b, 0x0a, 0x07, 0x50, 0x4c, 0x41, 0x59, 0x45, 0x52, 0x53, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07,
+	0x43, 0x4f, 0x55, 0x4e, 0x54, 0x45, 0x52, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x4c, 0x49, 0x53,
+	0x54, 0x10, 0x02, 0x22, 0x2a, 0x0a, 0x07, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x12, 0x09,
+	0x0a, 0x05, 0x41, 0x4c, 0x4c, 0x4f, 0x57, 0x10, 0x00, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x45, 0x4e,
+	0x59, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x41, 0x53, 0x53, 0x55, 0x4d, 0x45, 0x10, 0x02, 0x42,
+	0x42, 0x5a, 0x40, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x4f, 0x63,
+	0x74, 0x6f, 0x70, 0x73, 0x2f, 0x61, 0x67, 0x6f, 0x6e, 0x65, 0x73, 0x2d, 0x64, 0x69, 0x73, 0x63,
+	0x6f, 0x76, 0x65, 0x72, 0x2d, 0x6f, 0x70, 0x65, 0x6e, 0x6d, 0x61, 0x74, 0x63, 0x68, 0x2f, 0x70,
+	0x6b, 0x67, 0x2f, 0x65, 0x78, 0x74, 0x65, 0x6e, 0x73, 0x69, 0x6f, 0x6e, 0x73, 0x2f, 0x65, 0x78,
+	0x74, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_extensions_proto_rawDescOnce sync.Once
+	file_extensions_proto_rawDescData = file_extensions_proto_rawDesc
+)
+
+func file_extensions_proto_rawDescGZIP() []byte {
+	file_extensions_proto_rawDescOnce.Do(func() {
+		file_extensions_proto_rawDescData = protoimpl.X.CompressGZIP(file_extensions_proto_rawDescData)
+	})
+	return file_extensions_proto_rawDescData
+}
+
+var file_extensions_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_extensions_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_extensions_proto_goTypes = []interface{}{
+	(CapacityPolicy_Source)(0),   // 0: octops.extensions.CapacityPolicy.Source
+	(CapacityPolicy_Unknown)(0),  // 1: octops.extensions.CapacityPolicy.Unknown
+	(*AllocatorFilter)(nil),      // 2: octops.extensions.AllocatorFilter
+	(*GameServerAssignment)(nil), // 3: octops.extensions.GameServerAssignment
+	(*GameServerPort)(nil),       // 4: octops.extensions.GameServerPort
+	(*ConnectionFormat)(nil),     // 5: octops.extensions.ConnectionFormat
+	(*CapacityPolicy)(nil),       // 6: octops.extensions.CapacityPolicy
+	nil,                          // 7: octops.extensions.AllocatorFilter.LabelsEntry
+	nil,                          // 8: octops.extensions.AllocatorFilter.FieldsEntry
+}
+var file_extensions_proto_depIdxs = []int32{
+	7, // 0: octops.extensions.AllocatorFilter.labels:type_name -> octops.extensions.AllocatorFilter.LabelsEntry
+	8, // 1: octops.extensions.AllocatorFilter.fields:type_name -> octops.extensions.AllocatorFilter.FieldsEntry
+	4, // 2: octops.extensions.GameServerAssignment.ports:type_name -> octops.extensions.GameServerPort
+	0, // 3: octops.extensions.CapacityPolicy.source:type_name -> octops.extensions.CapacityPolicy.Source
+	1, // 4: octops.extensions.CapacityPolicy.unknown:type_name -> octops.extensions.CapacityPolicy.Unknown
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_extensions_proto_init() }
+func file_extensions_proto_init() {
+	if File_extensions_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_extensions_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AllocatorFilter); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_extensions_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GameServerAssignment); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_extensions_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GameServerPort); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_extensions_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConnectionFormat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_extensions_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CapacityPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_extensions_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_extensions_proto_goTypes,
+		DependencyIndexes: file_extensions_proto_depIdxs,
+		EnumInfos:         file_extensions_proto_enumTypes,
+		MessageInfos:      file_extensions_proto_msgTypes,
+	}.Build()
+	File_extensions_proto = out.File
+	file_extensions_proto_rawDesc = nil
+	file_extensions_proto_goTypes = nil
+	file_extensions_proto_depIdxs = nil
+}
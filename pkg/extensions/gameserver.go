@@ -0,0 +1,23 @@
+package extensions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+)
+
+// GameServer is the extension set on the Assignment with the details of the allocated GameServer.
+// Game frontends read it from the Ticket assignment, i.e. extensions.GameServer.Get(ticket.GetAssignment())
+var GameServer = Register[*extpb.GameServerAssignment]("gameserver")
+
+// GetGameServer returns the gameserver extension carried by the holder or an empty one if it is not set.
+func GetGameServer(holder Holder) (*extpb.GameServerAssignment, error) {
+	assignment, err := GameServer.Get(holder)
+	if err != nil {
+		if IsNotFound(err) {
+			return &extpb.GameServerAssignment{}, nil
+		}
+
+		return nil, err
+	}
+
+	return assignment, nil
+}
@@ -0,0 +1,49 @@
+package extensions
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+const (
+	// PartySizeArg is the ticket double arg with the number of players of a party ticket
+	PartySizeArg = "party_size"
+)
+
+// PartySize is the extension set on the Ticket with the number of players of the party. It takes precedence over the PartySizeArg.
+var PartySize = Register[*wrappers.Int64Value]("party_size")
+
+// Players is the extension set on the Assignment with the number of players of its tickets, used by the allocators to check the capacity
+var Players = Register[*wrappers.Int64Value]("players")
+
+// GetPartySize returns the number of players of the ticket. Tickets without a valid party size count as one player.
+func GetPartySize(ticket *pb.Ticket) int64 {
+	if size, err := PartySize.Get(ticket); err == nil && size.GetValue() > 0 {
+		return size.GetValue()
+	}
+
+	if size := int64(ticket.GetSearchFields().GetDoubleArgs()[PartySizeArg]); size > 0 {
+		return size
+	}
+
+	return 1
+}
+
+// CountPlayers returns the sum of the party sizes of the tickets
+func CountPlayers(tickets ...*pb.Ticket) int64 {
+	var players int64
+	for _, t := range tickets {
+		players += GetPartySize(t)
+	}
+
+	return players
+}
+
+// GetPlayers returns the players extension of the assignment or the fallback if it is not set
+func GetPlayers(assignment *pb.Assignment, fallback int64) int64 {
+	if players, err := Players.Get(assignment); err == nil && players.GetValue() > 0 {
+		return players.GetValue()
+	}
+
+	return fallback
+}
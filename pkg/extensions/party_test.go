@@ -0,0 +1,41 @@
+package extensions
+
+import (
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestGetPartySize(t *testing.T) {
+	withExtension := &pb.Ticket{SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{PartySizeArg: 2}}}
+	require.NoError(t, PartySize.Set(withExtension, &wrappers.Int64Value{Value: 4}))
+
+	testCases := []struct {
+		name   string
+		ticket *pb.Ticket
+		want   int64
+	}{
+		{name: "it should count a ticket without party size as one player", ticket: &pb.Ticket{}, want: 1},
+		{name: "it should read the party size from the search fields", ticket: &pb.Ticket{SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{PartySizeArg: 3}}}, want: 3},
+		{name: "it should prefer the party size extension", ticket: withExtension, want: 4},
+		{name: "it should ignore an invalid party size", ticket: &pb.Ticket{SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{PartySizeArg: 0}}}, want: 1},
+		{name: "it should accept a nil ticket", ticket: nil, want: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, GetPartySize(tc.ticket))
+		})
+	}
+
+	require.Equal(t, int64(8), CountPlayers(&pb.Ticket{}, withExtension, &pb.Ticket{SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{PartySizeArg: 3}}}))
+}
+
+func TestGetPlayers(t *testing.T) {
+	assignment := &pb.Assignment{}
+	require.Equal(t, int64(2), GetPlayers(assignment, 2))
+
+	require.NoError(t, Players.Set(assignment, &wrappers.Int64Value{Value: 7}))
+	require.Equal(t, int64(7), GetPlayers(assignment, 2))
+}
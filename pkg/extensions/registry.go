@@ -0,0 +1,153 @@
+package extensions
+
+import (
+	"fmt"
+	protov1 "github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+)
+
+var (
+	ErrExtensionNotFound = errors.New("extension not found")
+	ErrHolderIsNil       = errors.New("extension holder can't be nil")
+
+	registry = &Registry{keys: map[string]protoreflect.FullName{}}
+)
+
+// Holder is implemented by the Open Match messages that carry extensions,
+// i.e. pb.Match, pb.MatchProfile, pb.Assignment, pb.Ticket and pb.Backfill.
+type Holder interface {
+	GetExtensions() map[string]*any.Any
+}
+
+// Registry keeps track of the extension keys known by this application and the message stored under each of them.
+type Registry struct {
+	mux  sync.RWMutex
+	keys map[string]protoreflect.FullName
+}
+
+// Key is a typed handle for the extension stored under Name.
+type Key[T proto.Message] struct {
+	Name string
+}
+
+// Register adds the key to the registry and returns a typed handle for it.
+// It panics if the key is already registered for a different message, the same way the protobuf registry does.
+func Register[T proto.Message](name string) Key[T] {
+	var message T
+	registry.register(name, message.ProtoReflect().Descriptor().FullName())
+
+	return Key[T]{Name: name}
+}
+
+// IsNotFound returns true if the error was caused by a missing extension.
+func IsNotFound(err error) bool {
+	return errors.Cause(err) == ErrExtensionNotFound
+}
+
+func (r *Registry) register(name string, fullName protoreflect.FullName) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if registered, ok := r.keys[name]; ok && registered != fullName {
+		panic(fmt.Sprintf("extension %q is already registered for %s", name, registered))
+	}
+
+	r.keys[name] = fullName
+}
+
+// Get decodes the extension from the holder. It returns ErrExtensionNotFound if the holder does not carry it.
+func (k Key[T]) Get(holder Holder) (T, error) {
+	var message T
+	if isNil(holder) {
+		return message, ErrHolderIsNil
+	}
+
+	return k.GetFrom(holder.GetExtensions())
+}
+
+// GetFrom decodes the extension from an extensions map.
+func (k Key[T]) GetFrom(extensions map[string]*any.Any) (T, error) {
+	var message T
+
+	obj, ok := extensions[k.Name]
+	if !ok || obj == nil {
+		return message, errors.Wrapf(ErrExtensionNotFound, "key %s", k.Name)
+	}
+
+	message = message.ProtoReflect().Type().New().Interface().(T)
+	if err := FromAny(obj, protov1.MessageV1(message)); err != nil {
+		return message, errors.Wrapf(err, "key %s", k.Name)
+	}
+
+	return message, nil
+}
+
+// Set encodes the value and stores it in the holder extensions, creating the map if needed.
+func (k Key[T]) Set(holder Holder, value T) error {
+	if isNil(holder) {
+		return ErrHolderIsNil
+	}
+
+	obj, err := ToAny(protov1.MessageV1(value))
+	if err != nil {
+		return errors.Wrapf(err, "key %s", k.Name)
+	}
+
+	if extensions := holder.GetExtensions(); extensions != nil {
+		extensions[k.Name] = obj
+		return nil
+	}
+
+	extensions := map[string]*any.Any{k.Name: obj}
+	switch h := holder.(type) {
+	case *pb.Match:
+		h.Extensions = extensions
+	case *pb.MatchProfile:
+		h.Extensions = extensions
+	case *pb.Assignment:
+		h.Extensions = extensions
+	case *pb.Ticket:
+		h.Extensions = extensions
+	case *pb.Backfill:
+		h.Extensions = extensions
+	default:
+		return errors.Errorf("extensions can't be set on %T", holder)
+	}
+
+	return nil
+}
+
+// SetTo encodes the value and stores it in an extensions map.
+func (k Key[T]) SetTo(extensions map[string]*any.Any, value T) error {
+	obj, err := ToAny(protov1.MessageV1(value))
+	if err != nil {
+		return errors.Wrapf(err, "key %s", k.Name)
+	}
+
+	extensions[k.Name] = obj
+	return nil
+}
+
+func isNil(holder Holder) bool {
+	switch h := holder.(type) {
+	case nil:
+		return true
+	case *pb.Match:
+		return h == nil
+	case *pb.MatchProfile:
+		return h == nil
+	case *pb.Assignment:
+		return h == nil
+	case *pb.Ticket:
+		return h == nil
+	case *pb.Backfill:
+		return h == nil
+	}
+
+	return false
+}
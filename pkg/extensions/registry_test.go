@@ -0,0 +1,127 @@
+package extensions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestKey_SetGet(t *testing.T) {
+	filter := &extpb.AllocatorFilter{
+		Labels: map[string]string{"region": "us-east-1"},
+		Fields: map[string]string{"status.state": "Ready"},
+	}
+
+	testCases := []struct {
+		name   string
+		holder Holder
+	}{
+		{
+			name:   "it should set and get the filter on a Match",
+			holder: &pb.Match{},
+		},
+		{
+			name:   "it should set and get the filter on a MatchProfile",
+			holder: &pb.MatchProfile{},
+		},
+		{
+			name:   "it should set and get the filter on an Assignment",
+			holder: &pb.Assignment{},
+		},
+		{
+			name: "it should set and get the filter on an Assignment with other extensions",
+			holder: &pb.Assignment{
+				Extensions: map[string]*any.Any{},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, Filter.Set(tc.holder, filter))
+			require.Contains(t, tc.holder.GetExtensions(), Filter.Name)
+
+			got, err := Filter.Get(tc.holder)
+			require.NoError(t, err)
+			require.Equal(t, filter.GetLabels(), got.GetLabels())
+			require.Equal(t, filter.GetFields(), got.GetFields())
+		})
+	}
+}
+
+func TestKey_GetNotFound(t *testing.T) {
+	testCases := []struct {
+		name   string
+		holder Holder
+	}{
+		{
+			name:   "it should return not found for a Match without extensions",
+			holder: &pb.Match{},
+		},
+		{
+			name: "it should return not found for a MatchProfile without the key",
+			holder: &pb.MatchProfile{
+				Extensions: map[string]*any.Any{"other": {}},
+			},
+		},
+		{
+			name:   "it should return not found for an Assignment without extensions",
+			holder: &pb.Assignment{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Filter.Get(tc.holder)
+			require.Error(t, err)
+			require.True(t, IsNotFound(err))
+			require.Nil(t, got)
+		})
+	}
+
+	t.Run("it should return error for nil holder", func(t *testing.T) {
+		var assignment *pb.Assignment
+		_, err := Filter.Get(assignment)
+		require.Equal(t, ErrHolderIsNil, err)
+		require.Equal(t, ErrHolderIsNil, Filter.Set(assignment, &extpb.AllocatorFilter{}))
+	})
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("it should register the filter key", func(t *testing.T) {
+		require.EqualValues(t, "octops.extensions.AllocatorFilter", registry.keys[Filter.Name])
+	})
+
+	t.Run("it should allow registering the same key for the same message", func(t *testing.T) {
+		require.NotPanics(t, func() { Register[*extpb.AllocatorFilter](Filter.Name) })
+	})
+
+	t.Run("it should panic registering the same key for a different message", func(t *testing.T) {
+		require.Panics(t, func() { Register[*wrappers.StringValue](Filter.Name) })
+	})
+}
+
+func TestGetFilter(t *testing.T) {
+	t.Run("it should return the filter from the Assignment", func(t *testing.T) {
+		want := AllocatorFilterExtension{
+			Labels: map[string]string{"world": "Dune"},
+			Fields: map[string]string{"status.state": "Ready"},
+		}
+
+		extensions, err := want.Any()
+		require.NoError(t, err)
+
+		got, err := GetFilter(&pb.Assignment{Extensions: extensions})
+		require.NoError(t, err)
+		require.Equal(t, &want, got)
+	})
+
+	t.Run("it should return not found instead of nil filter", func(t *testing.T) {
+		got, err := GetFilter(&pb.Assignment{Extensions: map[string]*any.Any{}})
+		require.True(t, IsNotFound(err))
+		require.Nil(t, got)
+	})
+}
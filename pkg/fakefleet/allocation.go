@@ -0,0 +1,155 @@
+package fakefleet
+
+import (
+	pb "agones.dev/agones/pkg/allocation/go"
+	"context"
+	"crypto/tls"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+// AllocationServer implements the Agones Allocator Service over the fleet. The selectors are tried in order, the first
+// Ready GameServer matching a selector is Allocated. Without selectors the preferred ones are tried before the required.
+type AllocationServer struct {
+	pb.UnimplementedAllocationServiceServer
+	Fleet *Fleet
+}
+
+// NewAllocationGRPCServer serves the AllocationServer with TLS. The client certificate is required if the TLS config
+// has client CAs, like the Agones Allocator Service does.
+func NewAllocationGRPCServer(fleet *Fleet, tlsConfig *tls.Config) *grpc.Server {
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	pb.RegisterAllocationServiceServer(server, &AllocationServer{Fleet: fleet})
+
+	return server
+}
+
+func (s *AllocationServer) Allocate(ctx context.Context, req *pb.AllocationRequest) (*pb.AllocationResponse, error) {
+	logger := runtime.Logger().WithField("component", "fake_allocator")
+
+	selectors := req.GetGameServerSelectors()
+	if len(selectors) == 0 {
+		selectors = append(selectors, req.GetPreferredGameServerSelectors()...)
+		if req.GetRequiredGameServerSelector() != nil {
+			selectors = append(selectors, req.GetRequiredGameServerSelector())
+		}
+	}
+
+	if len(selectors) == 0 {
+		selectors = []*pb.GameServerSelector{{}}
+	}
+
+	for _, selector := range selectors {
+		gs, err := s.Fleet.Allocate(func(gs *allocator.GameServer) bool {
+			return (len(req.GetNamespace()) == 0 || req.GetNamespace() == gs.Namespace) && MatchSelector(gs, selector)
+		}, SelectorPlayers(selector))
+		if err != nil {
+			continue
+		}
+
+		logger.Infof("gameserver %s allocated", gs.Name)
+		return allocationResponse(gs), nil
+	}
+
+	return nil, status.Error(codes.ResourceExhausted, allocator.NotAvailableGameServerToAllocateMessage)
+}
+
+// MatchSelector returns true if the GameServer has the labels, state, players, counters and lists of the selector
+func MatchSelector(gs *allocator.GameServer, selector *pb.GameServerSelector) bool {
+	if !MatchLabels(gs, selector.GetMatchLabels()) {
+		return false
+	}
+
+	state := StateReady
+	if selector.GetGameServerState() == pb.GameServerSelector_ALLOCATED {
+		state = StateAllocated
+	}
+	if gs.Status.State != state {
+		return false
+	}
+
+	if players := selector.GetPlayers(); players != nil {
+		if gs.Status.Players == nil {
+			return false
+		}
+
+		available := uint64(gs.Status.Players.Capacity - gs.Status.Players.Count)
+		if available < players.GetMinAvailable() || (players.GetMaxAvailable() > 0 && available > players.GetMaxAvailable()) {
+			return false
+		}
+	}
+
+	for name, counterSelector := range selector.GetCounters() {
+		counter, ok := gs.Counter(name)
+		if !ok || !inBounds(counter.Count, counterSelector.GetMinCount(), counterSelector.GetMaxCount()) ||
+			!inBounds(counter.Available(), counterSelector.GetMinAvailable(), counterSelector.GetMaxAvailable()) {
+			return false
+		}
+	}
+
+	for name, listSelector := range selector.GetLists() {
+		list, ok := gs.List(name)
+		if !ok || !inBounds(list.Available(), listSelector.GetMinAvailable(), listSelector.GetMaxAvailable()) {
+			return false
+		}
+
+		if len(listSelector.GetContainsValue()) > 0 && !hasValue(list.Values, listSelector.GetContainsValue()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SelectorPlayers returns the players the selector asks room for, the director sets it on the players, counter or
+// list selector of the capacity policy. It is one if the selector doesn't ask for room.
+func SelectorPlayers(selector *pb.GameServerSelector) int64 {
+	players := max(1, int64(selector.GetPlayers().GetMinAvailable()))
+	for _, counter := range selector.GetCounters() {
+		players = max(players, counter.GetMinAvailable())
+	}
+
+	for _, list := range selector.GetLists() {
+		players = max(players, list.GetMinAvailable())
+	}
+
+	return players
+}
+
+// inBounds treats a zero max as unbounded, like Agones does
+func inBounds(value, min, max int64) bool {
+	return value >= min && (max == 0 || value <= max)
+}
+
+func hasValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func allocationResponse(gs *allocator.GameServer) *pb.AllocationResponse {
+	resp := &pb.AllocationResponse{
+		GameServerName: gs.Name,
+		Address:        gs.Status.Address,
+		NodeName:       gs.Status.NodeName,
+		Source:         "local",
+		Metadata: &pb.AllocationResponse_GameServerMetadata{
+			Labels:      gs.Labels,
+			Annotations: gs.Annotations,
+		},
+	}
+
+	for _, port := range gs.Status.Ports {
+		resp.Ports = append(resp.Ports, &pb.AllocationResponse_GameServerStatusPort{Name: port.Name, Port: port.Port})
+	}
+
+	return resp
+}
@@ -0,0 +1,100 @@
+package fakefleet
+
+import (
+	pb_agones "agones.dev/agones/pkg/allocation/go"
+	"context"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+)
+
+func TestAllocationServer(t *testing.T) {
+	fleet, err := NewFleet([]FleetSpec{
+		{Name: "dune", Replicas: 1, Labels: map[string]string{"world": "Dune"}, Capacity: 10},
+		{Name: "nova", Replicas: 1, Labels: map[string]string{"world": "Nova"}, Counters: map[string]int64{"players": 4}},
+	}, "127.0.0.1", 7000)
+	require.NoError(t, err)
+
+	certs, err := GenerateCertificates(t.TempDir())
+	require.NoError(t, err)
+
+	tlsConfig, err := config.ServerTLSConfig(certs.ServerCert, certs.ServerKey, certs.CACert)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := NewAllocationGRPCServer(fleet, tlsConfig)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	client, err := allocator.NewAgonesAllocatorClient(&allocator.AgonesAllocatorClientConfig{
+		KeyFile:              certs.ClientKey,
+		CertFile:             certs.ClientCert,
+		CaCertFile:           certs.CACert,
+		AllocatorServiceHost: "127.0.0.1",
+		AllocatorServicePort: ln.Addr().(*net.TCPAddr).Port,
+		Namespace:            "default",
+	})
+	require.NoError(t, err)
+
+	t.Run("it should allocate a Ready gameserver with the labels over mTLS", func(t *testing.T) {
+		resp, err := client.Allocate(context.Background(), &pb_agones.AllocationRequest{
+			Namespace:           "default",
+			GameServerSelectors: []*pb_agones.GameServerSelector{{MatchLabels: map[string]string{"world": "Dune"}}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "dune-0", resp.GetGameServerName())
+		require.Equal(t, "127.0.0.1", resp.GetAddress())
+		require.Equal(t, int32(7000), resp.GetPorts()[0].GetPort())
+
+		gs, err := fleet.Get("dune-0")
+		require.NoError(t, err)
+		require.Equal(t, StateAllocated, gs.Status.State)
+	})
+
+	t.Run("it should answer with the message of Agones when no gameserver is available", func(t *testing.T) {
+		_, err := client.Allocate(context.Background(), &pb_agones.AllocationRequest{
+			Namespace:           "default",
+			GameServerSelectors: []*pb_agones.GameServerSelector{{MatchLabels: map[string]string{"world": "Dune"}}},
+		})
+		require.Error(t, err)
+		require.Equal(t, allocator.NotAvailableGameServerToAllocateMessage, status.Convert(err).Message())
+	})
+
+	t.Run("it should allocate with the counter selector of the capacity policy", func(t *testing.T) {
+		policy := &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_COUNTER, Key: "players"}
+		selector := allocator.GameServerSelectorWithCapacity(map[string]string{"world": "Nova"}, policy, 5)
+		_, err := client.Allocate(context.Background(), &pb_agones.AllocationRequest{GameServerSelectors: []*pb_agones.GameServerSelector{selector}})
+		require.Error(t, err, "the counter has room for 4")
+
+		selector.Counters["players"].MinAvailable = 4
+		resp, err := client.Allocate(context.Background(), &pb_agones.AllocationRequest{GameServerSelectors: []*pb_agones.GameServerSelector{selector}})
+		require.NoError(t, err)
+		require.Equal(t, "nova-0", resp.GetGameServerName())
+	})
+
+	t.Run("it should ask room for the players of the selector", func(t *testing.T) {
+		policy := &extpb.CapacityPolicy{Source: extpb.CapacityPolicy_LIST, Key: "players"}
+		require.Equal(t, int64(3), SelectorPlayers(allocator.GameServerSelectorWithCapacity(nil, policy, 3)))
+		require.Equal(t, int64(1), SelectorPlayers(&pb_agones.GameServerSelector{}))
+	})
+
+	t.Run("it should refuse clients without a certificate", func(t *testing.T) {
+		tlsConfig, err := config.ClientTLSConfig(certs.CACert, "", "", "")
+		require.NoError(t, err)
+
+		conn, err := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = pb_agones.NewAllocationServiceClient(conn).Allocate(context.Background(), &pb_agones.AllocationRequest{})
+		require.Error(t, err)
+	})
+}
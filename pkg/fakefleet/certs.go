@@ -0,0 +1,128 @@
+package fakefleet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/pkg/errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const certificatesValidity = 365 * 24 * time.Hour
+
+// Certificates are the files of a self-signed CA and of the server and client certificates signed by it
+type Certificates struct {
+	CACert     string
+	ServerCert string
+	ServerKey  string
+	ClientCert string
+	ClientKey  string
+}
+
+// GenerateCertificates writes the certificates of the fake Allocator Service to the dir. The server certificate is
+// valid for localhost, 127.0.0.1 and the hosts. The director uses the client certificate and the CA.
+func GenerateCertificates(dir string, hosts ...string) (*Certificates, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create certificates dir %s", dir)
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-fleet-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(certificatesValidity),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create CA certificate")
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := &Certificates{}
+	if certs.CACert, err = writePEM(dir, "ca.crt", "CERTIFICATE", caDER); err != nil {
+		return nil, err
+	}
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) (string, string, error) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return "", "", err
+		}
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(certificatesValidity),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+			DNSNames:     []string{"localhost"},
+		}
+
+		for _, host := range hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else if len(host) > 0 {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "failed to create %s certificate", name)
+		}
+
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return "", "", err
+		}
+
+		certFile, err := writePEM(dir, name+".crt", "CERTIFICATE", der)
+		if err != nil {
+			return "", "", err
+		}
+
+		keyFile, err := writePEM(dir, name+".key", "EC PRIVATE KEY", keyDER)
+		return certFile, keyFile, err
+	}
+
+	if certs.ServerCert, certs.ServerKey, err = issue("server", 2, x509.ExtKeyUsageServerAuth); err != nil {
+		return nil, err
+	}
+
+	if certs.ClientCert, certs.ClientKey, err = issue("client", 3, x509.ExtKeyUsageClientAuth); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+func writePEM(dir, name, blockType string, der []byte) (string, error) {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		return "", errors.Wrapf(err, "failed to write %s", path)
+	}
+
+	return path, nil
+}
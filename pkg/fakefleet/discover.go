@@ -0,0 +1,128 @@
+package fakefleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/pkg/errors"
+	"net/http"
+	"strings"
+)
+
+// DiscoverHandler serves the Octops Discover API for the fleet. GET /api/v1/gameservers returns the GameServers with
+// all the labels and fields of the query, both encoded as comma separated key=value pairs,
+// i.e. ?labels=region=us-east-1,world=Dune&fields=status.state=Ready
+func DiscoverHandler(fleet *Fleet) http.Handler {
+	logger := runtime.Logger().WithField("component", "fake_discover")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(allocator.GET_GAMESERVER_PATH, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		labels, err := ParseSelector(r.URL.Query().Get("labels"))
+		if err != nil {
+			http.Error(w, errors.Wrap(err, "invalid labels").Error(), http.StatusBadRequest)
+			return
+		}
+
+		fields, err := ParseSelector(r.URL.Query().Get("fields"))
+		if err != nil {
+			http.Error(w, errors.Wrap(err, "invalid fields").Error(), http.StatusBadRequest)
+			return
+		}
+
+		gameservers := fleet.List(func(gs *allocator.GameServer) bool {
+			return MatchLabels(gs, labels) && MatchFields(gs, fields)
+		})
+		if gameservers == nil {
+			gameservers = []*allocator.GameServer{}
+		}
+
+		logger.Debugf("%d gameservers found for labels %v and fields %v", len(gameservers), labels, fields)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(allocator.GameServersResponse{Data: gameservers}); err != nil {
+			logger.Warn(errors.Wrap(err, "failed to write gameservers response").Error())
+		}
+	})
+
+	return mux
+}
+
+// ParseSelector parses comma separated key=value pairs, the format of the filter extension
+func ParseSelector(selector string) (map[string]string, error) {
+	pairs := map[string]string{}
+	if len(selector) == 0 {
+		return pairs, nil
+	}
+
+	for _, pair := range strings.Split(selector, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			return nil, errors.Errorf("%q is not a key=value pair", pair)
+		}
+		pairs[kv[0]] = kv[1]
+	}
+
+	return pairs, nil
+}
+
+// MatchLabels returns true if the GameServer has all the labels
+func MatchLabels(gs *allocator.GameServer, labels map[string]string) bool {
+	for k, v := range labels {
+		if value, ok := gs.Labels[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchFields returns true if the fields of the GameServer, addressed by their JSON path, have the values.
+// The metadata prefix of the Kubernetes object is optional, i.e. metadata.name and name are the same field.
+func MatchFields(gs *allocator.GameServer, fields map[string]string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+
+	data, err := json.Marshal(gs)
+	if err != nil {
+		return false
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return false
+	}
+
+	for path, want := range fields {
+		value, ok := lookupField(object, strings.Split(strings.TrimPrefix(path, "metadata."), "."))
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+func lookupField(object map[string]interface{}, path []string) (interface{}, bool) {
+	value, ok := object[path[0]]
+	if !ok {
+		return nil, false
+	}
+
+	if len(path) == 1 {
+		return value, true
+	}
+
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	return lookupField(nested, path[1:])
+}
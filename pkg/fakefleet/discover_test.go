@@ -0,0 +1,104 @@
+package fakefleet
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestDiscoverHandler(t *testing.T) {
+	fleet, err := NewFleet([]FleetSpec{
+		{Name: "dune", Replicas: 2, Labels: map[string]string{"world": "Dune", "region": "us-east-1"}, Capacity: 2},
+		{Name: "nova", Replicas: 1, Labels: map[string]string{"world": "Nova", "region": "us-east-1"}, Capacity: 2},
+	}, "127.0.0.1", 7000)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(DiscoverHandler(fleet))
+	defer server.Close()
+
+	client, err := allocator.NewAgonesDiscoverClientHTTP(server.URL)
+	require.NoError(t, err)
+	discover := &allocator.AgonesDiscoverAllocator{Client: client}
+
+	filter := &extensions.AllocatorFilterExtension{
+		Labels: map[string]string{"world": "Dune", "region": "us-east-1"},
+		Fields: map[string]string{"status.state": "Ready"},
+	}
+
+	t.Run("it should return the gameservers with the labels and fields of the filter", func(t *testing.T) {
+		gameservers, err := discover.ListGameServers(context.Background(), filter)
+		require.NoError(t, err)
+		require.Len(t, gameservers, 2)
+		require.Equal(t, "dune-0", gameservers[0].Name)
+
+		gameservers, err = discover.ListGameServers(context.Background(), &extensions.AllocatorFilterExtension{Fields: map[string]string{"status.state": "Allocated"}})
+		require.NoError(t, err)
+		require.Empty(t, gameservers)
+	})
+
+	t.Run("it should report the players connected to the assigned gameserver", func(t *testing.T) {
+		group := assignmentGroup(t, filter, "t1", "t2")
+		require.NoError(t, discover.Allocate(context.Background(), &pb.AssignTicketsRequest{Assignments: []*pb.AssignmentGroup{group}}))
+		require.Equal(t, "127.0.0.1:7000", group.GetAssignment().GetConnection())
+
+		_, err := fleet.Connect("dune-0")
+		require.NoError(t, err)
+		_, err = fleet.Connect("dune-0")
+		require.NoError(t, err)
+
+		// dune-0 is full, the next match goes to dune-1
+		group = assignmentGroup(t, filter, "t3")
+		require.NoError(t, discover.Allocate(context.Background(), &pb.AssignTicketsRequest{Assignments: []*pb.AssignmentGroup{group}}))
+		require.Equal(t, "127.0.0.1:7001", group.GetAssignment().GetConnection())
+
+		gs, err := discover.GetGameServer(context.Background(), "dune-0", filter)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), gs.Status.Players.Count)
+	})
+
+	t.Run("it should reject invalid selectors", func(t *testing.T) {
+		resp, err := http.Get(server.URL + allocator.GET_GAMESERVER_PATH + "?labels=world")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestMatchFields(t *testing.T) {
+	gs := &allocator.GameServer{
+		Name:   "dune-0",
+		Status: &allocator.GameServerStatus{State: StateReady, Players: &allocator.PlayerStatus{Capacity: 10}},
+	}
+
+	testCases := []struct {
+		name   string
+		fields map[string]string
+		want   bool
+	}{
+		{name: "it should match a nested field", fields: map[string]string{"status.state": "Ready"}, want: true},
+		{name: "it should match a metadata field", fields: map[string]string{"metadata.name": "dune-0"}, want: true},
+		{name: "it should match a number", fields: map[string]string{"status.players.capacity": "10"}, want: true},
+		{name: "it should not match a different value", fields: map[string]string{"status.state": "Allocated"}},
+		{name: "it should not match a missing field", fields: map[string]string{"status.nodeName": "node"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, MatchFields(gs, tc.fields))
+		})
+	}
+}
+
+func assignmentGroup(t *testing.T, filter *extensions.AllocatorFilterExtension, tickets ...string) *pb.AssignmentGroup {
+	assignment := &pb.Assignment{}
+	require.NoError(t, extensions.Filter.Set(assignment, filter.Proto()))
+	require.NoError(t, extensions.Connection.Set(assignment, &extpb.ConnectionFormat{Template: extensions.DefaultConnectionTemplate}))
+
+	return &pb.AssignmentGroup{TicketIds: tickets, Assignment: assignment}
+}
@@ -0,0 +1,461 @@
+// Package fakefleet keeps an in-memory fleet of GameServers behind fakes of the Octops Discover HTTP API and the
+// Agones Allocator Service, so the director runs without Kubernetes. Players connecting to the address of a
+// GameServer are counted against its player capacity, Counters and Lists like the Agones SDK of a game server does.
+package fakefleet
+
+import (
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	StateReady     = "Ready"
+	StateAllocated = "Allocated"
+
+	// FleetLabel is the label Agones sets with the name of the Fleet of the GameServer
+	FleetLabel = "agones.dev/fleet"
+	// PortName is the name of the port of every GameServer, the default of Agones
+	PortName = "default"
+
+	defaultNamespace = "default"
+	nodeName         = "fake-node"
+)
+
+var (
+	ErrGameServerFull = errors.New("the gameserver has no room for another player")
+)
+
+// FleetSpec describes a set of identical GameServers
+type FleetSpec struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace"`
+	Replicas  int               `yaml:"replicas"`
+	Labels    map[string]string `yaml:"labels"`
+	// Capacity is the player capacity, zero turns off the player tracking of the GameServers
+	Capacity int64 `yaml:"capacity"`
+	// Counters and Lists map the name of the Agones Counters and Lists to their capacity
+	Counters map[string]int64 `yaml:"counters"`
+	Lists    map[string]int64 `yaml:"lists"`
+}
+
+// DefaultFleets matches the game scenario of the README: one Fleet per world and region, except Orion on us-east-1
+func DefaultFleets() []FleetSpec {
+	var specs []FleetSpec
+	for _, world := range []string{"Dune", "Nova", "Pandora", "Orion"} {
+		for _, region := range []string{"us-east-1", "us-east-2", "us-west-1", "us-west-2"} {
+			if world == "Orion" && region == "us-east-1" {
+				continue
+			}
+
+			specs = append(specs, FleetSpec{
+				Name:     fmt.Sprintf("fleet-%s-%s", region, world),
+				Replicas: 2,
+				Labels:   map[string]string{"world": world, "region": region},
+				Capacity: 10,
+			})
+		}
+	}
+
+	return specs
+}
+
+// LoadFleets reads a YAML file with a list of FleetSpec
+func LoadFleets(file string) ([]FleetSpec, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read fleets file %s", file)
+	}
+
+	var specs []FleetSpec
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse fleets file %s", file)
+	}
+
+	return specs, nil
+}
+
+// Fleet holds the GameServers of the specs. Every GameServer gets its own port, starting at the port base.
+type Fleet struct {
+	// SessionDuration is how long a player stays connected. An Allocated GameServer without players for that long is
+	// Ready again, like the replacement Agones creates once a game session ends. Zero keeps the GameServers Allocated.
+	SessionDuration time.Duration
+	// ConnectOnAllocate connects the players of an allocation to the GameServer, so the fleet fills up without the
+	// players connecting to it
+	ConnectOnAllocate bool
+
+	mux         sync.Mutex
+	gameservers []*gameServerState
+	now         func() time.Time
+}
+
+type gameServerState struct {
+	gs *allocator.GameServer
+	// idleSince is the time the GameServer was allocated or its last player left
+	idleSince time.Time
+	version   int
+	// players holds the ids of the connected players
+	players map[string]bool
+}
+
+func NewFleet(specs []FleetSpec, address string, portBase int32) (*Fleet, error) {
+	if len(specs) == 0 {
+		return nil, errors.New("the fleet requires at least one fleet spec")
+	}
+
+	fleet := &Fleet{now: time.Now}
+	names := map[string]bool{}
+	port := portBase
+
+	for _, spec := range specs {
+		if len(spec.Name) == 0 || spec.Replicas <= 0 {
+			return nil, errors.Errorf("fleet %q requires a name and at least one replica", spec.Name)
+		}
+
+		if names[spec.Name] {
+			return nil, errors.Errorf("fleet %s is duplicated", spec.Name)
+		}
+		names[spec.Name] = true
+
+		for i := 0; i < spec.Replicas; i++ {
+			fleet.gameservers = append(fleet.gameservers, &gameServerState{gs: newGameServer(spec, i, address, port), version: 1, players: map[string]bool{}})
+			port++
+		}
+	}
+
+	sort.Slice(fleet.gameservers, func(i, j int) bool {
+		return fleet.gameservers[i].gs.Name < fleet.gameservers[j].gs.Name
+	})
+
+	return fleet, nil
+}
+
+func newGameServer(spec FleetSpec, replica int, address string, port int32) *allocator.GameServer {
+	namespace := spec.Namespace
+	if len(namespace) == 0 {
+		namespace = defaultNamespace
+	}
+
+	labels := map[string]string{FleetLabel: spec.Name}
+	for k, v := range spec.Labels {
+		labels[k] = v
+	}
+
+	status := &allocator.GameServerStatus{
+		State:    StateReady,
+		Address:  address,
+		Ports:    []allocator.GameServerStatusPort{{Name: PortName, Port: port}},
+		NodeName: nodeName,
+	}
+
+	if spec.Capacity > 0 {
+		status.Players = &allocator.PlayerStatus{Capacity: spec.Capacity}
+	}
+
+	for name, capacity := range spec.Counters {
+		if status.Counters == nil {
+			status.Counters = map[string]allocator.CounterStatus{}
+		}
+		status.Counters[name] = allocator.CounterStatus{Capacity: capacity}
+	}
+
+	for name, capacity := range spec.Lists {
+		if status.Lists == nil {
+			status.Lists = map[string]allocator.ListStatus{}
+		}
+		status.Lists[name] = allocator.ListStatus{Capacity: capacity}
+	}
+
+	return &allocator.GameServer{
+		UID:             uuid.New().String(),
+		Name:            fmt.Sprintf("%s-%d", spec.Name, replica),
+		Namespace:       namespace,
+		ResourceVersion: "1",
+		Labels:          labels,
+		Status:          status,
+	}
+}
+
+// List returns a copy of the GameServers for which match returns true
+func (f *Fleet) List(match func(gs *allocator.GameServer) bool) []*allocator.GameServer {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.recycleLocked()
+
+	var gameservers []*allocator.GameServer
+	for _, state := range f.gameservers {
+		if match == nil || match(state.gs) {
+			gameservers = append(gameservers, copyGameServer(state.gs))
+		}
+	}
+
+	return gameservers
+}
+
+// Get returns a copy of the GameServer. It returns allocator.ErrGameServersNotFound if it is not part of the fleet.
+func (f *Fleet) Get(name string) (*allocator.GameServer, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	state, ok := f.getLocked(name)
+	if !ok {
+		return nil, errors.Wrap(allocator.ErrGameServersNotFound, name)
+	}
+
+	return copyGameServer(state.gs), nil
+}
+
+// Allocate marks the first GameServer for which match returns true as Allocated and returns a copy of it. The players
+// of the allocation are connected to the GameServer if ConnectOnAllocate is set.
+// It returns allocator.ErrGameServersNotFound if none matches.
+func (f *Fleet) Allocate(match func(gs *allocator.GameServer) bool, players int64) (*allocator.GameServer, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.recycleLocked()
+
+	for _, state := range f.gameservers {
+		if !match(state.gs) {
+			continue
+		}
+
+		if state.gs.Status.State != StateAllocated {
+			state.gs.Status.State = StateAllocated
+			state.idleSince = f.now()
+		}
+
+		if f.ConnectOnAllocate {
+			for i := int64(0); i < players; i++ {
+				if _, err := f.connectLocked(state); err != nil {
+					break
+				}
+			}
+		}
+		f.bumpLocked(state)
+
+		return copyGameServer(state.gs), nil
+	}
+
+	return nil, allocator.ErrGameServersNotFound
+}
+
+// Connect adds a player to the GameServer and returns its id. The player is counted on the player tracking and on
+// every Counter, its id is added to every List. It returns ErrGameServerFull if any of their capacities is reached.
+// The player leaves after the SessionDuration.
+func (f *Fleet) Connect(name string) (string, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	state, ok := f.getLocked(name)
+	if !ok {
+		return "", errors.Wrap(allocator.ErrGameServersNotFound, name)
+	}
+
+	playerID, err := f.connectLocked(state)
+	if err != nil {
+		return "", errors.Wrap(err, name)
+	}
+	f.bumpLocked(state)
+
+	return playerID, nil
+}
+
+// connectLocked adds a player to the GameServer, the caller holds the mux and bumps the resource version
+func (f *Fleet) connectLocked(state *gameServerState) (string, error) {
+	status := state.gs.Status
+	if !hasRoom(status) {
+		return "", ErrGameServerFull
+	}
+
+	playerID := uuid.New().String()
+	state.players[playerID] = true
+	if status.Players != nil {
+		status.Players.Count++
+		status.Players.IDs = append(status.Players.IDs, playerID)
+	}
+
+	for key, counter := range status.Counters {
+		counter.Count++
+		status.Counters[key] = counter
+	}
+
+	for key, list := range status.Lists {
+		list.Values = append(list.Values, playerID)
+		status.Lists[key] = list
+	}
+
+	if f.SessionDuration > 0 {
+		name := state.gs.Name
+		time.AfterFunc(f.SessionDuration, func() { f.Disconnect(name, playerID) })
+	}
+
+	return playerID, nil
+}
+
+// Disconnect removes the player from the GameServer
+func (f *Fleet) Disconnect(name, playerID string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	state, ok := f.getLocked(name)
+	if !ok || !state.players[playerID] {
+		return
+	}
+	delete(state.players, playerID)
+
+	status := state.gs.Status
+	if status.Players != nil {
+		status.Players.Count--
+		status.Players.IDs = removeValue(status.Players.IDs, playerID)
+	}
+
+	for key, counter := range status.Counters {
+		counter.Count--
+		status.Counters[key] = counter
+	}
+
+	for key, list := range status.Lists {
+		list.Values = removeValue(list.Values, playerID)
+		status.Lists[key] = list
+	}
+
+	if len(state.players) == 0 {
+		state.idleSince = f.now()
+	}
+	f.bumpLocked(state)
+}
+
+// ByPort returns the name of the GameServer listening on the port
+func (f *Fleet) ByPort(port int32) (string, bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for _, state := range f.gameservers {
+		for _, p := range state.gs.Status.Ports {
+			if p.Port == port {
+				return state.gs.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// recycleLocked makes the Allocated GameServers idle for the SessionDuration Ready again, the caller holds the mux
+func (f *Fleet) recycleLocked() {
+	if f.SessionDuration <= 0 {
+		return
+	}
+
+	now := f.now()
+	for _, state := range f.gameservers {
+		if state.gs.Status.State != StateAllocated || len(state.players) > 0 {
+			continue
+		}
+
+		if now.Sub(state.idleSince) >= f.SessionDuration {
+			state.gs.Status.State = StateReady
+			f.bumpLocked(state)
+		}
+	}
+}
+
+func (f *Fleet) getLocked(name string) (*gameServerState, bool) {
+	for _, state := range f.gameservers {
+		if state.gs.Name == name {
+			return state, true
+		}
+	}
+
+	return nil, false
+}
+
+// bumpLocked increments the resource version on every change, like the Kubernetes API does
+func (f *Fleet) bumpLocked(state *gameServerState) {
+	state.version++
+	state.gs.ResourceVersion = strconv.Itoa(state.version)
+}
+
+// hasRoom returns true if the player tracking and every Counter and List have room for another player
+func hasRoom(status *allocator.GameServerStatus) bool {
+	if status.Players != nil && status.Players.Count >= status.Players.Capacity {
+		return false
+	}
+
+	for _, counter := range status.Counters {
+		if counter.Count >= counter.Capacity {
+			return false
+		}
+	}
+
+	for _, list := range status.Lists {
+		if int64(len(list.Values)) >= list.Capacity {
+			return false
+		}
+	}
+
+	return true
+}
+
+func removeValue(values []string, value string) []string {
+	for i, v := range values {
+		if v == value {
+			return append(values[:i:i], values[i+1:]...)
+		}
+	}
+
+	return values
+}
+
+func copyGameServer(gs *allocator.GameServer) *allocator.GameServer {
+	c := *gs
+	c.Labels = copyMap(gs.Labels)
+	c.Annotations = copyMap(gs.Annotations)
+
+	status := *gs.Status
+	status.Ports = append([]allocator.GameServerStatusPort(nil), gs.Status.Ports...)
+	if gs.Status.Players != nil {
+		players := *gs.Status.Players
+		players.IDs = append([]string(nil), gs.Status.Players.IDs...)
+		status.Players = &players
+	}
+
+	if gs.Status.Counters != nil {
+		status.Counters = map[string]allocator.CounterStatus{}
+		for k, v := range gs.Status.Counters {
+			status.Counters[k] = v
+		}
+	}
+
+	if gs.Status.Lists != nil {
+		status.Lists = map[string]allocator.ListStatus{}
+		for k, v := range gs.Status.Lists {
+			v.Values = append([]string(nil), v.Values...)
+			status.Lists[k] = v
+		}
+	}
+
+	c.Status = &status
+	return &c
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	c := map[string]string{}
+	for k, v := range m {
+		c[k] = v
+	}
+
+	return c
+}
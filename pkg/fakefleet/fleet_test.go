@@ -0,0 +1,197 @@
+package fakefleet
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/stretchr/testify/require"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewFleet(t *testing.T) {
+	t.Run("it should create the replicas of every fleet with their own port", func(t *testing.T) {
+		fleet, err := NewFleet([]FleetSpec{
+			{Name: "dune", Replicas: 2, Labels: map[string]string{"world": "Dune"}, Capacity: 10},
+			{Name: "nova", Replicas: 1, Counters: map[string]int64{"rooms": 4}},
+		}, "127.0.0.1", 7000)
+		require.NoError(t, err)
+
+		gameservers := fleet.List(nil)
+		require.Len(t, gameservers, 3)
+		require.Equal(t, "dune-0", gameservers[0].Name)
+		require.Equal(t, "Dune", gameservers[0].Labels["world"])
+		require.Equal(t, "dune", gameservers[0].Labels[FleetLabel])
+		require.Equal(t, StateReady, gameservers[0].Status.State)
+		require.Equal(t, int64(10), gameservers[0].Status.Players.Capacity)
+		require.Equal(t, int32(7001), gameservers[1].Status.Ports[0].Port)
+		require.Nil(t, gameservers[2].Status.Players)
+		require.Equal(t, int64(4), gameservers[2].Status.Counters["rooms"].Capacity)
+	})
+
+	t.Run("it should return an error for fleets without replicas or with the same name", func(t *testing.T) {
+		_, err := NewFleet([]FleetSpec{{Name: "dune"}}, "127.0.0.1", 7000)
+		require.Error(t, err)
+
+		_, err = NewFleet([]FleetSpec{{Name: "dune", Replicas: 1}, {Name: "dune", Replicas: 1}}, "127.0.0.1", 7000)
+		require.Error(t, err)
+	})
+}
+
+func TestFleet_Connect(t *testing.T) {
+	t.Run("it should count the players against the player capacity", func(t *testing.T) {
+		fleet, err := NewFleet([]FleetSpec{{Name: "dune", Replicas: 1, Capacity: 2}}, "127.0.0.1", 7000)
+		require.NoError(t, err)
+
+		first, err := fleet.Connect("dune-0")
+		require.NoError(t, err)
+		_, err = fleet.Connect("dune-0")
+		require.NoError(t, err)
+		_, err = fleet.Connect("dune-0")
+		require.ErrorIs(t, err, ErrGameServerFull)
+		_, err = fleet.Connect("nova-0")
+		require.ErrorIs(t, err, allocator.ErrGameServersNotFound)
+
+		gs, err := fleet.Get("dune-0")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), gs.Status.Players.Count)
+		require.Contains(t, gs.Status.Players.IDs, first)
+		require.Equal(t, StateReady, gs.Status.State, "players do not allocate the gameserver")
+
+		fleet.Disconnect("dune-0", first)
+		fleet.Disconnect("dune-0", first)
+		gs, err = fleet.Get("dune-0")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), gs.Status.Players.Count, "a player only leaves once")
+		require.NotContains(t, gs.Status.Players.IDs, first)
+	})
+
+	t.Run("it should count the players on the counters and lists", func(t *testing.T) {
+		fleet, err := NewFleet([]FleetSpec{{Name: "rooms", Replicas: 1, Counters: map[string]int64{"players": 3}, Lists: map[string]int64{"players": 2}}}, "127.0.0.1", 7000)
+		require.NoError(t, err)
+
+		first, err := fleet.Connect("rooms-0")
+		require.NoError(t, err)
+		second, err := fleet.Connect("rooms-0")
+		require.NoError(t, err)
+		_, err = fleet.Connect("rooms-0")
+		require.ErrorIs(t, err, ErrGameServerFull, "the list is full before the counter")
+
+		gs, err := fleet.Get("rooms-0")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), gs.Status.Counters["players"].Count)
+		require.Equal(t, []string{first, second}, gs.Status.Lists["players"].Values)
+
+		fleet.Disconnect("rooms-0", first)
+		gs, err = fleet.Get("rooms-0")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), gs.Status.Counters["players"].Count)
+		require.Equal(t, []string{second}, gs.Status.Lists["players"].Values)
+	})
+}
+
+func TestFleet_Allocate(t *testing.T) {
+	fleet, err := NewFleet([]FleetSpec{{Name: "dune", Replicas: 1, Capacity: 2}}, "127.0.0.1", 7000)
+	require.NoError(t, err)
+	fleet.SessionDuration = time.Minute
+
+	now := time.Now()
+	fleet.now = func() time.Time { return now }
+
+	ready := func(gs *allocator.GameServer) bool { return gs.Status.State == StateReady }
+
+	gs, err := fleet.Allocate(ready, 2)
+	require.NoError(t, err)
+	require.Equal(t, StateAllocated, gs.Status.State)
+	require.Zero(t, gs.Status.Players.Count, "players are only connected with ConnectOnAllocate")
+
+	_, err = fleet.Allocate(ready, 2)
+	require.ErrorIs(t, err, allocator.ErrGameServersNotFound)
+
+	// The gameserver is Ready again once it is idle for the session duration
+	now = now.Add(time.Minute)
+	gs, err = fleet.Allocate(ready, 2)
+	require.NoError(t, err)
+	require.Equal(t, "dune-0", gs.Name)
+}
+
+func TestFleet_AllocateConnect(t *testing.T) {
+	fleet, err := NewFleet([]FleetSpec{{Name: "dune", Replicas: 1, Capacity: 3, Counters: map[string]int64{"players": 3}}}, "127.0.0.1", 7000)
+	require.NoError(t, err)
+	fleet.ConnectOnAllocate = true
+
+	allocated := func(gs *allocator.GameServer) bool { return true }
+
+	gs, err := fleet.Allocate(allocated, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), gs.Status.Players.Count)
+	require.Equal(t, int64(2), gs.Status.Counters["players"].Count)
+
+	// Only the players with room are connected
+	gs, err = fleet.Allocate(allocated, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), gs.Status.Players.Count)
+	require.Equal(t, int64(3), gs.Status.Counters["players"].Count)
+}
+
+func TestFleet_ServePlayers(t *testing.T) {
+	port := freePort(t)
+	fleet, err := NewFleet([]FleetSpec{{Name: "dune", Replicas: 1, Capacity: 10}}, "127.0.0.1", port)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- fleet.ServePlayers(ctx, "127.0.0.1")
+	}()
+
+	// The player connects once the listener is up
+	addr := (&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(port)}).String()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		gs, err := fleet.Get("dune-0")
+		return err == nil && gs.Status.Players.Count == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestLoadFleets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "fleets.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+- name: fleet-us-east-1-Dune
+  replicas: 3
+  capacity: 10
+  labels:
+    world: Dune
+    region: us-east-1
+`), 0o644))
+
+	specs, err := LoadFleets(file)
+	require.NoError(t, err)
+	require.Equal(t, []FleetSpec{{
+		Name:     "fleet-us-east-1-Dune",
+		Replicas: 3,
+		Capacity: 10,
+		Labels:   map[string]string{"world": "Dune", "region": "us-east-1"},
+	}}, specs)
+}
+
+func freePort(t *testing.T) int32 {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return int32(ln.Addr().(*net.TCPAddr).Port)
+}
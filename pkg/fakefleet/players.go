@@ -0,0 +1,73 @@
+package fakefleet
+
+import (
+	"context"
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"net"
+	"sync"
+)
+
+// ServePlayers listens on the port of every GameServer of the fleet and connects a player for every TCP connection
+// accepted, i.e. the player simulator with --connect. The connections are closed right away. It blocks until the
+// context is cancelled.
+func (f *Fleet) ServePlayers(ctx context.Context, host string) error {
+	logger := runtime.Logger().WithField("component", "fake_fleet")
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+
+	for _, gs := range f.List(nil) {
+		for _, port := range gs.Status.Ports {
+			ln, err := net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(port.Port)))
+			if err != nil {
+				closeAll()
+				return errors.Wrapf(err, "failed to listen on the port of gameserver %s", gs.Name)
+			}
+			listeners = append(listeners, ln)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, ln := range listeners {
+		wg.Add(1)
+		go func(ln net.Listener) {
+			defer wg.Done()
+			f.acceptPlayers(logger, ln)
+		}(ln)
+	}
+
+	logger.Infof("accepting players on %d gameserver ports", len(listeners))
+	<-ctx.Done()
+
+	closeAll()
+	wg.Wait()
+	return nil
+}
+
+func (f *Fleet) acceptPlayers(logger *logrus.Entry, ln net.Listener) {
+	port := int32(ln.Addr().(*net.TCPAddr).Port)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Close()
+
+		name, ok := f.ByPort(port)
+		if !ok {
+			continue
+		}
+
+		if _, err := f.Connect(name); err != nil {
+			logger.Warn(errors.Wrap(err, "player rejected").Error())
+		}
+	}
+}
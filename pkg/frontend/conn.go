@@ -6,10 +6,14 @@ import (
 	"google.golang.org/grpc"
 )
 
-func FrontEndConn() (*grpc.ClientConn, error) {
+func FrontEndConn(omConfig config.OpenMatchConnConfig) (*grpc.ClientConn, error) {
 	logger := runtime.Logger()
-	omConfig := config.OpenMatch()
 
-	logger.Infof("connecting to OpenMatch FrontEnd service: %s", omConfig.FrontEnd)
-	return grpc.Dial(omConfig.FrontEnd, grpc.WithInsecure())
+	creds, err := omConfig.DialOption()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("connecting to OpenMatch FrontEnd service: %s (tls: %t)", omConfig.FrontEnd, omConfig.TLSEnabled)
+	return grpc.Dial(omConfig.FrontEnd, creds)
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
 	"open-match.dev/open-match/pkg/pb"
 )
 
@@ -28,3 +29,15 @@ func NewFrontEndService(conn *grpc.ClientConn) (*FrontEndService, error) {
 func (fe *FrontEndService) CreateTicket(ctx context.Context, ticket *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
 	return fe.frontendServiceClient.CreateTicket(ctx, ticket, opts...)
 }
+
+func (fe *FrontEndService) GetTicket(ctx context.Context, req *pb.GetTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+	return fe.frontendServiceClient.GetTicket(ctx, req, opts...)
+}
+
+func (fe *FrontEndService) DeleteTicket(ctx context.Context, req *pb.DeleteTicketRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	return fe.frontendServiceClient.DeleteTicket(ctx, req, opts...)
+}
+
+func (fe *FrontEndService) WatchAssignments(ctx context.Context, req *pb.WatchAssignmentsRequest, opts ...grpc.CallOption) (pb.FrontendService_WatchAssignmentsClient, error) {
+	return fe.frontendServiceClient.WatchAssignments(ctx, req, opts...)
+}
@@ -0,0 +1,185 @@
+package functions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+	"open-match.dev/open-match/pkg/pb"
+	"sort"
+)
+
+/*
+Criteria for Backfill Matches
+- Tickets go first to the existing backfills of the pools, up to their open slots
+- The remaining tickets are matched by MatchByGamePlayersCapacity
+- New matches with room left get a new backfill so the GameServer allocated for them is topped up later
+*/
+func MatchWithBackfill(playerCapacity int) MakeBackfillMatchesFunc {
+	return func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error) {
+		if err := ValidateMatchFunArguments(playerCapacity, profile, poolTickets); err != nil {
+			return nil, err
+		}
+
+		logger := runtime.Logger().WithFields(logrus.Fields{
+			"component": "match_function",
+			"command":   "backfill",
+		})
+
+		backfills := uniqueBackfills(poolBackfills)
+		free := make([]int64, len(backfills))
+		filled := make([][]*pb.Ticket, len(backfills))
+		for i, b := range backfills {
+			free[i] = extensions.GetOpenSlots(b)
+		}
+
+		var rest []*pb.Ticket
+		for _, t := range uniqueTickets(poolTickets) {
+			size := extensions.GetPartySize(t)
+			i := firstWithRoom(free, size)
+			if i < 0 {
+				rest = append(rest, t)
+				continue
+			}
+
+			filled[i] = append(filled[i], t)
+			free[i] -= size
+		}
+
+		var matches []*pb.Match
+		for i, b := range backfills {
+			if len(filled[i]) == 0 {
+				continue
+			}
+
+			backfill := proto.Clone(b).(*pb.Backfill)
+			if err := extensions.OpenSlots.Set(backfill, &wrappers.Int64Value{Value: free[i]}); err != nil {
+				return nil, err
+			}
+
+			match := CreateMatchForTickets(newMatchID(profile), profile.GetName(), profile.Extensions, filled[i]...)
+			match.Backfill = backfill
+			matches = append(matches, match)
+			logger.Debugf("backfill %s got %d tickets, open slots left %d", backfill.GetId(), len(filled[i]), free[i])
+		}
+
+		capacity := int64(playerCapacity)
+		for _, match := range packTickets(logger, profile, rest, capacity) {
+			if players := extensions.CountPlayers(match.Tickets...); players < capacity {
+				backfill, err := NewBackfill(profile, match.Tickets, capacity-players)
+				if err != nil {
+					return nil, err
+				}
+
+				match.Backfill = backfill
+				match.AllocateGameserver = true
+			}
+			matches = append(matches, match)
+		}
+
+		logger.Debugf("total matches for profile %s: %d", profile.GetName(), len(matches))
+		return matches, nil
+	}
+}
+
+// NewBackfill creates the backfill of a match with room left. Open Match creates it when the match is accepted.
+// Its search fields are the ones shared by the tickets so the pools that found the tickets also find the backfill.
+// The profile extensions are kept, the director uses them to bind the backfill to the allocated GameServer.
+func NewBackfill(profile *pb.MatchProfile, tickets []*pb.Ticket, openSlots int64) (*pb.Backfill, error) {
+	backfill := &pb.Backfill{
+		SearchFields: backfillSearchFields(tickets),
+		Extensions:   extensions.Clone(profile.GetExtensions()),
+	}
+
+	if err := extensions.OpenSlots.Set(backfill, &wrappers.Int64Value{Value: openSlots}); err != nil {
+		return nil, err
+	}
+
+	return backfill, nil
+}
+
+// backfillSearchFields keeps the tags and string args present with the same value in every ticket and the average of
+// the double args present in every ticket. The party size is not a property of the backfill.
+func backfillSearchFields(tickets []*pb.Ticket) *pb.SearchFields {
+	fields := &pb.SearchFields{
+		StringArgs: map[string]string{},
+		DoubleArgs: map[string]float64{},
+	}
+
+	if len(tickets) == 0 {
+		return fields
+	}
+
+	first := tickets[0].GetSearchFields()
+	for _, tag := range first.GetTags() {
+		if allTickets(tickets, func(sf *pb.SearchFields) bool { return hasTag(sf, tag) }) {
+			fields.Tags = append(fields.Tags, tag)
+		}
+	}
+
+	for k, v := range first.GetStringArgs() {
+		if allTickets(tickets, func(sf *pb.SearchFields) bool { value, ok := sf.GetStringArgs()[k]; return ok && value == v }) {
+			fields.StringArgs[k] = v
+		}
+	}
+
+	for k := range first.GetDoubleArgs() {
+		if k == extensions.PartySizeArg {
+			continue
+		}
+
+		if allTickets(tickets, func(sf *pb.SearchFields) bool { _, ok := sf.GetDoubleArgs()[k]; return ok }) {
+			var sum float64
+			for _, t := range tickets {
+				sum += t.GetSearchFields().GetDoubleArgs()[k]
+			}
+			fields.DoubleArgs[k] = sum / float64(len(tickets))
+		}
+	}
+
+	return fields
+}
+
+func allTickets(tickets []*pb.Ticket, match func(sf *pb.SearchFields) bool) bool {
+	for _, t := range tickets {
+		if !match(t.GetSearchFields()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasTag(sf *pb.SearchFields, tag string) bool {
+	for _, t := range sf.GetTags() {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// uniqueBackfills returns the backfills of the pools sorted by pool name, a backfill present in more than one pool is returned once
+func uniqueBackfills(poolBackfills map[string][]*pb.Backfill) []*pb.Backfill {
+	pools := make([]string, 0, len(poolBackfills))
+	for pool := range poolBackfills {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	var backfills []*pb.Backfill
+	seen := map[string]bool{}
+	for _, pool := range pools {
+		for _, b := range poolBackfills[pool] {
+			if seen[b.GetId()] {
+				continue
+			}
+			seen[b.GetId()] = true
+			backfills = append(backfills, b)
+		}
+	}
+
+	return backfills
+}
@@ -0,0 +1,79 @@
+package functions
+
+import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestMatchWithBackfill(t *testing.T) {
+	connection := &extpb.ConnectionFormat{Template: "{address}:{port:game}"}
+	profile := &pb.MatchProfile{Name: "profile"}
+	require.NoError(t, extensions.Connection.Set(profile, connection))
+
+	backfill := func(id string, openSlots int64) *pb.Backfill {
+		b := &pb.Backfill{Id: id}
+		require.NoError(t, extensions.OpenSlots.Set(b, &wrappers.Int64Value{Value: openSlots}))
+		return b
+	}
+
+	t.Run("it should fill the open slots of the existing backfills first", func(t *testing.T) {
+		b1 := backfill("b1", 3)
+		tickets := []*pb.Ticket{partyTicket(2), partyTicket(2), partyTicket(1)}
+
+		matches, err := MatchWithBackfill(4)(profile, map[string][]*pb.Ticket{"pool": tickets}, map[string][]*pb.Backfill{"pool": {b1}, "other": {b1}})
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+
+		require.Equal(t, "b1", matches[0].GetBackfill().GetId())
+		require.False(t, matches[0].GetAllocateGameserver())
+		require.Equal(t, []*pb.Ticket{tickets[0], tickets[2]}, matches[0].Tickets)
+		require.Equal(t, int64(0), extensions.GetOpenSlots(matches[0].GetBackfill()))
+		require.Equal(t, int64(3), extensions.GetOpenSlots(b1), "the backfill of the pool must not be changed")
+
+		require.Equal(t, []*pb.Ticket{tickets[1]}, matches[1].Tickets)
+		require.True(t, matches[1].GetAllocateGameserver())
+		require.Empty(t, matches[1].GetBackfill().GetId())
+		require.Equal(t, int64(2), extensions.GetOpenSlots(matches[1].GetBackfill()))
+
+		template, err := extensions.GetConnectionTemplate(matches[1].GetBackfill())
+		require.NoError(t, err)
+		require.Equal(t, connection.Template, template)
+	})
+
+	t.Run("it should not create backfills for full matches", func(t *testing.T) {
+		matches, err := MatchWithBackfill(2)(profile, map[string][]*pb.Ticket{"pool": {partyTicket(2)}}, nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 1)
+		require.Nil(t, matches[0].GetBackfill())
+		require.False(t, matches[0].GetAllocateGameserver())
+	})
+
+	t.Run("it should validate the arguments", func(t *testing.T) {
+		_, err := MatchWithBackfill(0)(profile, map[string][]*pb.Ticket{}, nil)
+		require.Equal(t, ErrPlayersCapacityInvalid, err)
+	})
+}
+
+func TestBackfillSearchFields(t *testing.T) {
+	tickets := []*pb.Ticket{
+		{SearchFields: &pb.SearchFields{
+			Tags:       []string{"mode.session", "ranked"},
+			StringArgs: map[string]string{"region": "us-east-1", "world": "Dune"},
+			DoubleArgs: map[string]float64{"skill": 100, "latency": 25, extensions.PartySizeArg: 2},
+		}},
+		{SearchFields: &pb.SearchFields{
+			Tags:       []string{"mode.session"},
+			StringArgs: map[string]string{"region": "us-east-1", "world": "Nova"},
+			DoubleArgs: map[string]float64{"skill": 300, "latency": 75},
+		}},
+	}
+
+	fields := backfillSearchFields(tickets)
+	require.Equal(t, []string{"mode.session"}, fields.Tags)
+	require.Equal(t, map[string]string{"region": "us-east-1"}, fields.StringArgs)
+	require.Equal(t, map[string]float64{"skill": 200, "latency": 50}, fields.DoubleArgs)
+}
@@ -1,13 +1,15 @@
 package functions
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
 	"github.com/golang/protobuf/ptypes/any"
 	"github.com/sirupsen/logrus"
 	"open-match.dev/open-match/pkg/pb"
+	"sort"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,11 +19,15 @@ const (
 
 var (
 	ErrPlayersCapacityInvalid = errors.New("player capacity must be higher than zero")
+
+	// matchSeq keeps the match ids unique for the matches created at the same time
+	matchSeq atomic.Int64
 )
 
 /*
 Criteria for Matches
-- Number or tickets should not exceed the PlayerCapacity set by the Status.Players.Capacity field from the GS
+- Number of players should not exceed the PlayerCapacity set by the Status.Players.Capacity field from the GS
+- Party tickets count as the number of players of the party and are never split across matches
 */
 func MatchByGamePlayersCapacity(playerCapacity int) MakeMatchesFunc {
 	return func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket) ([]*pb.Match, error) {
@@ -34,49 +40,78 @@ func MatchByGamePlayersCapacity(playerCapacity int) MakeMatchesFunc {
 			"command":   "matchmaker",
 		})
 
-		ctx, cancel := context.WithCancel(context.Background())
-		chTickets := make(chan *pb.Ticket)
+		matches := packTickets(logger, profile, uniqueTickets(poolTickets), int64(playerCapacity))
 
-		go func(pool map[string][]*pb.Ticket) {
-			defer func() {
-				cancel()
-			}()
+		logger.Debugf("total matches for profile %s: %d", profile.GetName(), len(matches))
+		return matches, nil
+	}
+}
 
-			for _, tickets := range pool {
-				for _, ticket := range tickets {
-					t := ticket
-					chTickets <- t
-				}
-			}
-		}(poolTickets)
-
-		var tickets []*pb.Ticket
-		var matches []*pb.Match
-		var match *pb.Match
-
-		for {
-			select {
-			case t := <-chTickets:
-				logger.Debugf("creating match for ticket %s", t.Id)
-				if tickets == nil || len(match.Tickets) == playerCapacity {
-					tickets = []*pb.Ticket{}
-					tickets = append(tickets, t)
-					id := fmt.Sprintf("profile-%v-%v", profile.GetName(), time.Now().UnixNano())
-					matches = append(matches, CreateMatchForTickets(id, profile.GetName(), profile.Extensions, tickets...))
-					match = matches[len(matches)-1]
-					break
-				}
-
-				if len(match.Tickets) < playerCapacity {
-					match.Tickets = append(match.Tickets, t)
-					break
-				}
-			case <-ctx.Done():
-				logger.Debugf("total matches for profile %s: %d", profile.GetName(), len(matches))
-				return matches, nil
+// uniqueTickets returns the tickets of the pools sorted by pool name. A ticket present in more than one pool is
+// returned once so it is part of a single match.
+func uniqueTickets(poolTickets map[string][]*pb.Ticket) []*pb.Ticket {
+	// Map order is random, sorting the pools keeps the matches of the same tickets stable
+	pools := make([]string, 0, len(poolTickets))
+	for pool := range poolTickets {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	var tickets []*pb.Ticket
+	seen := map[string]bool{}
+	for _, pool := range pools {
+		for _, t := range poolTickets[pool] {
+			if seen[t.GetId()] {
+				continue
 			}
+			seen[t.GetId()] = true
+			tickets = append(tickets, t)
+		}
+	}
+
+	return tickets
+}
+
+// packTickets adds every ticket to the first match with room for its whole party, creating matches as needed
+func packTickets(logger *logrus.Entry, profile *pb.MatchProfile, tickets []*pb.Ticket, capacity int64) []*pb.Match {
+	var matches []*pb.Match
+	var free []int64
+
+	for _, t := range tickets {
+		size := extensions.GetPartySize(t)
+		if size > capacity {
+			logger.Debugf("ticket %s skipped, party size %d exceeds the player capacity %d", t.GetId(), size, capacity)
+			continue
 		}
+
+		logger.Debugf("creating match for ticket %s", t.GetId())
+		i := firstWithRoom(free, size)
+		if i < 0 {
+			matches = append(matches, CreateMatchForTickets(newMatchID(profile), profile.GetName(), profile.Extensions, t))
+			free = append(free, capacity-size)
+			continue
+		}
+
+		matches[i].Tickets = append(matches[i].Tickets, t)
+		free[i] -= size
 	}
+
+	return matches
+}
+
+// firstWithRoom returns the index of the first free room that fits the party or -1 if none does
+func firstWithRoom(free []int64, size int64) int {
+	for i, f := range free {
+		if size <= f {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func newMatchID(profile *pb.MatchProfile) string {
+	return fmt.Sprintf("profile-%v-%v-%v", profile.GetName(), time.Now().UnixNano(), matchSeq.Add(1))
 }
 
 func CreateMatchForTickets(matchID, profileName string, extensions map[string]*any.Any, tickets ...*pb.Ticket) *pb.Match {
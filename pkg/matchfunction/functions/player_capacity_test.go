@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"open-match.dev/open-match/pkg/pb"
@@ -18,6 +19,8 @@ import (
 	- tickets % capacity != 0 return matches count == (tickets / capacity) + 1
 */
 func TestMatchByGamePlayersCapacity(t *testing.T) {
+	sharedTicket := partyTicket(2)
+
 	type wantErr struct {
 		want bool
 		err  error
@@ -30,6 +33,7 @@ func TestMatchByGamePlayersCapacity(t *testing.T) {
 		poolTickets      map[string][]*pb.Ticket
 		wantMatches      int
 		wantTotalTickets int
+		wantPlayers      []int64
 		wantErr          wantErr
 	}{
 		{
@@ -212,6 +216,54 @@ func TestMatchByGamePlayersCapacity(t *testing.T) {
 			wantMatches:      2,
 			wantTotalTickets: 4,
 		},
+		{
+			name:     "it should count the party size against the PlayerCapacity",
+			capacity: 4,
+			profile: &pb.MatchProfile{
+				Name: "pool_mode_world",
+			},
+			poolTickets: map[string][]*pb.Ticket{
+				"pool_1": {
+					partyTicket(3),
+					partyTicket(2),
+					partyTicket(1),
+					partyTicket(2),
+				},
+			},
+			wantMatches:      2,
+			wantTotalTickets: 4,
+			wantPlayers:      []int64{4, 4},
+		},
+		{
+			name:     "it should skip the parties larger than the PlayerCapacity",
+			capacity: 2,
+			profile: &pb.MatchProfile{
+				Name: "pool_mode_world",
+			},
+			poolTickets: map[string][]*pb.Ticket{
+				"pool_1": {
+					partyTicket(3),
+					partyTicket(2),
+				},
+			},
+			wantMatches:      1,
+			wantTotalTickets: 1,
+			wantPlayers:      []int64{2},
+		},
+		{
+			name:     "it should add a ticket present in more than one pool to a single match",
+			capacity: 4,
+			profile: &pb.MatchProfile{
+				Name: "pool_mode_world",
+			},
+			poolTickets: map[string][]*pb.Ticket{
+				"pool_1": {sharedTicket},
+				"pool_2": {sharedTicket},
+			},
+			wantMatches:      1,
+			wantTotalTickets: 1,
+			wantPlayers:      []int64{2},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -229,7 +281,32 @@ func TestMatchByGamePlayersCapacity(t *testing.T) {
 				require.NoError(t, err)
 				require.Len(t, matches, tc.wantMatches, "Number of Matches is wrong")
 				require.Len(t, tickets, tc.wantTotalTickets, "Number of Tickets is wrong")
+				if tc.wantPlayers != nil {
+					var players []int64
+					for _, match := range matches {
+						players = append(players, extensions.CountPlayers(match.Tickets...))
+					}
+					require.Equal(t, tc.wantPlayers, players)
+				}
 			}
 		})
 	}
 }
+
+func partyTicket(size float64) *pb.Ticket {
+	return &pb.Ticket{
+		Id:           uuid.New().String(),
+		SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{extensions.PartySizeArg: size}},
+	}
+}
+
+func TestNewMatchID(t *testing.T) {
+	profile := &pb.MatchProfile{Name: "world_based_profile_Dune"}
+
+	ids := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id := newMatchID(profile)
+		require.False(t, ids[id], "match id %s is duplicated", id)
+		ids[id] = true
+	}
+}
@@ -0,0 +1,67 @@
+package functions
+
+import (
+	"github.com/pkg/errors"
+	"open-match.dev/open-match/pkg/pb"
+	"sort"
+	"sync"
+)
+
+// Factory builds a match function for the player capacity of the GameServers
+type Factory func(playerCapacity int) MakeBackfillMatchesFunc
+
+var (
+	registryMux sync.RWMutex
+	registry    = map[string]Factory{
+		"player_capacity": func(playerCapacity int) MakeBackfillMatchesFunc {
+			return IgnoreBackfills(MatchByGamePlayersCapacity(playerCapacity))
+		},
+		"backfill": MatchWithBackfill,
+	}
+)
+
+// Register adds the match function to the registry, replacing the one registered with the same name
+func Register(name string, factory Factory) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+
+	registry[name] = factory
+}
+
+// Get builds the match function registered with the name
+func Get(name string, playerCapacity int) (MakeBackfillMatchesFunc, error) {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	factory, ok := registry[name]
+	if !ok {
+		return nil, errors.Errorf("match function %q is not registered, it should be one of %v", name, namesLocked())
+	}
+
+	return factory(playerCapacity), nil
+}
+
+// Names returns the names of the registered match functions sorted
+func Names() []string {
+	registryMux.RLock()
+	defer registryMux.RUnlock()
+
+	return namesLocked()
+}
+
+func namesLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// IgnoreBackfills adapts a match function that only uses the tickets of the pools
+func IgnoreBackfills(makeMatches MakeMatchesFunc) MakeBackfillMatchesFunc {
+	return func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error) {
+		return makeMatches(profile, poolTickets)
+	}
+}
@@ -0,0 +1,36 @@
+package functions
+
+import (
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("it should build the registered match functions", func(t *testing.T) {
+		require.Subset(t, Names(), []string{"backfill", "player_capacity"})
+
+		makeMatches, err := Get("player_capacity", 2)
+		require.NoError(t, err)
+
+		matches, err := makeMatches(&pb.MatchProfile{Name: "profile"}, map[string][]*pb.Ticket{"pool": {{Id: "t1"}, {Id: "t2"}, {Id: "t3"}}}, nil)
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+	})
+
+	t.Run("it should return error for an unknown match function", func(t *testing.T) {
+		_, err := Get("random", 2)
+		require.Error(t, err)
+	})
+
+	t.Run("it should register a match function", func(t *testing.T) {
+		Register("test_none", func(playerCapacity int) MakeBackfillMatchesFunc {
+			return IgnoreBackfills(func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket) ([]*pb.Match, error) {
+				return nil, nil
+			})
+		})
+
+		_, err := Get("test_none", 1)
+		require.NoError(t, err)
+	})
+}
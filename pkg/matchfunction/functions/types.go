@@ -11,3 +11,6 @@ var (
 )
 
 type MakeMatchesFunc func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket) ([]*pb.Match, error)
+
+// MakeBackfillMatchesFunc makes matches from the tickets and the backfills found by the pools of the profile
+type MakeBackfillMatchesFunc func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error)
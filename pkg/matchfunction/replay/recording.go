@@ -0,0 +1,240 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"open-match.dev/open-match/pkg/pb"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Recording is the input of a match function Run: the profile and what its pools found
+type Recording struct {
+	RecordedAt time.Time
+	// Function and PlayerCapacity are the match function that made the matches of the Run, empty if unknown
+	Function       string
+	PlayerCapacity int
+	Profile        *pb.MatchProfile
+	PoolTickets    map[string][]*pb.Ticket
+	PoolBackfills  map[string][]*pb.Backfill
+}
+
+// recordingJSON holds the messages encoded with protojson, so the files use the Open Match field names
+type recordingJSON struct {
+	RecordedAt     time.Time                    `json:"recorded_at"`
+	Function       string                       `json:"function,omitempty"`
+	PlayerCapacity int                          `json:"player_capacity,omitempty"`
+	Profile        json.RawMessage              `json:"profile"`
+	PoolTickets    map[string][]json.RawMessage `json:"pool_tickets"`
+	PoolBackfills  map[string][]json.RawMessage `json:"pool_backfills,omitempty"`
+}
+
+func (r *Recording) MarshalJSON() ([]byte, error) {
+	profile, err := protojson.Marshal(r.Profile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode profile")
+	}
+
+	out := recordingJSON{
+		RecordedAt:     r.RecordedAt,
+		Function:       r.Function,
+		PlayerCapacity: r.PlayerCapacity,
+		Profile:        profile,
+		PoolTickets:    map[string][]json.RawMessage{},
+	}
+
+	for pool, tickets := range r.PoolTickets {
+		out.PoolTickets[pool] = []json.RawMessage{}
+		for _, t := range tickets {
+			ticket, err := protojson.Marshal(t)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to encode ticket %s", t.GetId())
+			}
+			out.PoolTickets[pool] = append(out.PoolTickets[pool], ticket)
+		}
+	}
+
+	if len(r.PoolBackfills) > 0 {
+		out.PoolBackfills = map[string][]json.RawMessage{}
+		for pool, backfills := range r.PoolBackfills {
+			out.PoolBackfills[pool] = []json.RawMessage{}
+			for _, b := range backfills {
+				backfill, err := protojson.Marshal(b)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to encode backfill %s", b.GetId())
+				}
+				out.PoolBackfills[pool] = append(out.PoolBackfills[pool], backfill)
+			}
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+func (r *Recording) UnmarshalJSON(data []byte) error {
+	var in recordingJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	if len(in.Profile) == 0 {
+		return errors.New("recording does not have a profile")
+	}
+
+	r.RecordedAt = in.RecordedAt
+	r.Function, r.PlayerCapacity = in.Function, in.PlayerCapacity
+	r.Profile = &pb.MatchProfile{}
+	if err := protojson.Unmarshal(in.Profile, r.Profile); err != nil {
+		return errors.Wrap(err, "failed to decode profile")
+	}
+
+	r.PoolTickets = map[string][]*pb.Ticket{}
+	for pool, tickets := range in.PoolTickets {
+		r.PoolTickets[pool] = []*pb.Ticket{}
+		for i, raw := range tickets {
+			ticket := &pb.Ticket{}
+			if err := protojson.Unmarshal(raw, ticket); err != nil {
+				return errors.Wrapf(err, "failed to decode ticket %d of pool %s", i, pool)
+			}
+			r.PoolTickets[pool] = append(r.PoolTickets[pool], ticket)
+		}
+	}
+
+	r.PoolBackfills = map[string][]*pb.Backfill{}
+	for pool, backfills := range in.PoolBackfills {
+		for i, raw := range backfills {
+			backfill := &pb.Backfill{}
+			if err := protojson.Unmarshal(raw, backfill); err != nil {
+				return errors.Wrapf(err, "failed to decode backfill %d of pool %s", i, pool)
+			}
+			r.PoolBackfills[pool] = append(r.PoolBackfills[pool], backfill)
+		}
+	}
+
+	return nil
+}
+
+// DirRecorder writes every Run to its own file on Dir, named after the profile and the time of the Run
+type DirRecorder struct {
+	Dir string
+	// Function and PlayerCapacity are written to the recordings so the replay runs the same match function
+	Function       string
+	PlayerCapacity int
+	seq            atomic.Int64
+}
+
+func NewDirRecorder(dir string) (*DirRecorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create recording dir %s", dir)
+	}
+
+	return &DirRecorder{Dir: dir}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Record implements the service.Recorder
+func (d *DirRecorder) Record(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) error {
+	recording := &Recording{
+		RecordedAt:     time.Now().UTC(),
+		Function:       d.Function,
+		PlayerCapacity: d.PlayerCapacity,
+		Profile:        profile,
+		PoolTickets:    poolTickets,
+		PoolBackfills:  poolBackfills,
+	}
+
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The sequence keeps the names unique for the Runs of the same profile recorded at the same time
+	name := fmt.Sprintf("%s-%d-%d.json", unsafeFileChars.ReplaceAllString(profile.GetName(), "_"), recording.RecordedAt.UnixNano(), d.seq.Add(1))
+	return os.WriteFile(filepath.Join(d.Dir, name), data, 0o644)
+}
+
+// Load reads the recordings of the paths. A path can be a file with a recording or a list of recordings,
+// or a dir whose .json files are read in name order.
+func Load(paths ...string) ([]*Recording, error) {
+	var recordings []*Recording
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read recording %s", path)
+		}
+
+		files := []string{path}
+		if info.IsDir() {
+			files, err = filepath.Glob(filepath.Join(path, "*.json"))
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(files)
+		}
+
+		for _, file := range files {
+			loaded, err := loadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			recordings = append(recordings, loaded...)
+		}
+	}
+
+	if len(recordings) == 0 {
+		return nil, errors.New("no recordings found")
+	}
+
+	return recordings, nil
+}
+
+// RecordedFunction returns the match function and player capacity the recordings were made with. They are empty if
+// none of the recordings has them. It returns an error if the recordings were made with different ones.
+func RecordedFunction(recordings []*Recording) (string, int, error) {
+	var function string
+	var playerCapacity int
+	for _, r := range recordings {
+		if len(r.Function) == 0 {
+			continue
+		}
+
+		if len(function) > 0 && (r.Function != function || r.PlayerCapacity != playerCapacity) {
+			return "", 0, errors.Errorf("the recordings were made with match functions %s/%d and %s/%d, replay them apart", function, playerCapacity, r.Function, r.PlayerCapacity)
+		}
+		function, playerCapacity = r.Function, r.PlayerCapacity
+	}
+
+	return function, playerCapacity, nil
+}
+
+func loadFile(file string) ([]*Recording, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read recording %s", file)
+	}
+
+	var recordings []*Recording
+	if strings.HasPrefix(string(bytes.TrimSpace(data)), "[") {
+		err = json.Unmarshal(data, &recordings)
+	} else {
+		recording := &Recording{}
+		err = json.Unmarshal(data, recording)
+		recordings = append(recordings, recording)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode recording %s", file)
+	}
+
+	return recordings, nil
+}
@@ -0,0 +1,103 @@
+package replay
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"open-match.dev/open-match/pkg/pb"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirRecorder_Record(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recordings")
+	recorder, err := NewDirRecorder(dir)
+	require.NoError(t, err)
+	recorder.Function, recorder.PlayerCapacity = "backfill", 4
+
+	profile := &pb.MatchProfile{Name: "world_based_profile_Dune/us-east-1", Pools: []*pb.Pool{{Name: "pool_mode_Dune"}}}
+	poolTickets := map[string][]*pb.Ticket{
+		"pool_mode_Dune": {
+			{Id: "t1", SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{"skill": 10}, Tags: []string{"mode.session"}}},
+			{Id: "t2"},
+		},
+		"empty": {},
+	}
+	poolBackfills := map[string][]*pb.Backfill{"pool_mode_Dune": {{Id: "b1", Generation: 2}}}
+
+	require.NoError(t, recorder.Record(profile, poolTickets, poolBackfills))
+	require.NoError(t, recorder.Record(profile, poolTickets, nil))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	recordings, err := Load(dir)
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+
+	got := recordings[0]
+	require.True(t, proto.Equal(profile, got.Profile))
+	require.Len(t, got.PoolTickets["pool_mode_Dune"], 2)
+	require.Empty(t, got.PoolTickets["empty"])
+	require.True(t, proto.Equal(poolTickets["pool_mode_Dune"][0], got.PoolTickets["pool_mode_Dune"][0]))
+	require.Equal(t, int64(2), got.PoolBackfills["pool_mode_Dune"][0].GetGeneration())
+	require.False(t, got.RecordedAt.IsZero())
+
+	function, playerCapacity, err := RecordedFunction(recordings)
+	require.NoError(t, err)
+	require.Equal(t, "backfill", function)
+	require.Equal(t, 4, playerCapacity)
+}
+
+func TestRecordedFunction(t *testing.T) {
+	t.Run("it should be empty for recordings without function", func(t *testing.T) {
+		function, playerCapacity, err := RecordedFunction([]*Recording{{}, {}})
+		require.NoError(t, err)
+		require.Empty(t, function)
+		require.Zero(t, playerCapacity)
+	})
+
+	t.Run("it should return error for recordings of different functions", func(t *testing.T) {
+		_, _, err := RecordedFunction([]*Recording{
+			{Function: "player_capacity", PlayerCapacity: 10},
+			{},
+			{Function: "player_capacity", PlayerCapacity: 4},
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("it should load a file with a list of recordings", func(t *testing.T) {
+		data, err := json.Marshal([]*Recording{
+			{Profile: &pb.MatchProfile{Name: "p1"}, PoolTickets: map[string][]*pb.Ticket{"pool": {{Id: "t1"}}}},
+			{Profile: &pb.MatchProfile{Name: "p2"}},
+		})
+		require.NoError(t, err)
+
+		file := filepath.Join(t.TempDir(), "profiles.json")
+		require.NoError(t, os.WriteFile(file, data, 0o644))
+
+		recordings, err := Load(file)
+		require.NoError(t, err)
+		require.Len(t, recordings, 2)
+		require.Equal(t, "p1", recordings[0].Profile.GetName())
+		require.Equal(t, "t1", recordings[0].PoolTickets["pool"][0].GetId())
+		require.Equal(t, "p2", recordings[1].Profile.GetName())
+	})
+
+	t.Run("it should return error for a recording without profile", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "recording.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"pool_tickets": {}}`), 0o644))
+
+		_, err := Load(file)
+		require.Error(t, err)
+	})
+
+	t.Run("it should return error if there are no recordings", func(t *testing.T) {
+		_, err := Load(t.TempDir())
+		require.Error(t, err)
+	})
+}
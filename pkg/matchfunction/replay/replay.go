@@ -0,0 +1,261 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/functions"
+	"google.golang.org/protobuf/encoding/protojson"
+	"io"
+	"open-match.dev/open-match/pkg/pb"
+	"text/tabwriter"
+	"time"
+)
+
+// Result is the outcome of replaying a recording
+type Result struct {
+	Profile  string
+	Matches  []*pb.Match
+	Stats    Stats
+	Duration time.Duration
+	Err      error
+}
+
+// Stats describes the proposals made for a recording
+type Stats struct {
+	Tickets          int   `json:"tickets"`
+	Players          int64 `json:"players"`
+	Backfills        int   `json:"backfills"`
+	Matches          int   `json:"matches"`
+	MatchedTickets   int   `json:"matched_tickets"`
+	MatchedPlayers   int64 `json:"matched_players"`
+	UnmatchedTickets int   `json:"unmatched_tickets"`
+	// DuplicateTickets are proposed by more than one match, Open Match only accepts the first of them
+	DuplicateTickets int `json:"duplicate_tickets"`
+	// BackfillMatches fill existing backfills, NewBackfills are created for matches with room left
+	BackfillMatches int     `json:"backfill_matches"`
+	NewBackfills    int     `json:"new_backfills"`
+	MinPlayers      int64   `json:"min_players"`
+	MaxPlayers      int64   `json:"max_players"`
+	AvgPlayers      float64 `json:"avg_players"`
+	// FillRate is the share of the capacity of the matches taken by their players
+	FillRate float64 `json:"fill_rate"`
+}
+
+// Run replays the recordings against the match function, one after the other
+func Run(recordings []*Recording, makeMatches functions.MakeBackfillMatchesFunc, playerCapacity int) []*Result {
+	var results []*Result
+
+	for _, recording := range recordings {
+		start := time.Now()
+		matches, err := makeMatches(recording.Profile, recording.PoolTickets, recording.PoolBackfills)
+		results = append(results, &Result{
+			Profile:  recording.Profile.GetName(),
+			Matches:  matches,
+			Stats:    NewStats(recording, matches, playerCapacity),
+			Duration: time.Since(start),
+			Err:      err,
+		})
+	}
+
+	return results
+}
+
+func NewStats(recording *Recording, matches []*pb.Match, playerCapacity int) Stats {
+	stats := Stats{Matches: len(matches)}
+
+	tickets := map[string]*pb.Ticket{}
+	for _, pool := range recording.PoolTickets {
+		for _, t := range pool {
+			tickets[t.GetId()] = t
+		}
+	}
+
+	backfills := map[string]bool{}
+	for _, pool := range recording.PoolBackfills {
+		for _, b := range pool {
+			backfills[b.GetId()] = true
+		}
+	}
+
+	stats.Tickets = len(tickets)
+	stats.Backfills = len(backfills)
+	for _, t := range tickets {
+		stats.Players += extensions.GetPartySize(t)
+	}
+
+	matched := map[string]bool{}
+	for i, match := range matches {
+		players := extensions.CountPlayers(match.GetTickets()...)
+		if i == 0 || players < stats.MinPlayers {
+			stats.MinPlayers = players
+		}
+		if players > stats.MaxPlayers {
+			stats.MaxPlayers = players
+		}
+
+		switch {
+		case match.GetBackfill() == nil:
+		case len(match.GetBackfill().GetId()) > 0:
+			stats.BackfillMatches++
+		default:
+			stats.NewBackfills++
+		}
+
+		for _, t := range match.GetTickets() {
+			if matched[t.GetId()] {
+				stats.DuplicateTickets++
+				continue
+			}
+			matched[t.GetId()] = true
+			stats.MatchedTickets++
+			stats.MatchedPlayers += extensions.GetPartySize(t)
+		}
+	}
+
+	stats.UnmatchedTickets = stats.Tickets - len(matched)
+	if stats.UnmatchedTickets < 0 {
+		// Tickets that are not part of the pools, only a faulty match function proposes them
+		stats.UnmatchedTickets = 0
+	}
+
+	if stats.Matches > 0 {
+		stats.AvgPlayers = float64(stats.MatchedPlayers) / float64(stats.Matches)
+	}
+
+	if stats.Matches > 0 && playerCapacity > 0 {
+		stats.FillRate = float64(stats.MatchedPlayers) / float64(stats.Matches*playerCapacity)
+	}
+
+	return stats
+}
+
+// Total adds up the stats of the results. Min and max players are the ones of all the matches.
+func Total(results []*Result) Stats {
+	var total Stats
+	var capacity float64
+
+	first := true
+	for _, r := range results {
+		s := r.Stats
+		total.Tickets += s.Tickets
+		total.Players += s.Players
+		total.Backfills += s.Backfills
+		total.Matches += s.Matches
+		total.MatchedTickets += s.MatchedTickets
+		total.MatchedPlayers += s.MatchedPlayers
+		total.UnmatchedTickets += s.UnmatchedTickets
+		total.DuplicateTickets += s.DuplicateTickets
+		total.BackfillMatches += s.BackfillMatches
+		total.NewBackfills += s.NewBackfills
+
+		if s.FillRate > 0 {
+			capacity += float64(s.MatchedPlayers) / s.FillRate
+		}
+
+		if s.Matches == 0 {
+			continue
+		}
+
+		if first || s.MinPlayers < total.MinPlayers {
+			total.MinPlayers = s.MinPlayers
+		}
+		if s.MaxPlayers > total.MaxPlayers {
+			total.MaxPlayers = s.MaxPlayers
+		}
+		first = false
+	}
+
+	if total.Matches > 0 {
+		total.AvgPlayers = float64(total.MatchedPlayers) / float64(total.Matches)
+	}
+
+	if capacity > 0 {
+		total.FillRate = float64(total.MatchedPlayers) / capacity
+	}
+
+	return total
+}
+
+// WriteTable prints the proposals of every result followed by the stats of every profile
+func WriteTable(out io.Writer, results []*Result) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "PROFILE\tMATCH\tTICKETS\tPLAYERS\tBACKFILL")
+	for _, r := range results {
+		for _, match := range r.Matches {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", r.Profile, match.GetMatchId(), len(match.GetTickets()), extensions.CountPlayers(match.GetTickets()...), backfillColumn(match))
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "PROFILE\tTICKETS\tMATCHES\tMATCHED\tUNMATCHED\tDUPLICATED\tPLAYERS MIN/AVG/MAX\tFILL RATE\tBACKFILLS\tDURATION\tERROR")
+	for _, r := range results {
+		writeStats(w, r.Profile, r.Stats, r.Duration.String(), errColumn(r.Err))
+	}
+	writeStats(w, "TOTAL", Total(results), "", "")
+
+	return w.Flush()
+}
+
+func writeStats(w io.Writer, profile string, s Stats, duration, err string) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d/%.1f/%d\t%.1f%%\t%d/%d/%d\t%s\t%s\n",
+		profile, s.Tickets, s.Matches, s.MatchedTickets, s.UnmatchedTickets, s.DuplicateTickets,
+		s.MinPlayers, s.AvgPlayers, s.MaxPlayers, s.FillRate*100, s.Backfills, s.BackfillMatches, s.NewBackfills, duration, err)
+}
+
+func backfillColumn(match *pb.Match) string {
+	switch {
+	case match.GetBackfill() == nil:
+		return "-"
+	case len(match.GetBackfill().GetId()) > 0:
+		return match.GetBackfill().GetId()
+	default:
+		return "new"
+	}
+}
+
+func errColumn(err error) string {
+	if err == nil {
+		return "-"
+	}
+
+	return err.Error()
+}
+
+type resultJSON struct {
+	Profile  string            `json:"profile"`
+	Matches  []json.RawMessage `json:"matches"`
+	Stats    Stats             `json:"stats"`
+	Duration string            `json:"duration"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// WriteJSON prints the results with the matches encoded as Open Match JSON
+func WriteJSON(out io.Writer, results []*Result) error {
+	report := struct {
+		Results []resultJSON `json:"results"`
+		Total   Stats        `json:"total"`
+	}{Results: []resultJSON{}, Total: Total(results)}
+
+	for _, r := range results {
+		result := resultJSON{Profile: r.Profile, Matches: []json.RawMessage{}, Stats: r.Stats, Duration: r.Duration.String()}
+		if r.Err != nil {
+			result.Error = r.Err.Error()
+		}
+
+		for _, match := range r.Matches {
+			data, err := protojson.Marshal(match)
+			if err != nil {
+				return err
+			}
+			result.Matches = append(result.Matches, data)
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/functions"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	recording := &Recording{
+		Profile: &pb.MatchProfile{Name: "profile"},
+		PoolTickets: map[string][]*pb.Ticket{
+			"a": {{Id: "t1"}, {Id: "t2"}, {Id: "t3"}},
+			"b": {{Id: "t3"}, {Id: "t4", SearchFields: &pb.SearchFields{DoubleArgs: map[string]float64{extensions.PartySizeArg: 3}}}},
+		},
+	}
+
+	t.Run("it should make the matches of the registered match function", func(t *testing.T) {
+		makeMatches, err := functions.Get("player_capacity", 4)
+		require.NoError(t, err)
+
+		results := Run([]*Recording{recording}, makeMatches, 4)
+		require.Len(t, results, 1)
+		require.NoError(t, results[0].Err)
+
+		stats := results[0].Stats
+		require.Equal(t, 4, stats.Tickets)
+		require.Equal(t, int64(6), stats.Players)
+		require.Equal(t, 2, stats.Matches)
+		require.Equal(t, 4, stats.MatchedTickets)
+		require.Equal(t, int64(6), stats.MatchedPlayers)
+		require.Equal(t, 0, stats.UnmatchedTickets)
+		require.Equal(t, 0, stats.DuplicateTickets)
+		require.Equal(t, int64(3), stats.MinPlayers)
+		require.Equal(t, int64(3), stats.MaxPlayers)
+		require.Equal(t, 0.75, stats.FillRate)
+	})
+
+	t.Run("it should count the tickets proposed twice and the unmatched tickets", func(t *testing.T) {
+		duplicate := func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) ([]*pb.Match, error) {
+			return []*pb.Match{
+				{MatchId: "m1", Tickets: []*pb.Ticket{{Id: "t1"}, {Id: "t2"}}},
+				{MatchId: "m2", Tickets: []*pb.Ticket{{Id: "t2"}}, Backfill: &pb.Backfill{}},
+			}, nil
+		}
+
+		stats := Run([]*Recording{recording}, duplicate, 4)[0].Stats
+		require.Equal(t, 2, stats.MatchedTickets)
+		require.Equal(t, 1, stats.DuplicateTickets)
+		require.Equal(t, 2, stats.UnmatchedTickets)
+		require.Equal(t, 1, stats.NewBackfills)
+	})
+}
+
+func TestWrite(t *testing.T) {
+	makeMatches, err := functions.Get("player_capacity", 2)
+	require.NoError(t, err)
+
+	recordings := []*Recording{
+		{Profile: &pb.MatchProfile{Name: "p1"}, PoolTickets: map[string][]*pb.Ticket{"pool": {{Id: "t1"}, {Id: "t2"}}}},
+		{Profile: &pb.MatchProfile{Name: "p2"}, PoolTickets: map[string][]*pb.Ticket{"pool": {{Id: "t3"}}}},
+	}
+	results := Run(recordings, makeMatches, 2)
+
+	t.Run("it should print the proposals and the stats", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, WriteTable(&out, results))
+		require.Contains(t, out.String(), "profile-p1-")
+		require.Contains(t, out.String(), "TOTAL")
+	})
+
+	t.Run("it should print the totals as JSON", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, WriteJSON(&out, results))
+
+		var report struct {
+			Results []struct {
+				Matches []json.RawMessage `json:"matches"`
+			} `json:"results"`
+			Total Stats `json:"total"`
+		}
+		require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		require.Len(t, report.Results, 2)
+		require.Equal(t, 2, report.Total.Matches)
+		require.Equal(t, 3, report.Total.MatchedTickets)
+		require.Equal(t, 0.75, report.Total.FillRate)
+		require.Equal(t, int64(1), report.Total.MinPlayers)
+		require.Equal(t, int64(2), report.Total.MaxPlayers)
+	})
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
 	"github.com/Octops/agones-discover-openmatch/pkg/config"
 	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/functions"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/replay"
 	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/service"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"net"
 	"open-match.dev/open-match/pkg/pb"
 	"time"
@@ -21,16 +25,42 @@ type MatchFunction interface {
 
 type Server struct {
 	logger             *logrus.Entry
+	config             config.OpenMatchConnConfig
+	serverConfig       config.MatchFunctionConfig
 	conn               *grpc.ClientConn
 	grpcServer         *grpc.Server
+	health             *health.Server
 	queryServiceClient pb.QueryServiceClient
+	// Dialer replaces the network dialer of the QueryService connection, i.e. to reach an in-process Open Match
+	Dialer func(ctx context.Context, addr string) (net.Conn, error)
 }
 
-func NewServer() (*Server, error) {
+// NewServer creates the match function server. It serves the gRPC health service and, if enabled, reflection.
+func NewServer(omConfig config.OpenMatchConnConfig, serverConfig config.MatchFunctionConfig) (*Server, error) {
 	logger := runtime.Logger().WithField("source", "server")
+
+	opts, err := omConfig.MatchFunctionServerOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	grpcServer := grpc.NewServer(append(opts, serverConfig.ServerOptions()...)...)
+
+	// The server reports NOT_SERVING until it is listening
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	if serverConfig.Reflection {
+		reflection.Register(grpcServer)
+	}
+
 	return &Server{
-		logger:     logger,
-		grpcServer: grpc.NewServer(),
+		logger:       logger,
+		config:       omConfig,
+		serverConfig: serverConfig,
+		grpcServer:   grpcServer,
+		health:       healthServer,
 	}, nil
 }
 
@@ -39,7 +69,17 @@ func (s *Server) DialQueryService(addr string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure(), grpc.WithBlock())
+	creds, err := s.config.DialOption()
+	if err != nil {
+		return err
+	}
+
+	opts := []grpc.DialOption{creds, grpc.WithBlock()}
+	if s.Dialer != nil {
+		opts = append(opts, grpc.WithContextDialer(s.Dialer))
+	}
+
+	conn, err := grpc.DialContext(ctx, addr, opts...)
 	if err != nil {
 		return errors.Wrapf(err, "error dialing QueryService on %s", addr)
 	}
@@ -56,42 +96,108 @@ func (s *Server) RegisterMatchFunction(factory func(client pb.QueryServiceClient
 }
 
 func (s *Server) Serve(ctx context.Context, port int32) error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return errors.Wrapf(err, "TCP net listener initialization failed for port %d", port)
+	}
+
+	s.logger.Infof("TCP net listener initialized for port %d", port)
+	return s.ServeListener(ctx, ln)
+}
+
+// ServeListener serves the match function on the listener until the context is cancelled. The listener is closed on return.
+func (s *Server) ServeListener(ctx context.Context, ln net.Listener) error {
 	defer s.Finalizer()
 
-	if err := s.DialQueryService(config.OpenMatch().QueryService); err != nil {
+	if err := s.DialQueryService(s.config.QueryService); err != nil {
+		ln.Close()
 		return errors.Wrap(err, "failed to dial OpenMatch Query Service")
 	}
 
-	// TODO: PlayerCapacity 10 is a random number but must match with the GS Status.Players.Capacity
-	// The MMF should have a Register function that should be passed to the Server
-	s.RegisterMatchFunction(service.NewMatchFunctionService, functions.MatchByGamePlayersCapacity(10))
+	// The PlayerCapacity must match the capacity of the GameServers
+	name, capacity := s.serverConfig.FunctionName(), s.serverConfig.PlayerCapacity
+	makeMatches, err := functions.Get(name, capacity)
+	if err != nil {
+		ln.Close()
+		return err
+	}
 
-	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	recorder, err := s.newRecorder(name, capacity)
 	if err != nil {
-		return errors.Wrapf(err, "TCP net listener initialization failed for port %d", port)
+		ln.Close()
+		return err
 	}
 
-	defer ln.Close()
+	// Only the backfill mode queries the backfills of the pools
+	if s.serverConfig.Backfill {
+		pb.RegisterMatchFunctionServer(s.grpcServer, service.NewBackfillMatchFunctionService(s.queryServiceClient, makeMatches, recorder))
+	} else {
+		s.RegisterMatchFunction(func(client pb.QueryServiceClient, makeMatchesFunc functions.MakeMatchesFunc) pb.MatchFunctionServer {
+			return service.NewRecordingMatchFunctionService(client, makeMatchesFunc, recorder)
+		}, func(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket) ([]*pb.Match, error) {
+			return makeMatches(profile, poolTickets, nil)
+		})
+	}
 
-	ctxServer, cancel := context.WithCancel(ctx)
-	defer cancel()
+	s.logger.Infof("serving match function %s with player capacity %d", name, capacity)
+	return s.serve(ctx, ln)
+}
 
-	s.logger.Infof("TCP net listener initialized for port %d", port)
+// newRecorder returns nil if the recording mode is off. The recordings carry the match function, so they are replayed
+// with the same one.
+func (s *Server) newRecorder(function string, playerCapacity int) (service.Recorder, error) {
+	if len(s.serverConfig.RecordDir) == 0 {
+		return nil, nil
+	}
+
+	recorder, err := replay.NewDirRecorder(s.serverConfig.RecordDir)
+	if err != nil {
+		return nil, err
+	}
+	recorder.Function, recorder.PlayerCapacity = function, playerCapacity
+
+	s.logger.Warnf("recording the pools of every run to %s, tickets are written to disk", s.serverConfig.RecordDir)
+	return recorder, nil
+}
+
+// serve blocks until the context is cancelled or the gRPC server fails. The listener is closed by the gRPC server.
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	errServe := make(chan error, 1)
 	go func() {
-		if err := s.grpcServer.Serve(ln); err != nil {
-			s.logger.Fatal(errors.Wrap(err, "gRPC serve failed"))
-			cancel()
-		}
+		errServe <- s.grpcServer.Serve(ln)
 	}()
 
-	<-ctxServer.Done()
-	return nil
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	select {
+	case err := <-errServe:
+		return errors.Wrap(err, "gRPC serve failed")
+	case <-ctx.Done():
+		return nil
+	}
 }
 
+// Finalizer stops accepting new streams and waits up to the GracefulStopTimeout for the Run streams in progress.
+// The streams still running after that are cut off. The QueryService connection is closed last since the streams use it.
 func (s *Server) Finalizer() {
 	s.logger.Info("stopping match function server")
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(s.serverConfig.GracefulStopTimeout):
+		s.logger.Warnf("match function streams still running after %s, stopping server", s.serverConfig.GracefulStopTimeout)
+		s.grpcServer.Stop()
+		<-stopped
+	}
+
 	if s.conn != nil {
 		s.conn.Close()
 	}
-	s.grpcServer.Stop()
 }
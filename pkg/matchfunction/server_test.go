@@ -0,0 +1,126 @@
+package matchfunction
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+	"time"
+)
+
+func TestServer_serve(t *testing.T) {
+	t.Run("it should finish the streams in progress before stopping", func(t *testing.T) {
+		mmf := &blockingMatchFunction{started: make(chan struct{}), release: make(chan struct{})}
+		conn, stop := startServer(t, time.Second, mmf)
+
+		health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, health.GetStatus())
+
+		stream, err := pb.NewMatchFunctionClient(conn).Run(context.Background(), &pb.RunRequest{Profile: &pb.MatchProfile{Name: "profile"}})
+		require.NoError(t, err)
+		<-mmf.started
+
+		stopped := stop()
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			close(mmf.release)
+		}()
+
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, "m1", resp.GetProposal().GetMatchId())
+
+		<-stopped
+	})
+
+	t.Run("it should cut off the streams after the graceful stop timeout", func(t *testing.T) {
+		mmf := &blockingMatchFunction{started: make(chan struct{}), release: make(chan struct{})}
+		defer close(mmf.release)
+		conn, stop := startServer(t, 20*time.Millisecond, mmf)
+
+		stream, err := pb.NewMatchFunctionClient(conn).Run(context.Background(), &pb.RunRequest{Profile: &pb.MatchProfile{Name: "profile"}})
+		require.NoError(t, err)
+		<-mmf.started
+
+		select {
+		case <-stop():
+		case <-time.After(time.Second):
+			require.Fail(t, "server did not stop after the graceful stop timeout")
+		}
+
+		_, err = stream.Recv()
+		require.Error(t, err)
+	})
+
+	t.Run("it should return the serve error", func(t *testing.T) {
+		server, err := NewServer(config.OpenMatchConnConfig{}, config.Default().MatchFunction)
+		require.NoError(t, err)
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		ln.Close()
+
+		err = server.serve(context.Background(), ln)
+		require.Error(t, err)
+	})
+}
+
+// startServer serves the match function on a local port. The returned func cancels the server and runs the Finalizer.
+func startServer(t *testing.T, gracefulStopTimeout time.Duration, mmf pb.MatchFunctionServer) (*grpc.ClientConn, func() <-chan struct{}) {
+	serverConfig := config.Default().MatchFunction
+	serverConfig.GracefulStopTimeout = gracefulStopTimeout
+
+	server, err := NewServer(config.OpenMatchConnConfig{}, serverConfig)
+	require.NoError(t, err)
+	pb.RegisterMatchFunctionServer(server.grpcServer, mmf)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errServe := make(chan error, 1)
+	go func() {
+		errServe <- server.serve(ctx, ln)
+	}()
+
+	conn, err := grpc.Dial(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	stop := func() <-chan struct{} {
+		cancel()
+		require.NoError(t, <-errServe)
+
+		stopped := make(chan struct{})
+		go func() {
+			server.Finalizer()
+			close(stopped)
+		}()
+		return stopped
+	}
+
+	return conn, stop
+}
+
+type blockingMatchFunction struct {
+	pb.UnimplementedMatchFunctionServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingMatchFunction) Run(req *pb.RunRequest, stream pb.MatchFunction_RunServer) error {
+	close(f.started)
+	select {
+	case <-f.release:
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+
+	return stream.Send(&pb.RunResponse{Proposal: &pb.Match{MatchId: "m1"}})
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction/functions"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"open-match.dev/open-match/pkg/matchfunction"
+	"open-match.dev/open-match/pkg/pb"
+)
+
+// BackfillMatchFunctionService queries the backfills of the pools besides the tickets
+type BackfillMatchFunctionService struct {
+	logger             *logrus.Entry
+	queryServiceClient pb.QueryServiceClient
+	makeMatchesFunc    functions.MakeBackfillMatchesFunc
+	recorder           Recorder
+}
+
+// NewBackfillMatchFunctionService records the pool tickets and backfills of every Run. A nil recorder records nothing.
+func NewBackfillMatchFunctionService(queryServiceClient pb.QueryServiceClient, makeMatchesFunc functions.MakeBackfillMatchesFunc, recorder Recorder) pb.MatchFunctionServer {
+	return &BackfillMatchFunctionService{
+		logger:             runtime.Logger().WithField("source", "match_function"),
+		queryServiceClient: queryServiceClient,
+		makeMatchesFunc:    makeMatchesFunc,
+		recorder:           recorder,
+	}
+}
+
+func (s *BackfillMatchFunctionService) Run(req *pb.RunRequest, stream pb.MatchFunction_RunServer) error {
+	poolTickets, err := matchfunction.QueryPools(stream.Context(), s.queryServiceClient, req.GetProfile().GetPools())
+	if err != nil {
+		err = errors.Wrap(err, "failed to query pools")
+		s.logger.Error(err)
+		return err
+	}
+
+	poolBackfills, err := matchfunction.QueryBackfillPools(stream.Context(), s.queryServiceClient, req.GetProfile().GetPools())
+	if err != nil {
+		err = errors.Wrap(err, "failed to query backfill pools")
+		s.logger.Error(err)
+		return err
+	}
+
+	record(s.logger, s.recorder, req.GetProfile(), poolTickets, poolBackfills)
+
+	proposals, err := s.makeMatchesFunc(req.GetProfile(), poolTickets, poolBackfills)
+	if err != nil {
+		err = errors.Wrap(err, "failed to make matches")
+		s.logger.Error(err)
+		return err
+	}
+
+	for _, proposal := range proposals {
+		if err := stream.Send(&pb.RunResponse{Proposal: proposal}); err != nil {
+			err := errors.Wrap(err, "failed to stream proposals to Open Match")
+			s.logger.Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -9,17 +9,29 @@ import (
 	"open-match.dev/open-match/pkg/pb"
 )
 
+// Recorder keeps the input of every Run so it can be replayed offline
+type Recorder interface {
+	Record(profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) error
+}
+
 type MatchFunctionService struct {
 	logger             *logrus.Entry
 	queryServiceClient pb.QueryServiceClient
 	makeMatchesFunc    functions.MakeMatchesFunc
+	recorder           Recorder
 }
 
 func NewMatchFunctionService(queryServiceClient pb.QueryServiceClient, makeMatchesFunc functions.MakeMatchesFunc) pb.MatchFunctionServer {
+	return NewRecordingMatchFunctionService(queryServiceClient, makeMatchesFunc, nil)
+}
+
+// NewRecordingMatchFunctionService records the pool tickets of every Run before making the matches. A nil recorder records nothing.
+func NewRecordingMatchFunctionService(queryServiceClient pb.QueryServiceClient, makeMatchesFunc functions.MakeMatchesFunc, recorder Recorder) pb.MatchFunctionServer {
 	return &MatchFunctionService{
 		logger:             runtime.Logger().WithField("source", "match_function"),
 		queryServiceClient: queryServiceClient,
 		makeMatchesFunc:    makeMatchesFunc,
+		recorder:           recorder,
 	}
 }
 
@@ -31,6 +43,8 @@ func (s *MatchFunctionService) Run(req *pb.RunRequest, stream pb.MatchFunction_R
 		return err
 	}
 
+	record(s.logger, s.recorder, req.GetProfile(), poolTickets, nil)
+
 	proposals, err := s.makeMatchesFunc(req.GetProfile(), poolTickets)
 	if err != nil {
 		err = errors.Wrap(err, "failed to make matches")
@@ -48,3 +62,14 @@ func (s *MatchFunctionService) Run(req *pb.RunRequest, stream pb.MatchFunction_R
 
 	return nil
 }
+
+// record doesn't fail the Run, the matches are made even if the recording is lost
+func record(logger *logrus.Entry, recorder Recorder, profile *pb.MatchProfile, poolTickets map[string][]*pb.Ticket, poolBackfills map[string][]*pb.Backfill) {
+	if recorder == nil {
+		return
+	}
+
+	if err := recorder.Record(profile, poolTickets, poolBackfills); err != nil {
+		logger.Warn(errors.Wrapf(err, "failed to record pools of profile %s", profile.GetName()).Error())
+	}
+}
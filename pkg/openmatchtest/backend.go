@@ -0,0 +1,181 @@
+package openmatchtest
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"open-match.dev/open-match/pkg/pb"
+	"time"
+)
+
+// FetchMatches runs the match function of the request and sends the proposals accepted by the evaluator
+func (om *OpenMatch) FetchMatches(req *pb.FetchMatchesRequest, stream pb.BackendService_FetchMatchesServer) error {
+	if req.GetConfig() == nil || req.GetProfile() == nil {
+		return status.Error(codes.InvalidArgument, ".config and .profile are required")
+	}
+
+	proposals, err := om.runMatchFunction(stream.Context(), req.GetConfig(), req.GetProfile())
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	for _, match := range om.evaluate(proposals) {
+		if err := stream.Send(&pb.FetchMatchesResponse{Match: match}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (om *OpenMatch) runMatchFunction(ctx context.Context, config *pb.FunctionConfig, profile *pb.MatchProfile) ([]*pb.Match, error) {
+	if config.GetType() != pb.FunctionConfig_GRPC {
+		return nil, errors.Errorf("function config type %s is not supported", config.GetType())
+	}
+
+	conn, err := om.dial(matchFunctionAddr(config))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stream, err := pb.NewMatchFunctionClient(conn).Run(ctx, &pb.RunRequest{Profile: profile})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run the match function")
+	}
+
+	var proposals []*pb.Match
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return proposals, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to receive the proposals of the match function")
+		}
+
+		proposals = append(proposals, resp.GetProposal())
+	}
+}
+
+// evaluate accepts the proposals in order. A proposal is rejected if one of its tickets is not in the pool anymore or
+// was taken by an accepted proposal, or if its backfill is gone or was updated since the match function read it.
+// The tickets of the accepted proposals are pending until they are assigned or released.
+func (om *OpenMatch) evaluate(proposals []*pb.Match) []*pb.Match {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	now := time.Now()
+	var accepted []*pb.Match
+
+	for _, proposal := range proposals {
+		if !om.acceptableLocked(proposal, now) {
+			continue
+		}
+
+		match := proto.Clone(proposal).(*pb.Match)
+		for _, t := range match.GetTickets() {
+			om.tickets[t.GetId()].pendingUntil = now.Add(om.PendingReleaseTimeout)
+		}
+
+		if match.GetBackfill() != nil {
+			match.Backfill = om.fillBackfillLocked(match)
+		}
+
+		om.matches = append(om.matches, match)
+		accepted = append(accepted, match)
+	}
+
+	return accepted
+}
+
+func (om *OpenMatch) acceptableLocked(proposal *pb.Match, now time.Time) bool {
+	for _, t := range proposal.GetTickets() {
+		state, ok := om.tickets[t.GetId()]
+		if !ok || !state.available(now) {
+			return false
+		}
+	}
+
+	if backfill := proposal.GetBackfill(); backfill != nil && len(backfill.GetId()) > 0 {
+		state, ok := om.backfills[backfill.GetId()]
+		if !ok || state.backfill.GetGeneration() != backfill.GetGeneration() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fillBackfillLocked creates or updates the backfill of the match and makes its tickets wait for the backfill
+// to be acknowledged, the caller holds the mux
+func (om *OpenMatch) fillBackfillLocked(match *pb.Match) *pb.Backfill {
+	var state *backfillState
+	if len(match.GetBackfill().GetId()) == 0 {
+		state = om.backfills[om.createBackfillLocked(match.GetBackfill()).GetId()]
+	} else {
+		state = om.backfills[match.GetBackfill().GetId()]
+		backfill := proto.Clone(match.GetBackfill()).(*pb.Backfill)
+		backfill.CreateTime = state.backfill.GetCreateTime()
+		backfill.Generation = state.backfill.GetGeneration() + 1
+		state.backfill = backfill
+	}
+
+	for _, t := range match.GetTickets() {
+		om.tickets[t.GetId()].backfillID = state.backfill.GetId()
+		state.tickets = append(state.tickets, t.GetId())
+	}
+
+	return proto.Clone(state.backfill).(*pb.Backfill)
+}
+
+func (om *OpenMatch) AssignTickets(ctx context.Context, req *pb.AssignTicketsRequest) (*pb.AssignTicketsResponse, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	resp := &pb.AssignTicketsResponse{}
+	for _, group := range req.GetAssignments() {
+		if group.GetAssignment() == nil {
+			return nil, status.Error(codes.InvalidArgument, "AssignmentGroup.Assignment is required")
+		}
+
+		for _, id := range group.GetTicketIds() {
+			state, ok := om.tickets[id]
+			if !ok {
+				resp.Failures = append(resp.Failures, &pb.AssignmentFailure{TicketId: id, Cause: pb.AssignmentFailure_TICKET_NOT_FOUND})
+				continue
+			}
+
+			om.assignLocked(state, group.GetAssignment(), "")
+		}
+	}
+
+	return resp, nil
+}
+
+func (om *OpenMatch) ReleaseTickets(ctx context.Context, req *pb.ReleaseTicketsRequest) (*pb.ReleaseTicketsResponse, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	for _, id := range req.GetTicketIds() {
+		if state, ok := om.tickets[id]; ok {
+			state.pendingUntil = time.Time{}
+		}
+	}
+
+	return &pb.ReleaseTicketsResponse{}, nil
+}
+
+func (om *OpenMatch) ReleaseAllTickets(ctx context.Context, req *pb.ReleaseAllTicketsRequest) (*pb.ReleaseAllTicketsResponse, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	for _, state := range om.tickets {
+		state.pendingUntil = time.Time{}
+	}
+
+	return &pb.ReleaseAllTicketsResponse{}, nil
+}
@@ -0,0 +1,93 @@
+package openmatchtest
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/allocator"
+	"github.com/Octops/agones-discover-openmatch/pkg/config"
+	"github.com/Octops/agones-discover-openmatch/pkg/director"
+	"github.com/Octops/agones-discover-openmatch/pkg/director/openmatch"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions/extpb"
+	"github.com/Octops/agones-discover-openmatch/pkg/matchfunction"
+	"github.com/stretchr/testify/require"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+	"time"
+)
+
+func TestDirectorAndMatchFunction(t *testing.T) {
+	om, conn := startOpenMatch(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mmf, err := matchfunction.NewServer(config.OpenMatchConnConfig{QueryService: Addr}, config.MatchFunctionConfig{GracefulStopTimeout: time.Second, PlayerCapacity: 10})
+	require.NoError(t, err)
+	mmf.Dialer = om.DialContext
+
+	mmfDone := make(chan error, 1)
+	go func() {
+		mmfDone <- mmf.ServeListener(ctx, om.Listen(testMatchFunctionAddr))
+	}()
+
+	// The profiles pick one skill and one latency range at random, a combination of tickets fits each of them
+	const perCombination = 3
+	for _, skill := range []float64{5, 50, 500} {
+		for _, latency := range []float64{10, 30, 60, 90} {
+			for i := 0; i < perCombination; i++ {
+				_, err := om.CreateTickets(&pb.Ticket{SearchFields: &pb.SearchFields{
+					DoubleArgs: map[string]float64{"skill": skill, "latency": latency},
+					StringArgs: map[string]string{"world": "Dune", "region": "us-east-1"},
+					Tags:       []string{"mode.session"},
+				}})
+				require.NoError(t, err)
+			}
+		}
+	}
+
+	options := openmatch.DirectorOptions{
+		Profile:       openmatch.ProfileOptions{ConnectionTemplate: "{address}:{port}"},
+		MatchFunction: openmatch.MatchFunctionServer{HostName: "mmf", Port: 50502},
+	}
+	scheduler := &director.Scheduler{Interval: 50 * time.Millisecond}
+
+	directorDone := make(chan error, 1)
+	go func() {
+		directorDone <- openmatch.RunDirector(ctx, runtime.Logger(), om.Dial, scheduler, options, allocator.NewAllocatorService(&testAllocator{}))
+	}()
+
+	ctxWait, cancelWait := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelWait()
+
+	assignments, err := om.WaitForAssignments(ctxWait, perCombination)
+	require.NoError(t, err)
+
+	cancel()
+	require.NoError(t, <-directorDone)
+	require.NoError(t, <-mmfDone)
+
+	require.Len(t, assignments, perCombination)
+	for _, a := range assignments {
+		require.Equal(t, "10.0.0.1:7000", a.Assignment.GetConnection())
+	}
+
+	require.Len(t, om.Matches(), 1, "the tickets of a combination fit in a single match")
+	require.Len(t, queryIDs(t, conn), 12*perCombination-perCombination, "the director releases the pending tickets when it stops")
+}
+
+// testAllocator allocates the same GameServer for every match
+type testAllocator struct{}
+
+func (a *testAllocator) Allocate(ctx context.Context, req *pb.AssignTicketsRequest) error {
+	for _, group := range req.GetAssignments() {
+		err := allocator.SetGameServerAssignment(group, &extpb.GameServerAssignment{
+			Name:    "gs-1",
+			Address: "10.0.0.1",
+			Ports:   []*extpb.GameServerPort{{Name: "default", Port: 7000}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package openmatchtest
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+	"time"
+)
+
+func (om *OpenMatch) CreateTicket(ctx context.Context, req *pb.CreateTicketRequest) (*pb.Ticket, error) {
+	if req.GetTicket() == nil {
+		return nil, status.Error(codes.InvalidArgument, ".ticket is required")
+	}
+
+	if len(req.GetTicket().GetId()) > 0 || req.GetTicket().GetAssignment() != nil || req.GetTicket().GetCreateTime() != nil {
+		return nil, status.Error(codes.InvalidArgument, "tickets cannot be created with an id, assignment or create time")
+	}
+
+	ticket := proto.Clone(req.GetTicket()).(*pb.Ticket)
+	ticket.Id = uuid.New().String()
+	ticket.CreateTime = timestamppb.Now()
+
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	om.tickets[ticket.Id] = &ticketState{ticket: ticket}
+	return proto.Clone(ticket).(*pb.Ticket), nil
+}
+
+func (om *OpenMatch) GetTicket(ctx context.Context, req *pb.GetTicketRequest) (*pb.Ticket, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	state, ok := om.tickets[req.GetTicketId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "ticket %s not found", req.GetTicketId())
+	}
+
+	return proto.Clone(state.ticket).(*pb.Ticket), nil
+}
+
+func (om *OpenMatch) DeleteTicket(ctx context.Context, req *pb.DeleteTicketRequest) (*emptypb.Empty, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	delete(om.tickets, req.GetTicketId())
+	return &emptypb.Empty{}, nil
+}
+
+// WatchAssignments sends the assignment of the ticket every time it changes, until the client goes away
+func (om *OpenMatch) WatchAssignments(req *pb.WatchAssignmentsRequest, stream pb.FrontendService_WatchAssignmentsServer) error {
+	var sent *pb.Assignment
+
+	for {
+		om.mux.Lock()
+		state, ok := om.tickets[req.GetTicketId()]
+		changed := om.changed
+		var assignment *pb.Assignment
+		if ok {
+			assignment = state.ticket.GetAssignment()
+		}
+		om.mux.Unlock()
+
+		if !ok {
+			return status.Errorf(codes.NotFound, "ticket %s not found", req.GetTicketId())
+		}
+
+		if assignment != nil && !proto.Equal(assignment, sent) {
+			if err := stream.Send(&pb.WatchAssignmentsResponse{Assignment: assignment}); err != nil {
+				return err
+			}
+			sent = assignment
+		}
+
+		select {
+		case <-changed:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (om *OpenMatch) CreateBackfill(ctx context.Context, req *pb.CreateBackfillRequest) (*pb.Backfill, error) {
+	if req.GetBackfill() == nil {
+		return nil, status.Error(codes.InvalidArgument, ".backfill is required")
+	}
+
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	return proto.Clone(om.createBackfillLocked(req.GetBackfill())).(*pb.Backfill), nil
+}
+
+func (om *OpenMatch) GetBackfill(ctx context.Context, req *pb.GetBackfillRequest) (*pb.Backfill, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	state, ok := om.backfills[req.GetBackfillId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "backfill %s not found", req.GetBackfillId())
+	}
+
+	return proto.Clone(state.backfill).(*pb.Backfill), nil
+}
+
+// UpdateBackfill replaces the backfill and bumps its generation. The tickets waiting for the backfill go back to the pool.
+func (om *OpenMatch) UpdateBackfill(ctx context.Context, req *pb.UpdateBackfillRequest) (*pb.Backfill, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	state, ok := om.backfills[req.GetBackfill().GetId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "backfill %s not found", req.GetBackfill().GetId())
+	}
+
+	backfill := proto.Clone(req.GetBackfill()).(*pb.Backfill)
+	backfill.CreateTime = state.backfill.GetCreateTime()
+	backfill.Generation = state.backfill.GetGeneration() + 1
+
+	om.releaseBackfillTicketsLocked(state)
+	state.backfill = backfill
+
+	return proto.Clone(backfill).(*pb.Backfill), nil
+}
+
+// DeleteBackfill removes the backfill and returns its tickets to the pool
+func (om *OpenMatch) DeleteBackfill(ctx context.Context, req *pb.DeleteBackfillRequest) (*emptypb.Empty, error) {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	if state, ok := om.backfills[req.GetBackfillId()]; ok {
+		om.releaseBackfillTicketsLocked(state)
+		delete(om.backfills, req.GetBackfillId())
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// AcknowledgeBackfill assigns the tickets waiting for the backfill to the connection of the GameServer
+func (om *OpenMatch) AcknowledgeBackfill(ctx context.Context, req *pb.AcknowledgeBackfillRequest) (*pb.AcknowledgeBackfillResponse, error) {
+	if req.GetAssignment() == nil {
+		return nil, status.Error(codes.InvalidArgument, ".assignment is required")
+	}
+
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	state, ok := om.backfills[req.GetBackfillId()]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "backfill %s not found", req.GetBackfillId())
+	}
+
+	resp := &pb.AcknowledgeBackfillResponse{Backfill: proto.Clone(state.backfill).(*pb.Backfill)}
+	for _, id := range state.tickets {
+		ticket, ok := om.tickets[id]
+		if !ok || ticket.backfillID != state.backfill.GetId() {
+			continue
+		}
+
+		om.assignLocked(ticket, req.GetAssignment(), state.backfill.GetId())
+		resp.Tickets = append(resp.Tickets, proto.Clone(ticket.ticket).(*pb.Ticket))
+	}
+	state.tickets = nil
+
+	return resp, nil
+}
+
+// createBackfillLocked stores a copy of the backfill with a new id and the first generation, the caller holds the mux
+func (om *OpenMatch) createBackfillLocked(backfill *pb.Backfill) *pb.Backfill {
+	created := proto.Clone(backfill).(*pb.Backfill)
+	created.Id = uuid.New().String()
+	created.CreateTime = timestamppb.Now()
+	created.Generation = 1
+
+	om.backfills[created.Id] = &backfillState{backfill: created}
+	return created
+}
+
+// releaseBackfillTicketsLocked returns the tickets waiting for the backfill to the pool, the caller holds the mux
+func (om *OpenMatch) releaseBackfillTicketsLocked(state *backfillState) {
+	for _, id := range state.tickets {
+		if ticket, ok := om.tickets[id]; ok && ticket.backfillID == state.backfill.GetId() {
+			ticket.backfillID = ""
+		}
+	}
+	state.tickets = nil
+}
+
+// assignLocked sets the assignment of the ticket and wakes up the watchers, the caller holds the mux
+func (om *OpenMatch) assignLocked(state *ticketState, assignment *pb.Assignment, backfillID string) {
+	state.ticket.Assignment = proto.Clone(assignment).(*pb.Assignment)
+	state.backfillID = ""
+	state.pendingUntil = time.Time{}
+
+	om.assigned = append(om.assigned, Assignment{
+		TicketID:   state.ticket.GetId(),
+		Assignment: proto.Clone(assignment).(*pb.Assignment),
+		BackfillID: backfillID,
+	})
+	om.notifyLocked()
+}
@@ -0,0 +1,220 @@
+// Package openmatchtest runs an in-memory Open Match for tests. The Frontend, Backend and Query services are served on
+// bufconn listeners, FetchMatches calls the match function server and the assignments are recorded, so the director and
+// the mmf can run end to end in go test without a cluster.
+package openmatchtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"time"
+)
+
+const (
+	// Addr is the address of the Frontend, Backend and Query services, they share a single server
+	Addr = "openmatch:50500"
+
+	bufSize = 1024 * 1024
+
+	defaultPendingReleaseTimeout = time.Minute
+)
+
+// OpenMatch keeps the tickets, backfills and assignments in memory. Use NewOpenMatch and Start it before dialing.
+type OpenMatch struct {
+	// PendingReleaseTimeout is the time the tickets of a match are not returned by the Query service unless they are released
+	PendingReleaseTimeout time.Duration
+
+	pb.UnimplementedFrontendServiceServer
+	pb.UnimplementedBackendServiceServer
+	pb.UnimplementedQueryServiceServer
+
+	mux       sync.Mutex
+	tickets   map[string]*ticketState
+	backfills map[string]*backfillState
+	matches   []*pb.Match
+	assigned  []Assignment
+	// changed is closed and replaced every time an assignment is set, watchers wait on it
+	changed chan struct{}
+
+	networkMux sync.Mutex
+	listeners  map[string]*bufconn.Listener
+	server     *grpc.Server
+}
+
+// Assignment records the assignment of a ticket by AssignTickets or AcknowledgeBackfill
+type Assignment struct {
+	TicketID   string
+	Assignment *pb.Assignment
+	// BackfillID is set if the ticket was assigned by acknowledging the backfill
+	BackfillID string
+}
+
+type ticketState struct {
+	ticket *pb.Ticket
+	// pendingUntil is set while the ticket is part of a match not assigned yet
+	pendingUntil time.Time
+	// backfillID is set while the ticket waits for the backfill to be acknowledged
+	backfillID string
+}
+
+type backfillState struct {
+	backfill *pb.Backfill
+	tickets  []string
+}
+
+func NewOpenMatch() *OpenMatch {
+	return &OpenMatch{
+		PendingReleaseTimeout: defaultPendingReleaseTimeout,
+		tickets:               map[string]*ticketState{},
+		backfills:             map[string]*backfillState{},
+		changed:               make(chan struct{}),
+		listeners:             map[string]*bufconn.Listener{},
+	}
+}
+
+// Start serves the Frontend, Backend and Query services on Addr
+func (om *OpenMatch) Start() {
+	om.server = grpc.NewServer()
+	pb.RegisterFrontendServiceServer(om.server, om)
+	pb.RegisterBackendServiceServer(om.server, om)
+	pb.RegisterQueryServiceServer(om.server, om)
+
+	ln := om.Listen(Addr)
+	go om.server.Serve(ln)
+}
+
+// Stop cuts off the streams and closes the listeners, the match function listeners included
+func (om *OpenMatch) Stop() {
+	if om.server != nil {
+		om.server.Stop()
+	}
+
+	om.networkMux.Lock()
+	defer om.networkMux.Unlock()
+
+	for _, ln := range om.listeners {
+		ln.Close()
+	}
+}
+
+// Listen returns an in-memory listener for the address. Other servers, like the match function, listen on it so
+// Open Match reaches them with the host and port set on the FetchMatches request.
+func (om *OpenMatch) Listen(addr string) net.Listener {
+	om.networkMux.Lock()
+	defer om.networkMux.Unlock()
+
+	ln := bufconn.Listen(bufSize)
+	om.listeners[addr] = ln
+	return ln
+}
+
+// DialContext connects to the listener of the address. Addresses without a listener use the network.
+func (om *OpenMatch) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	om.networkMux.Lock()
+	ln, ok := om.listeners[addr]
+	om.networkMux.Unlock()
+
+	if !ok {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	return ln.DialContext(ctx)
+}
+
+// Dial connects to the Open Match services. It can be used as the director ConnFunc.
+func (om *OpenMatch) Dial() (*grpc.ClientConn, error) {
+	return om.dial(Addr)
+}
+
+func (om *OpenMatch) dial(addr string) (*grpc.ClientConn, error) {
+	conn, err := grpc.Dial(addr, grpc.WithContextDialer(om.DialContext), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to dial %s", addr)
+	}
+
+	return conn, nil
+}
+
+// CreateTickets adds the tickets to the pool the way the Frontend does and returns their ids
+func (om *OpenMatch) CreateTickets(tickets ...*pb.Ticket) ([]string, error) {
+	var ids []string
+	for _, t := range tickets {
+		created, err := om.CreateTicket(context.Background(), &pb.CreateTicketRequest{Ticket: t})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, created.GetId())
+	}
+
+	return ids, nil
+}
+
+// Assignments returns the assignments recorded so far, in the order they were made
+func (om *OpenMatch) Assignments() []Assignment {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	return append([]Assignment(nil), om.assigned...)
+}
+
+// Matches returns the matches accepted by FetchMatches so far
+func (om *OpenMatch) Matches() []*pb.Match {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	return append([]*pb.Match(nil), om.matches...)
+}
+
+// Backfills returns the backfills kept by Open Match
+func (om *OpenMatch) Backfills() []*pb.Backfill {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	var backfills []*pb.Backfill
+	for _, b := range om.backfills {
+		backfills = append(backfills, b.backfill)
+	}
+
+	return backfills
+}
+
+// WaitForAssignments blocks until at least n tickets are assigned or the context is done
+func (om *OpenMatch) WaitForAssignments(ctx context.Context, n int) ([]Assignment, error) {
+	for {
+		om.mux.Lock()
+		assigned, changed := len(om.assigned), om.changed
+		om.mux.Unlock()
+
+		if assigned >= n {
+			return om.Assignments(), nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return om.Assignments(), errors.Wrapf(ctx.Err(), "%d of %d tickets assigned", assigned, n)
+		}
+	}
+}
+
+// notifyLocked wakes up the watchers, the caller holds the mux
+func (om *OpenMatch) notifyLocked() {
+	close(om.changed)
+	om.changed = make(chan struct{})
+}
+
+// available returns true if the ticket can be part of a new match
+func (s *ticketState) available(now time.Time) bool {
+	return s.ticket.GetAssignment() == nil && len(s.backfillID) == 0 && !now.Before(s.pendingUntil)
+}
+
+func matchFunctionAddr(config *pb.FunctionConfig) string {
+	return fmt.Sprintf("%s:%d", config.GetHost(), config.GetPort())
+}
@@ -0,0 +1,267 @@
+package openmatchtest
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+	"time"
+)
+
+const testMatchFunctionAddr = "mmf:50502"
+
+func TestInPool(t *testing.T) {
+	created := time.Now()
+	fields := &pb.SearchFields{
+		DoubleArgs: map[string]float64{"skill": 10},
+		StringArgs: map[string]string{"world": "Dune"},
+		Tags:       []string{"mode.session"},
+	}
+
+	testCases := []struct {
+		name string
+		pool *pb.Pool
+		want bool
+	}{
+		{
+			name: "it should match the pool without filters",
+			pool: &pb.Pool{},
+			want: true,
+		},
+		{
+			name: "it should match every filter of the pool",
+			pool: &pb.Pool{
+				DoubleRangeFilters:  []*pb.DoubleRangeFilter{{DoubleArg: "skill", Min: 0, Max: 10}},
+				StringEqualsFilters: []*pb.StringEqualsFilter{{StringArg: "world", Value: "Dune"}},
+				TagPresentFilters:   []*pb.TagPresentFilter{{Tag: "mode.session"}},
+				CreatedBefore:       timestamppb.New(created.Add(time.Second)),
+				CreatedAfter:        timestamppb.New(created.Add(-time.Second)),
+			},
+			want: true,
+		},
+		{
+			name: "it should not match the excluded max of the range",
+			pool: &pb.Pool{DoubleRangeFilters: []*pb.DoubleRangeFilter{{DoubleArg: "skill", Min: 0, Max: 10, Exclude: pb.DoubleRangeFilter_MAX}}},
+		},
+		{
+			name: "it should not match a missing double arg",
+			pool: &pb.Pool{DoubleRangeFilters: []*pb.DoubleRangeFilter{{DoubleArg: "latency", Min: 0, Max: 100}}},
+		},
+		{
+			name: "it should not match a different string arg",
+			pool: &pb.Pool{StringEqualsFilters: []*pb.StringEqualsFilter{{StringArg: "world", Value: "Nova"}}},
+		},
+		{
+			name: "it should not match a missing tag",
+			pool: &pb.Pool{TagPresentFilters: []*pb.TagPresentFilter{{Tag: "mode.battleroyale"}}},
+		},
+		{
+			name: "it should not match tickets created after the created before",
+			pool: &pb.Pool{CreatedBefore: timestamppb.New(created.Add(-time.Second))},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, inPool(tc.pool, fields, timestamppb.New(created)))
+		})
+	}
+}
+
+func TestOpenMatch_FetchMatches(t *testing.T) {
+	t.Run("it should accept a ticket once and keep the matched tickets out of the pool until released", func(t *testing.T) {
+		om, conn := startOpenMatch(t)
+		ids, err := om.CreateTickets(&pb.Ticket{}, &pb.Ticket{}, &pb.Ticket{})
+		require.NoError(t, err)
+
+		// The second proposal reuses a ticket of the first one, the evaluator rejects it
+		serveMatchFunction(t, om, func(pool []*pb.Ticket) []*pb.Match {
+			return []*pb.Match{
+				{MatchId: "m1", Tickets: pool[:2]},
+				{MatchId: "m2", Tickets: pool[1:]},
+			}
+		})
+
+		matches := fetch(t, conn)
+		require.Len(t, matches, 1)
+		require.Equal(t, "m1", matches[0].GetMatchId())
+		require.Equal(t, []string{ids[2]}, queryIDs(t, conn))
+
+		_, err = pb.NewBackendServiceClient(conn).ReleaseTickets(context.Background(), &pb.ReleaseTicketsRequest{TicketIds: ids[:1]})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{ids[0], ids[2]}, queryIDs(t, conn))
+	})
+
+	t.Run("it should record the assignments and report the tickets not found", func(t *testing.T) {
+		om, conn := startOpenMatch(t)
+		ids, err := om.CreateTickets(&pb.Ticket{})
+		require.NoError(t, err)
+
+		resp, err := pb.NewBackendServiceClient(conn).AssignTickets(context.Background(), &pb.AssignTicketsRequest{
+			Assignments: []*pb.AssignmentGroup{{TicketIds: []string{ids[0], "missing"}, Assignment: &pb.Assignment{Connection: "10.0.0.1:7000"}}},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.GetFailures(), 1)
+		require.Equal(t, pb.AssignmentFailure_TICKET_NOT_FOUND, resp.GetFailures()[0].GetCause())
+
+		require.Len(t, om.Assignments(), 1)
+		require.Equal(t, "10.0.0.1:7000", om.Assignments()[0].Assignment.GetConnection())
+		require.Empty(t, queryIDs(t, conn))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		watch, err := pb.NewFrontendServiceClient(conn).WatchAssignments(ctx, &pb.WatchAssignmentsRequest{TicketId: ids[0]})
+		require.NoError(t, err)
+		assignment, err := watch.Recv()
+		require.NoError(t, err)
+		require.Equal(t, "10.0.0.1:7000", assignment.GetAssignment().GetConnection())
+	})
+}
+
+func TestOpenMatch_Backfill(t *testing.T) {
+	t.Run("it should create the backfill of the match and assign its tickets once acknowledged", func(t *testing.T) {
+		om, conn := startOpenMatch(t)
+		ids, err := om.CreateTickets(&pb.Ticket{}, &pb.Ticket{})
+		require.NoError(t, err)
+
+		serveMatchFunction(t, om, func(pool []*pb.Ticket) []*pb.Match {
+			return []*pb.Match{{MatchId: "m1", Tickets: pool[:1], Backfill: &pb.Backfill{}}}
+		})
+
+		matches := fetch(t, conn)
+		require.Len(t, matches, 1)
+		backfill := matches[0].GetBackfill()
+		require.NotEmpty(t, backfill.GetId())
+		require.Equal(t, int64(1), backfill.GetGeneration())
+
+		// The ticket waits for the backfill even after the pending release
+		_, err = pb.NewBackendServiceClient(conn).ReleaseAllTickets(context.Background(), &pb.ReleaseAllTicketsRequest{})
+		require.NoError(t, err)
+		require.Equal(t, []string{ids[1]}, queryIDs(t, conn))
+
+		resp, err := pb.NewFrontendServiceClient(conn).AcknowledgeBackfill(context.Background(), &pb.AcknowledgeBackfillRequest{
+			BackfillId: backfill.GetId(),
+			Assignment: &pb.Assignment{Connection: "10.0.0.1:7000"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.GetTickets(), 1)
+		require.Equal(t, ids[0], resp.GetTickets()[0].GetId())
+
+		require.Len(t, om.Assignments(), 1)
+		require.Equal(t, backfill.GetId(), om.Assignments()[0].BackfillID)
+	})
+
+	t.Run("it should reject the proposals of a backfill updated since the match function ran", func(t *testing.T) {
+		om, conn := startOpenMatch(t)
+		_, err := om.CreateTickets(&pb.Ticket{})
+		require.NoError(t, err)
+
+		backfill, err := pb.NewFrontendServiceClient(conn).CreateBackfill(context.Background(), &pb.CreateBackfillRequest{Backfill: &pb.Backfill{}})
+		require.NoError(t, err)
+
+		_, err = pb.NewFrontendServiceClient(conn).UpdateBackfill(context.Background(), &pb.UpdateBackfillRequest{Backfill: backfill})
+		require.NoError(t, err)
+
+		serveMatchFunction(t, om, func(pool []*pb.Ticket) []*pb.Match {
+			return []*pb.Match{{MatchId: "m1", Tickets: pool, Backfill: backfill}}
+		})
+
+		require.Empty(t, fetch(t, conn))
+	})
+}
+
+func startOpenMatch(t *testing.T) (*OpenMatch, *grpc.ClientConn) {
+	om := NewOpenMatch()
+	om.Start()
+	t.Cleanup(om.Stop)
+
+	conn, err := om.Dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return om, conn
+}
+
+// testMatchFunction proposes the matches returned by propose for the tickets of the first pool of the profile
+type testMatchFunction struct {
+	pb.UnimplementedMatchFunctionServer
+	query   pb.QueryServiceClient
+	propose func(pool []*pb.Ticket) []*pb.Match
+}
+
+func (f *testMatchFunction) Run(req *pb.RunRequest, stream pb.MatchFunction_RunServer) error {
+	var pool []*pb.Ticket
+	if len(req.GetProfile().GetPools()) > 0 {
+		query, err := f.query.QueryTickets(stream.Context(), &pb.QueryTicketsRequest{Pool: req.GetProfile().GetPools()[0]})
+		if err != nil {
+			return err
+		}
+
+		for {
+			resp, err := query.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			pool = append(pool, resp.GetTickets()...)
+		}
+	}
+
+	for _, match := range f.propose(pool) {
+		if err := stream.Send(&pb.RunResponse{Proposal: match}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func serveMatchFunction(t *testing.T, om *OpenMatch, propose func(pool []*pb.Ticket) []*pb.Match) {
+	conn, err := om.Dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	server := grpc.NewServer()
+	pb.RegisterMatchFunctionServer(server, &testMatchFunction{query: pb.NewQueryServiceClient(conn), propose: propose})
+	go server.Serve(om.Listen(testMatchFunctionAddr))
+	t.Cleanup(server.Stop)
+}
+
+func fetch(t *testing.T, conn *grpc.ClientConn) []*pb.Match {
+	stream, err := pb.NewBackendServiceClient(conn).FetchMatches(context.Background(), &pb.FetchMatchesRequest{
+		Config:  &pb.FunctionConfig{Host: "mmf", Port: 50502, Type: pb.FunctionConfig_GRPC},
+		Profile: &pb.MatchProfile{Name: "profile", Pools: []*pb.Pool{{Name: "all"}}},
+	})
+	require.NoError(t, err)
+
+	var matches []*pb.Match
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return matches
+		}
+		require.NoError(t, err)
+		matches = append(matches, resp.GetMatch())
+	}
+}
+
+func queryIDs(t *testing.T, conn *grpc.ClientConn) []string {
+	stream, err := pb.NewQueryServiceClient(conn).QueryTicketIds(context.Background(), &pb.QueryTicketIdsRequest{Pool: &pb.Pool{Name: "all"}})
+	require.NoError(t, err)
+
+	var ids []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return ids
+		}
+		require.NoError(t, err)
+		ids = append(ids, resp.GetIds()...)
+	}
+}
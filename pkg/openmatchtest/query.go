@@ -0,0 +1,143 @@
+package openmatchtest
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+	"sort"
+	"time"
+)
+
+// QueryTickets returns the tickets of the pool that are not assigned, pending or waiting for a backfill
+func (om *OpenMatch) QueryTickets(req *pb.QueryTicketsRequest, stream pb.QueryService_QueryTicketsServer) error {
+	if req.GetPool() == nil {
+		return status.Error(codes.InvalidArgument, ".pool is required")
+	}
+
+	tickets := om.queryTickets(req.GetPool())
+	if len(tickets) == 0 {
+		return nil
+	}
+
+	return stream.Send(&pb.QueryTicketsResponse{Tickets: tickets})
+}
+
+func (om *OpenMatch) QueryTicketIds(req *pb.QueryTicketIdsRequest, stream pb.QueryService_QueryTicketIdsServer) error {
+	if req.GetPool() == nil {
+		return status.Error(codes.InvalidArgument, ".pool is required")
+	}
+
+	var ids []string
+	for _, t := range om.queryTickets(req.GetPool()) {
+		ids = append(ids, t.GetId())
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return stream.Send(&pb.QueryTicketIdsResponse{Ids: ids})
+}
+
+func (om *OpenMatch) QueryBackfills(req *pb.QueryBackfillsRequest, stream pb.QueryService_QueryBackfillsServer) error {
+	if req.GetPool() == nil {
+		return status.Error(codes.InvalidArgument, ".pool is required")
+	}
+
+	om.mux.Lock()
+	var backfills []*pb.Backfill
+	for _, state := range om.backfills {
+		if inPool(req.GetPool(), state.backfill.GetSearchFields(), state.backfill.GetCreateTime()) {
+			backfills = append(backfills, proto.Clone(state.backfill).(*pb.Backfill))
+		}
+	}
+	om.mux.Unlock()
+
+	if len(backfills) == 0 {
+		return nil
+	}
+
+	sort.Slice(backfills, func(i, j int) bool {
+		return backfills[i].GetCreateTime().AsTime().Before(backfills[j].GetCreateTime().AsTime())
+	})
+
+	return stream.Send(&pb.QueryBackfillsResponse{Backfills: backfills})
+}
+
+// queryTickets returns the tickets available for matchmaking in the order they were created
+func (om *OpenMatch) queryTickets(pool *pb.Pool) []*pb.Ticket {
+	om.mux.Lock()
+	defer om.mux.Unlock()
+
+	now := time.Now()
+	var tickets []*pb.Ticket
+	for _, state := range om.tickets {
+		if state.available(now) && inPool(pool, state.ticket.GetSearchFields(), state.ticket.GetCreateTime()) {
+			tickets = append(tickets, proto.Clone(state.ticket).(*pb.Ticket))
+		}
+	}
+
+	sort.Slice(tickets, func(i, j int) bool {
+		return tickets[i].GetCreateTime().AsTime().Before(tickets[j].GetCreateTime().AsTime())
+	})
+
+	return tickets
+}
+
+// inPool applies the filters of the pool the way Open Match does, every filter must match
+func inPool(pool *pb.Pool, fields *pb.SearchFields, createTime *timestamppb.Timestamp) bool {
+	for _, f := range pool.GetDoubleRangeFilters() {
+		value, ok := fields.GetDoubleArgs()[f.GetDoubleArg()]
+		if !ok || !inRange(f, value) {
+			return false
+		}
+	}
+
+	for _, f := range pool.GetStringEqualsFilters() {
+		value, ok := fields.GetStringArgs()[f.GetStringArg()]
+		if !ok || value != f.GetValue() {
+			return false
+		}
+	}
+
+	for _, f := range pool.GetTagPresentFilters() {
+		if !hasTag(fields.GetTags(), f.GetTag()) {
+			return false
+		}
+	}
+
+	if pool.GetCreatedBefore() != nil && !createTime.AsTime().Before(pool.GetCreatedBefore().AsTime()) {
+		return false
+	}
+
+	if pool.GetCreatedAfter() != nil && !createTime.AsTime().After(pool.GetCreatedAfter().AsTime()) {
+		return false
+	}
+
+	return true
+}
+
+func inRange(f *pb.DoubleRangeFilter, value float64) bool {
+	switch f.GetExclude() {
+	case pb.DoubleRangeFilter_MIN:
+		return value > f.GetMin() && value <= f.GetMax()
+	case pb.DoubleRangeFilter_MAX:
+		return value >= f.GetMin() && value < f.GetMax()
+	case pb.DoubleRangeFilter_BOTH:
+		return value > f.GetMin() && value < f.GetMax()
+	default:
+		return value >= f.GetMin() && value <= f.GetMax()
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
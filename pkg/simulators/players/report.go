@@ -0,0 +1,214 @@
+package players
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// ReportDimensions are the ticket string args the match rate is broken down by, the same the director builds profiles from
+var ReportDimensions = []string{"region", "world"}
+
+// Report is the outcome of a simulation. It is written as JSON to compare runs and as a table for humans.
+type Report struct {
+	Duration time.Duration `json:"duration"`
+	Players  int           `json:"players"`
+	Matched  int           `json:"matched"`
+	// Abandoned players deleted their ticket after their patience ran out
+	Abandoned int `json:"abandoned"`
+	// TimedOut players were still waiting when the simulation was interrupted
+	TimedOut        int     `json:"timed_out"`
+	MatchRate       float64 `json:"match_rate"`
+	AbandonmentRate float64 `json:"abandonment_rate"`
+	// Throughput is the matched players per second
+	Throughput float64 `json:"throughput"`
+	// WaitTime is the time to match of the matched players
+	WaitTime Percentiles `json:"wait_time"`
+	// Dimensions maps every dimension, i.e. region, to the match rate of its values
+	Dimensions  map[string][]DimensionReport `json:"dimensions"`
+	GameServers []GameServerReport           `json:"gameservers"`
+}
+
+type Percentiles struct {
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+type DimensionReport struct {
+	Value     string  `json:"value"`
+	Players   int     `json:"players"`
+	Matched   int     `json:"matched"`
+	MatchRate float64 `json:"match_rate"`
+}
+
+// GameServerReport counts the players assigned to a GameServer
+type GameServerReport struct {
+	Name    string `json:"name"`
+	Players int    `json:"players"`
+}
+
+// NewReport builds the report from the results of the players tracked during the duration of the simulation
+func NewReport(results []PlayerResult, duration time.Duration) *Report {
+	report := &Report{
+		Duration:    duration,
+		Players:     len(results),
+		Dimensions:  map[string][]DimensionReport{},
+		GameServers: []GameServerReport{},
+	}
+
+	var waits []time.Duration
+	gameServers := map[string]int{}
+	dimensions := map[string]map[string]*DimensionReport{}
+	for _, dimension := range ReportDimensions {
+		dimensions[dimension] = map[string]*DimensionReport{}
+	}
+
+	for _, r := range results {
+		switch r.Outcome {
+		case OutcomeMatched:
+			report.Matched++
+			waits = append(waits, r.Wait)
+			gameServers[r.GameServer]++
+		case OutcomeAbandoned:
+			report.Abandoned++
+		case OutcomeTimedOut:
+			report.TimedOut++
+		}
+
+		for _, dimension := range ReportDimensions {
+			value, ok := r.Dimensions[dimension]
+			if !ok {
+				continue
+			}
+
+			d, ok := dimensions[dimension][value]
+			if !ok {
+				d = &DimensionReport{Value: value}
+				dimensions[dimension][value] = d
+			}
+
+			d.Players++
+			if r.Outcome == OutcomeMatched {
+				d.Matched++
+			}
+		}
+	}
+
+	report.MatchRate = rate(report.Matched, report.Players)
+	report.AbandonmentRate = rate(report.Abandoned, report.Players)
+	if duration > 0 {
+		report.Throughput = float64(report.Matched) / duration.Seconds()
+	}
+	report.WaitTime = percentiles(waits)
+
+	for dimension, values := range dimensions {
+		list := []DimensionReport{}
+		for _, d := range values {
+			d.MatchRate = rate(d.Matched, d.Players)
+			list = append(list, *d)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Value < list[j].Value })
+		report.Dimensions[dimension] = list
+	}
+
+	for name, players := range gameServers {
+		report.GameServers = append(report.GameServers, GameServerReport{Name: name, Players: players})
+	}
+	sort.Slice(report.GameServers, func(i, j int) bool {
+		if report.GameServers[i].Players != report.GameServers[j].Players {
+			return report.GameServers[i].Players > report.GameServers[j].Players
+		}
+		return report.GameServers[i].Name < report.GameServers[j].Name
+	})
+
+	return report
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return errors.Wrap(encoder.Encode(r), "failed to encode report")
+}
+
+// WriteJSONFile writes the report to the file, replacing it if it exists
+func (r *Report) WriteJSONFile(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create report file %s", file)
+	}
+	defer f.Close()
+
+	if err := r.WriteJSON(f); err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "DURATION\tPLAYERS\tMATCHED\tABANDONED\tTIMED OUT\tMATCH RATE\tABANDONMENT RATE\tTHROUGHPUT\n")
+	fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t%s\t%.2f/s\n\n", r.Duration.Round(time.Millisecond), r.Players, r.Matched, r.Abandoned,
+		r.TimedOut, percent(r.MatchRate), percent(r.AbandonmentRate), r.Throughput)
+
+	fmt.Fprintf(tw, "WAIT TIME\tP50\tP90\tP95\tP99\tMAX\n")
+	fmt.Fprintf(tw, "\t%s\t%s\t%s\t%s\t%s\n\n", r.WaitTime.P50.Round(time.Millisecond), r.WaitTime.P90.Round(time.Millisecond),
+		r.WaitTime.P95.Round(time.Millisecond), r.WaitTime.P99.Round(time.Millisecond), r.WaitTime.Max.Round(time.Millisecond))
+
+	fmt.Fprintf(tw, "DIMENSION\tVALUE\tPLAYERS\tMATCHED\tMATCH RATE\n")
+	for _, dimension := range ReportDimensions {
+		for _, d := range r.Dimensions[dimension] {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", dimension, d.Value, d.Players, d.Matched, percent(d.MatchRate))
+		}
+	}
+
+	fmt.Fprintf(tw, "\nGAMESERVER\tPLAYERS\n")
+	for _, gs := range r.GameServers {
+		fmt.Fprintf(tw, "%s\t%d\n", gs.Name, gs.Players)
+	}
+
+	return tw.Flush()
+}
+
+// percentiles uses the nearest rank method
+func percentiles(waits []time.Duration) Percentiles {
+	if len(waits) == 0 {
+		return Percentiles{}
+	}
+
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	rank := func(p float64) time.Duration {
+		return waits[int(math.Ceil(p*float64(len(waits))))-1]
+	}
+
+	return Percentiles{
+		P50: rank(0.50),
+		P90: rank(0.90),
+		P95: rank(0.95),
+		P99: rank(0.99),
+		Max: waits[len(waits)-1],
+	}
+}
+
+func rate(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(count) / float64(total)
+}
+
+func percent(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
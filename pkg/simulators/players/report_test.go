@@ -0,0 +1,83 @@
+package players
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReport(t *testing.T) {
+	var results []PlayerResult
+	for i := 1; i <= 100; i++ {
+		results = append(results, PlayerResult{
+			Outcome:    OutcomeMatched,
+			Wait:       time.Duration(i) * time.Millisecond,
+			Dimensions: map[string]string{"region": "us-east-1", "world": "Dune"},
+			GameServer: []string{"gs-1", "gs-2"}[i%2],
+		})
+	}
+	for i := 0; i < 20; i++ {
+		results = append(results, PlayerResult{Outcome: OutcomeAbandoned, Wait: time.Minute, Dimensions: map[string]string{"region": "us-west-1", "world": "Dune"}})
+	}
+	for i := 0; i < 5; i++ {
+		results = append(results, PlayerResult{Outcome: OutcomeTimedOut, Dimensions: map[string]string{"region": "us-west-1", "world": "Nova"}})
+	}
+
+	report := NewReport(results, 10*time.Second)
+
+	require.Equal(t, 125, report.Players)
+	require.Equal(t, 100, report.Matched)
+	require.Equal(t, 20, report.Abandoned)
+	require.Equal(t, 5, report.TimedOut)
+	require.InDelta(t, 0.8, report.MatchRate, 0.0001)
+	require.InDelta(t, 0.16, report.AbandonmentRate, 0.0001)
+	require.InDelta(t, 10, report.Throughput, 0.0001)
+	require.Equal(t, Percentiles{P50: 50 * time.Millisecond, P90: 90 * time.Millisecond, P95: 95 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}, report.WaitTime)
+
+	require.Equal(t, []DimensionReport{
+		{Value: "us-east-1", Players: 100, Matched: 100, MatchRate: 1},
+		{Value: "us-west-1", Players: 25, Matched: 0, MatchRate: 0},
+	}, report.Dimensions["region"])
+	require.Equal(t, []DimensionReport{
+		{Value: "Dune", Players: 120, Matched: 100, MatchRate: 100.0 / 120},
+		{Value: "Nova", Players: 5, Matched: 0, MatchRate: 0},
+	}, report.Dimensions["world"])
+
+	require.Equal(t, []GameServerReport{{Name: "gs-1", Players: 50}, {Name: "gs-2", Players: 50}}, report.GameServers)
+}
+
+func TestReport_Write(t *testing.T) {
+	report := NewReport([]PlayerResult{
+		{Outcome: OutcomeMatched, Wait: time.Second, Dimensions: map[string]string{"region": "us-east-1"}, GameServer: "gs-1"},
+	}, time.Second)
+
+	file := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, report.WriteJSONFile(file))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+
+	decoded := &Report{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.Equal(t, report, decoded)
+
+	table := &bytes.Buffer{}
+	require.NoError(t, report.WriteTable(table))
+	require.True(t, strings.Contains(table.String(), "us-east-1"))
+	require.True(t, strings.Contains(table.String(), "gs-1"))
+	require.True(t, strings.Contains(table.String(), "100.0%"))
+}
+
+func TestNewReport_Empty(t *testing.T) {
+	report := NewReport(nil, 0)
+
+	require.Zero(t, report.MatchRate)
+	require.Zero(t, report.Throughput)
+	require.Equal(t, Percentiles{}, report.WaitTime)
+	require.NoError(t, report.WriteTable(&bytes.Buffer{}))
+}
@@ -0,0 +1,309 @@
+package players
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	CurveConstant = "constant"
+	CurveRamp     = "ramp"
+	CurveDiurnal  = "diurnal"
+	CurveSpike    = "spike"
+
+	DistributionUniform = "uniform"
+	DistributionNormal  = "normal"
+	DistributionChoice  = "choice"
+)
+
+// Scenario describes the traffic shape of a simulation: how many players arrive over time, their attributes and
+// the size of their parties. It is loaded from a YAML file.
+type Scenario struct {
+	Name string `yaml:"name"`
+	// Duration of the simulation, zero runs until the simulator is stopped
+	Duration time.Duration `yaml:"duration"`
+	// Tick is the interval between batches of players
+	Tick time.Duration `yaml:"tick"`
+	// Seed makes the runs reproducible, zero uses the current time
+	Seed       int64      `yaml:"seed"`
+	Arrival    Curve      `yaml:"arrival"`
+	Attributes Attributes `yaml:"attributes"`
+	// PartySizes weights the number of players of a party by size, i.e. {1: 6, 2: 3, 4: 1}
+	PartySizes map[int]float64 `yaml:"party_sizes"`
+}
+
+// Curve is the arrival rate of parties per second over the elapsed time of the simulation
+type Curve struct {
+	// Type is constant, ramp, diurnal or spike
+	Type string `yaml:"type"`
+	// Rate is used by constant and is the base rate of diurnal and spike
+	Rate float64 `yaml:"rate"`
+	// From and To are the rates at the start and at the end of a ramp of the length of Over
+	From float64       `yaml:"from"`
+	To   float64       `yaml:"to"`
+	Over time.Duration `yaml:"over"`
+	// Amplitude and Period shape the sine wave of diurnal around Rate
+	Amplitude float64       `yaml:"amplitude"`
+	Period    time.Duration `yaml:"period"`
+	// Peak is the rate of a spike starting At and lasting Width
+	Peak  float64       `yaml:"peak"`
+	At    time.Duration `yaml:"at"`
+	Width time.Duration `yaml:"width"`
+}
+
+// Attributes sets the distributions the player match requests are drawn from
+type Attributes struct {
+	// Regions and Worlds are weighted choices, i.e. {us-east-1: 3, us-west-1: 1}
+	Regions Weights      `yaml:"regions"`
+	Worlds  Weights      `yaml:"worlds"`
+	Skill   Distribution `yaml:"skill"`
+	Latency Distribution `yaml:"latency"`
+}
+
+// Weights maps values to their relative weight
+type Weights map[string]float64
+
+// Distribution draws a number from a uniform or normal distribution, or from a list of values
+type Distribution struct {
+	// Type is uniform, normal or choice
+	Type   string    `yaml:"type"`
+	Min    float64   `yaml:"min"`
+	Max    float64   `yaml:"max"`
+	Mean   float64   `yaml:"mean"`
+	StdDev float64   `yaml:"stddev"`
+	Values []float64 `yaml:"values"`
+}
+
+// DefaultScenario matches the behaviour of the TimeIntervalPlayerSimulator: a constant rate and uniform attributes
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Name:    "default",
+		Tick:    time.Second,
+		Arrival: Curve{Type: CurveConstant, Rate: 2},
+		Attributes: Attributes{
+			Regions: Weights{"us-east-1": 1, "us-east-2": 1, "us-west-1": 1, "us-west-2": 1},
+			Worlds:  Weights{"Dune": 1, "Nova": 1, "Pandora": 1, "Orion": 1},
+			Skill:   Distribution{Type: DistributionChoice, Values: []float64{10, 100, 1000}},
+			Latency: Distribution{Type: DistributionChoice, Values: []float64{25, 50, 75, 100}},
+		},
+		PartySizes: map[int]float64{1: 1},
+	}
+}
+
+// LoadScenario reads the scenario file. Attributes and party sizes not set by the file use the DefaultScenario.
+func LoadScenario(file string) (*Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read scenario file %s", file)
+	}
+
+	scenario := &Scenario{}
+	if err := yaml.Unmarshal(data, scenario); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse scenario file %s", file)
+	}
+
+	defaults := DefaultScenario()
+	if scenario.Tick == 0 {
+		scenario.Tick = defaults.Tick
+	}
+
+	if len(scenario.Attributes.Regions) == 0 {
+		scenario.Attributes.Regions = defaults.Attributes.Regions
+	}
+
+	if len(scenario.Attributes.Worlds) == 0 {
+		scenario.Attributes.Worlds = defaults.Attributes.Worlds
+	}
+
+	if len(scenario.Attributes.Skill.Type) == 0 {
+		scenario.Attributes.Skill = defaults.Attributes.Skill
+	}
+
+	if len(scenario.Attributes.Latency.Type) == 0 {
+		scenario.Attributes.Latency = defaults.Attributes.Latency
+	}
+
+	if len(scenario.PartySizes) == 0 {
+		scenario.PartySizes = defaults.PartySizes
+	}
+
+	if err := scenario.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid scenario file %s", file)
+	}
+
+	return scenario, nil
+}
+
+func (s *Scenario) Validate() error {
+	if s.Duration < 0 {
+		return errors.New("duration can't be lower than zero")
+	}
+
+	if s.Tick <= 0 {
+		return errors.New("tick must be higher than zero")
+	}
+
+	if err := s.Arrival.Validate(); err != nil {
+		return errors.Wrap(err, "arrival")
+	}
+
+	if err := s.Attributes.Regions.Validate(); err != nil {
+		return errors.Wrap(err, "regions")
+	}
+
+	if err := s.Attributes.Worlds.Validate(); err != nil {
+		return errors.Wrap(err, "worlds")
+	}
+
+	if err := s.Attributes.Skill.Validate(); err != nil {
+		return errors.Wrap(err, "skill")
+	}
+
+	if err := s.Attributes.Latency.Validate(); err != nil {
+		return errors.Wrap(err, "latency")
+	}
+
+	sizes := Weights{}
+	for size, weight := range s.PartySizes {
+		if size < 1 {
+			return errors.Errorf("party size %d must be at least 1", size)
+		}
+		sizes[strconv.Itoa(size)] = weight
+	}
+
+	return errors.Wrap(sizes.Validate(), "party_sizes")
+}
+
+// RateAt returns the parties per second at the elapsed time of the simulation
+func (c Curve) RateAt(elapsed time.Duration) float64 {
+	var rate float64
+	switch c.Type {
+	case CurveConstant:
+		rate = c.Rate
+	case CurveRamp:
+		progress := 1.0
+		if elapsed < c.Over {
+			progress = float64(elapsed) / float64(c.Over)
+		}
+		rate = c.From + (c.To-c.From)*progress
+	case CurveDiurnal:
+		rate = c.Rate + c.Amplitude*math.Sin(2*math.Pi*float64(elapsed)/float64(c.Period))
+	case CurveSpike:
+		rate = c.Rate
+		if elapsed >= c.At && elapsed < c.At+c.Width {
+			rate = c.Peak
+		}
+	}
+
+	return math.Max(rate, 0)
+}
+
+func (c Curve) Validate() error {
+	switch c.Type {
+	case CurveConstant:
+		if c.Rate < 0 {
+			return errors.New("rate can't be lower than zero")
+		}
+	case CurveRamp:
+		if c.From < 0 || c.To < 0 || c.Over <= 0 {
+			return errors.New("ramp requires from and to not lower than zero and over higher than zero")
+		}
+	case CurveDiurnal:
+		if c.Rate < 0 || c.Period <= 0 {
+			return errors.New("diurnal requires rate not lower than zero and period higher than zero")
+		}
+	case CurveSpike:
+		if c.Rate < 0 || c.Peak < 0 || c.Width <= 0 {
+			return errors.New("spike requires rate and peak not lower than zero and width higher than zero")
+		}
+	default:
+		return errors.Errorf("curve type %q is invalid, it should be constant, ramp, diurnal or spike", c.Type)
+	}
+
+	return nil
+}
+
+// Pick returns a value with a probability proportional to its weight
+func (w Weights) Pick(rnd *rand.Rand) string {
+	keys := make([]string, 0, len(w))
+	var total float64
+	for k, weight := range w {
+		keys = append(keys, k)
+		total += weight
+	}
+	// Map order is random, sorting keeps the runs with the same seed reproducible
+	sort.Strings(keys)
+
+	n := rnd.Float64() * total
+	for _, k := range keys {
+		n -= w[k]
+		if n < 0 {
+			return k
+		}
+	}
+
+	return keys[len(keys)-1]
+}
+
+func (w Weights) Validate() error {
+	if len(w) == 0 {
+		return errors.New("at least one value is required")
+	}
+
+	var total float64
+	for k, weight := range w {
+		if weight < 0 {
+			return errors.Errorf("weight of %s can't be lower than zero", k)
+		}
+		total += weight
+	}
+
+	if total <= 0 {
+		return errors.New("the sum of the weights must be higher than zero")
+	}
+
+	return nil
+}
+
+// Sample draws a number from the distribution. Normal samples are clamped to Min and Max if Max is set.
+func (d Distribution) Sample(rnd *rand.Rand) float64 {
+	switch d.Type {
+	case DistributionUniform:
+		return d.Min + rnd.Float64()*(d.Max-d.Min)
+	case DistributionNormal:
+		v := d.Mean + rnd.NormFloat64()*d.StdDev
+		if d.Max > d.Min {
+			v = math.Min(math.Max(v, d.Min), d.Max)
+		}
+		return v
+	default:
+		return d.Values[rnd.Intn(len(d.Values))]
+	}
+}
+
+func (d Distribution) Validate() error {
+	switch d.Type {
+	case DistributionUniform:
+		if d.Max < d.Min {
+			return errors.New("uniform requires max not lower than min")
+		}
+	case DistributionNormal:
+		if d.StdDev < 0 {
+			return errors.New("normal requires stddev not lower than zero")
+		}
+	case DistributionChoice:
+		if len(d.Values) == 0 {
+			return errors.New("choice requires at least one value")
+		}
+	default:
+		return errors.Errorf("distribution type %q is invalid, it should be uniform, normal or choice", d.Type)
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package players
+
+import (
+	"context"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ScenarioPlayerSimulator creates parties of players following the arrival curve and attribute distributions of a Scenario
+type ScenarioPlayerSimulator struct {
+	mux      *sync.Mutex
+	logger   *logrus.Entry
+	rnd      *rand.Rand
+	Scenario *Scenario
+	Creator  *TicketCreator
+	Players  []*Player
+	// Tracker waits for the assignments of the players, nil skips the tracking
+	Tracker *AssignmentTracker
+}
+
+func NewScenarioPlayerSimulator(scenario *Scenario, creator *TicketCreator) (*ScenarioPlayerSimulator, error) {
+	if err := scenario.Validate(); err != nil {
+		return nil, err
+	}
+
+	seed := scenario.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &ScenarioPlayerSimulator{
+		mux:      &sync.Mutex{},
+		logger:   runtime.Logger().WithField("source", "player_simulator"),
+		rnd:      rand.New(rand.NewSource(seed)),
+		Scenario: scenario,
+		Creator:  creator,
+		Players:  []*Player{},
+	}, nil
+}
+
+// Run creates a batch of parties every tick until the context is cancelled or the scenario duration is reached
+func (s *ScenarioPlayerSimulator) Run(ctx context.Context) error {
+	s.logger.WithFields(logrus.Fields{
+		"scenario": s.Scenario.Name,
+		"duration": s.Scenario.Duration,
+		"tick":     s.Scenario.Tick,
+		"arrival":  s.Scenario.Arrival.Type,
+	}).Info("starting Players Simulator")
+
+	// Players keep waiting for their assignment after the end of the scenario, until their patience runs out
+	ctxPlayers := ctx
+	if s.Scenario.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Scenario.Duration)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(s.Scenario.Tick)
+	defer ticker.Stop()
+
+	var requests sync.WaitGroup
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			requests.Wait()
+			s.logger.Infof("scenario %s finished, total Players: %d", s.Scenario.Name, s.TotalPlayers())
+			return nil
+		case <-ticker.C:
+			players := s.CreateBatch(time.Since(start))
+			if len(players) == 0 {
+				continue
+			}
+
+			requests.Add(1)
+			go func() {
+				defer requests.Done()
+				// Requests already started are completed when the scenario ends, an interrupt stops them
+				s.Creator.Create(ctxPlayers, s.logger, players)
+				s.AddPlayers(withTicket(players))
+				if s.Tracker != nil {
+					s.Tracker.Track(ctxPlayers, players)
+				}
+			}()
+		}
+	}
+}
+
+// CreateBatch creates the parties arriving in the tick ending at the elapsed time. Arrivals follow a Poisson process
+// with the rate of the curve.
+func (s *ScenarioPlayerSimulator) CreateBatch(elapsed time.Duration) []*Player {
+	lambda := s.Scenario.Arrival.RateAt(elapsed) * s.Scenario.Tick.Seconds()
+
+	var players []*Player
+	for i := poisson(s.rnd, lambda); i > 0; i-- {
+		players = append(players, s.CreateParty()...)
+	}
+
+	return players
+}
+
+// CreateParty creates the players of a party. Members share the region, world and latency, the skill is drawn for each one.
+func (s *ScenarioPlayerSimulator) CreateParty() []*Player {
+	attributes := s.Scenario.Attributes
+	sizes := Weights{}
+	for size, weight := range s.Scenario.PartySizes {
+		sizes[strconv.Itoa(size)] = weight
+	}
+	size, _ := strconv.Atoi(sizes.Pick(s.rnd))
+
+	partyID := uuid.New().String()
+	region := attributes.Regions.Pick(s.rnd)
+	world := attributes.Worlds.Pick(s.rnd)
+	latency := attributes.Latency.Sample(s.rnd)
+
+	var players []*Player
+	for i := 0; i < size; i++ {
+		players = append(players, &Player{
+			UID:     uuid.New().String(),
+			PartyID: partyID,
+			MatchRequest: &MatchRequest{
+				Tags: []string{GAME_MODE_SESSION, SIMULATOR_TAG},
+				StringArgs: map[string]string{
+					"region": region,
+					"world":  world,
+				},
+				DoubleArgs: map[string]float64{
+					"skill":   attributes.Skill.Sample(s.rnd),
+					"latency": latency,
+				},
+			},
+		})
+	}
+
+	return players
+}
+
+func (s *ScenarioPlayerSimulator) AddPlayers(players []*Player) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.Players = append(s.Players, players...)
+}
+
+func (s *ScenarioPlayerSimulator) TotalPlayers() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return len(s.Players)
+}
+
+// poisson draws the number of events of a Poisson distribution, large means use the normal approximation
+func poisson(rnd *rand.Rand, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+
+	if lambda > 30 {
+		return int(math.Max(0, math.Round(lambda+rnd.NormFloat64()*math.Sqrt(lambda))))
+	}
+
+	limit := math.Exp(-lambda)
+	p := 1.0
+	var k int
+	for {
+		p *= rnd.Float64()
+		if p <= limit {
+			return k
+		}
+		k++
+	}
+}
@@ -0,0 +1,210 @@
+package players
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"math/rand"
+	"open-match.dev/open-match/pkg/pb"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadScenario(t *testing.T) {
+	t.Run("it should load the scenario and fill the defaults", func(t *testing.T) {
+		scenario, err := LoadScenario(writeScenario(t, `
+name: evening
+duration: 10m
+seed: 42
+arrival:
+  type: diurnal
+  rate: 10
+  amplitude: 5
+  period: 24h
+attributes:
+  regions:
+    us-east-1: 3
+    us-west-1: 1
+  skill:
+    type: normal
+    mean: 500
+    stddev: 150
+    min: 0
+    max: 1000
+party_sizes:
+  1: 6
+  2: 3
+  4: 1
+`))
+		require.NoError(t, err)
+		require.Equal(t, "evening", scenario.Name)
+		require.Equal(t, 10*time.Minute, scenario.Duration)
+		require.Equal(t, time.Second, scenario.Tick)
+		require.Equal(t, 24*time.Hour, scenario.Arrival.Period)
+		require.Equal(t, Weights{"us-east-1": 3, "us-west-1": 1}, scenario.Attributes.Regions)
+		require.Equal(t, DefaultScenario().Attributes.Worlds, scenario.Attributes.Worlds)
+		require.Equal(t, DistributionNormal, scenario.Attributes.Skill.Type)
+		require.Equal(t, DefaultScenario().Attributes.Latency, scenario.Attributes.Latency)
+		require.Equal(t, map[int]float64{1: 6, 2: 3, 4: 1}, scenario.PartySizes)
+	})
+
+	testCases := []struct {
+		name     string
+		scenario string
+	}{
+		{name: "it should reject an unknown curve", scenario: "arrival:\n  type: wave\n"},
+		{name: "it should reject a ramp without length", scenario: "arrival:\n  type: ramp\n  from: 1\n  to: 10\n"},
+		{name: "it should reject negative weights", scenario: "arrival:\n  type: constant\n  rate: 1\nattributes:\n  regions:\n    us-east-1: -1\n"},
+		{name: "it should reject an unknown distribution", scenario: "arrival:\n  type: constant\n  rate: 1\nattributes:\n  skill:\n    type: poisson\n"},
+		{name: "it should reject an empty party", scenario: "arrival:\n  type: constant\n  rate: 1\nparty_sizes:\n  0: 1\n"},
+		{name: "it should reject an invalid file", scenario: "arrival: [constant]\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadScenario(writeScenario(t, tc.scenario))
+			require.Error(t, err)
+		})
+	}
+
+	_, err := LoadScenario(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
+
+func TestCurve_RateAt(t *testing.T) {
+	testCases := []struct {
+		name    string
+		curve   Curve
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "constant", curve: Curve{Type: CurveConstant, Rate: 5}, elapsed: time.Hour, want: 5},
+		{name: "ramp start", curve: Curve{Type: CurveRamp, From: 2, To: 12, Over: 10 * time.Second}, want: 2},
+		{name: "ramp middle", curve: Curve{Type: CurveRamp, From: 2, To: 12, Over: 10 * time.Second}, elapsed: 5 * time.Second, want: 7},
+		{name: "ramp end", curve: Curve{Type: CurveRamp, From: 2, To: 12, Over: 10 * time.Second}, elapsed: time.Minute, want: 12},
+		{name: "diurnal peak", curve: Curve{Type: CurveDiurnal, Rate: 10, Amplitude: 5, Period: 4 * time.Hour}, elapsed: time.Hour, want: 15},
+		{name: "diurnal low", curve: Curve{Type: CurveDiurnal, Rate: 10, Amplitude: 5, Period: 4 * time.Hour}, elapsed: 3 * time.Hour, want: 5},
+		{name: "diurnal never negative", curve: Curve{Type: CurveDiurnal, Rate: 1, Amplitude: 5, Period: 4 * time.Hour}, elapsed: 3 * time.Hour, want: 0},
+		{name: "before spike", curve: Curve{Type: CurveSpike, Rate: 1, Peak: 50, At: time.Minute, Width: 10 * time.Second}, elapsed: 30 * time.Second, want: 1},
+		{name: "spike", curve: Curve{Type: CurveSpike, Rate: 1, Peak: 50, At: time.Minute, Width: 10 * time.Second}, elapsed: 65 * time.Second, want: 50},
+		{name: "after spike", curve: Curve{Type: CurveSpike, Rate: 1, Peak: 50, At: time.Minute, Width: 10 * time.Second}, elapsed: 70 * time.Second, want: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.InDelta(t, tc.want, tc.curve.RateAt(tc.elapsed), 0.0001)
+		})
+	}
+}
+
+func TestWeights_Pick(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	weights := Weights{"us-east-1": 3, "us-west-1": 1, "eu-west-1": 0}
+
+	picked := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		picked[weights.Pick(rnd)]++
+	}
+
+	require.InDelta(t, 3000, picked["us-east-1"], 150)
+	require.InDelta(t, 1000, picked["us-west-1"], 150)
+	require.Zero(t, picked["eu-west-1"])
+}
+
+func TestDistribution_Sample(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	normal := Distribution{Type: DistributionNormal, Mean: 500, StdDev: 150, Min: 0, Max: 1000}
+	var sum float64
+	for i := 0; i < 2000; i++ {
+		v := normal.Sample(rnd)
+		require.GreaterOrEqual(t, v, 0.0)
+		require.LessOrEqual(t, v, 1000.0)
+		sum += v
+	}
+	require.InDelta(t, 500, sum/2000, 20)
+
+	uniform := Distribution{Type: DistributionUniform, Min: 25, Max: 50}
+	for i := 0; i < 100; i++ {
+		v := uniform.Sample(rnd)
+		require.GreaterOrEqual(t, v, 25.0)
+		require.Less(t, v, 50.0)
+	}
+
+	choice := Distribution{Type: DistributionChoice, Values: []float64{10, 100}}
+	require.Contains(t, []float64{10, 100}, choice.Sample(rnd))
+}
+
+func TestScenarioPlayerSimulator_CreateBatch(t *testing.T) {
+	scenario := DefaultScenario()
+	scenario.Seed = 7
+	scenario.Arrival = Curve{Type: CurveConstant, Rate: 20}
+	scenario.PartySizes = map[int]float64{3: 1}
+
+	simulator, err := NewScenarioPlayerSimulator(scenario, nil)
+	require.NoError(t, err)
+
+	var total int
+	for i := 0; i < 50; i++ {
+		players := simulator.CreateBatch(time.Duration(i) * time.Second)
+		require.Zero(t, len(players)%3)
+		total += len(players)
+
+		parties := map[string][]*Player{}
+		for _, p := range players {
+			parties[p.PartyID] = append(parties[p.PartyID], p)
+		}
+
+		for _, members := range parties {
+			require.Len(t, members, 3)
+			for _, m := range members {
+				require.Equal(t, members[0].MatchRequest.StringArgs, m.MatchRequest.StringArgs)
+				require.Equal(t, members[0].MatchRequest.DoubleArgs["latency"], m.MatchRequest.DoubleArgs["latency"])
+			}
+		}
+	}
+
+	// 20 parties of 3 players per second on average
+	require.InDelta(t, 50*20*3, total, 300)
+}
+
+func TestScenarioPlayerSimulator_Run(t *testing.T) {
+	scenario := DefaultScenario()
+	scenario.Duration = 100 * time.Millisecond
+	scenario.Tick = 10 * time.Millisecond
+	scenario.Arrival = Curve{Type: CurveConstant, Rate: 500}
+
+	requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+		return &pb.Ticket{Id: uuid.New().String(), SearchFields: request.Ticket.SearchFields}, nil
+	}
+
+	simulator, err := NewScenarioPlayerSimulator(scenario, NewTicketCreator(requestMatchFunc, 1, 0, 0, 0))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- simulator.Run(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "simulator did not stop at the end of the scenario")
+	}
+
+	require.Greater(t, simulator.TotalPlayers(), 0)
+	for _, p := range simulator.Players {
+		require.NotNil(t, p.MatchRequest.Ticket)
+	}
+}
+
+func writeScenario(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	return path
+}
@@ -0,0 +1,195 @@
+package players
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"math"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"time"
+)
+
+// TicketStats counts the CreateTicket calls of a TicketCreator
+type TicketStats struct {
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// TicketCreator creates the tickets of the players with a pool of workers. The calls share a token bucket rate limit and
+// have their own timeout. A failed call is counted and the player is left without ticket, the other tickets are still created.
+type TicketCreator struct {
+	mux              sync.Mutex
+	requestMatchFunc RequestMatchFunc
+	workers          int
+	timeout          time.Duration
+	limiter          *tokenBucket
+	stats            TicketStats
+}
+
+// NewTicketCreator returns a TicketCreator with at least one worker. A zero rate is unlimited and a zero timeout only
+// bounds the calls by the context.
+func NewTicketCreator(requestMatchFunc RequestMatchFunc, workers int, rate float64, burst int, timeout time.Duration) *TicketCreator {
+	if workers < 1 {
+		workers = 1
+	}
+
+	creator := &TicketCreator{
+		requestMatchFunc: requestMatchFunc,
+		workers:          workers,
+		timeout:          timeout,
+	}
+
+	if rate > 0 {
+		creator.limiter = newTokenBucket(rate, burst)
+	}
+
+	return creator
+}
+
+// Create creates a ticket for every party and stores it on the MatchRequest of its members. Players without party get a
+// ticket of their own. Cancelling the context stops the creation, the parties not started yet are not counted.
+func (c *TicketCreator) Create(ctx context.Context, logger *logrus.Entry, players []*Player) TicketStats {
+	parties := make(chan []*Player)
+	go func() {
+		defer close(parties)
+		for _, party := range groupParties(players) {
+			select {
+			case parties <- party:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var stats TicketStats
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for party := range parties {
+				err := c.createTicket(ctx, logger, party)
+
+				mux.Lock()
+				if err != nil {
+					stats.Failed++
+					logger.WithError(err).Debugf("failed to create the ticket of player %s", party[0].UID)
+				} else {
+					stats.Created++
+				}
+				mux.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	c.mux.Lock()
+	c.stats.Created += stats.Created
+	c.stats.Failed += stats.Failed
+	c.mux.Unlock()
+
+	if stats.Failed > 0 {
+		logger.Warnf("failed to create %d of %d tickets", stats.Failed, stats.Created+stats.Failed)
+	}
+
+	return stats
+}
+
+// Stats returns the tickets created and failed since the TicketCreator was created
+func (c *TicketCreator) Stats() TicketStats {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.stats
+}
+
+func (c *TicketCreator) createTicket(ctx context.Context, logger *logrus.Entry, party []*Player) error {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req := &pb.CreateTicketRequest{
+		Ticket: &pb.Ticket{
+			SearchFields: partySearchFields(party),
+		},
+	}
+
+	requested := time.Now()
+	ticket, err := c.requestMatchFunc(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for _, player := range party {
+		player.MatchRequest.Ticket = ticket
+		player.MatchRequest.Requested = requested
+		logger.Debugf("ticketID=%s playerUID=%s stringArgs=%s doubleArgs=%v", ticket.GetId(), player.UID, ticket.GetSearchFields().GetStringArgs(), ticket.GetSearchFields().GetDoubleArgs())
+	}
+
+	return nil
+}
+
+// tokenBucket allows rate tokens per second with bursts of up to burst tokens
+type tokenBucket struct {
+	mux    sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  math.Max(1, float64(burst)),
+		tokens: math.Max(1, float64(burst)),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// Wait takes a token, blocking until one is available or the context is done
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		wait := b.reserve()
+		if wait == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Wrap(ctx.Err(), "rate limit wait cancelled")
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token if there is one, otherwise it returns the time until the next token
+func (b *tokenBucket) reserve() time.Duration {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration(math.Ceil((1 - b.tokens) / b.rate * float64(time.Second)))
+}
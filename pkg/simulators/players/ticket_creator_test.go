@@ -0,0 +1,137 @@
+package players
+
+import (
+	"context"
+	"errors"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"open-match.dev/open-match/pkg/pb"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTicketCreator_Create(t *testing.T) {
+	newPlayers := func(count int) []*Player {
+		var players []*Player
+		for i := 0; i < count; i++ {
+			players = append(players, &Player{
+				UID:          strconv.Itoa(i),
+				MatchRequest: &MatchRequest{StringArgs: map[string]string{"uid": strconv.Itoa(i)}},
+			})
+		}
+		return players
+	}
+
+	logger := runtime.NewLogger(true)
+
+	t.Run("it should create the tickets with the workers concurrently", func(t *testing.T) {
+		var inFlight, maxInFlight atomic.Int32
+		requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				max := maxInFlight.Load()
+				if current <= max || maxInFlight.CompareAndSwap(max, current) {
+					break
+				}
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			return &pb.Ticket{Id: uuid.New().String(), SearchFields: request.Ticket.SearchFields}, nil
+		}
+
+		players := newPlayers(8)
+		start := time.Now()
+		stats := NewTicketCreator(requestMatchFunc, 4, 0, 0, 0).Create(context.Background(), logger, players)
+		require.Equal(t, TicketStats{Created: 8}, stats)
+		require.Equal(t, int32(4), maxInFlight.Load())
+		for _, player := range players {
+			require.NotNil(t, player.MatchRequest.Ticket)
+			require.False(t, player.MatchRequest.Requested.Before(start), "the request time is set by ticket, not by batch")
+		}
+		require.True(t, players[7].MatchRequest.Requested.After(players[0].MatchRequest.Requested))
+	})
+
+	t.Run("it should count the failed tickets and create the others", func(t *testing.T) {
+		requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+			if request.Ticket.SearchFields.StringArgs["uid"] == "1" {
+				return nil, errors.New("frontend unavailable")
+			}
+			return &pb.Ticket{Id: uuid.New().String(), SearchFields: request.Ticket.SearchFields}, nil
+		}
+
+		creator := NewTicketCreator(requestMatchFunc, 2, 0, 0, 0)
+		players := newPlayers(3)
+		require.Equal(t, TicketStats{Created: 2, Failed: 1}, creator.Create(context.Background(), logger, players))
+		require.Nil(t, players[1].MatchRequest.Ticket)
+		require.Len(t, withTicket(players), 2)
+
+		creator.Create(context.Background(), logger, newPlayers(2))
+		require.Equal(t, TicketStats{Created: 3, Failed: 2}, creator.Stats())
+	})
+
+	t.Run("it should time out every call", func(t *testing.T) {
+		requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		start := time.Now()
+		stats := NewTicketCreator(requestMatchFunc, 2, 0, 0, 20*time.Millisecond).Create(context.Background(), logger, newPlayers(4))
+		require.Equal(t, TicketStats{Failed: 4}, stats)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("it should stop creating tickets when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var calls atomic.Int32
+		requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+			calls.Add(1)
+			cancel()
+			return &pb.Ticket{Id: uuid.New().String()}, nil
+		}
+
+		stats := NewTicketCreator(requestMatchFunc, 1, 0, 0, 0).Create(ctx, logger, newPlayers(10))
+		require.Less(t, int(calls.Load()), 10)
+		require.Equal(t, int(calls.Load()), stats.Created)
+	})
+
+	t.Run("it should wait for the rate limit", func(t *testing.T) {
+		requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+			return &pb.Ticket{Id: uuid.New().String()}, nil
+		}
+
+		start := time.Now()
+		stats := NewTicketCreator(requestMatchFunc, 4, 50, 1, 0).Create(context.Background(), logger, newPlayers(5))
+		require.Equal(t, TicketStats{Created: 5}, stats)
+		require.GreaterOrEqual(t, time.Since(start), 70*time.Millisecond, "4 tickets wait 20ms each for a token")
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(10, 2)
+	bucket.last = now
+	bucket.now = func() time.Time { return now }
+
+	require.Zero(t, bucket.reserve())
+	require.Zero(t, bucket.reserve())
+	require.Equal(t, 100*time.Millisecond, bucket.reserve(), "the burst is used")
+
+	now = now.Add(50 * time.Millisecond)
+	require.Equal(t, 50*time.Millisecond, bucket.reserve())
+
+	now = now.Add(time.Second)
+	require.Zero(t, bucket.reserve())
+	require.Zero(t, bucket.reserve())
+	require.NotZero(t, bucket.reserve(), "the tokens are capped to the burst")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, bucket.Wait(ctx), context.Canceled)
+}
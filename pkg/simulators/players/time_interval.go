@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
@@ -15,18 +16,26 @@ import (
 
 const (
 	GAME_MODE_SESSION = "mode.session"
+	// SIMULATOR_TAG is set on every ticket of the simulator so they can be expired with the tickets command
+	SIMULATOR_TAG = "source.simulator"
 )
 
 type MatchRequest struct {
-	Ticket     *pb.Ticket
+	Ticket *pb.Ticket
+	// Requested is the time the ticket was requested, the wait and patience of the player start from it
+	Requested  time.Time
 	Tags       []string
 	StringArgs map[string]string
 	DoubleArgs map[string]float64
 }
 
 type Player struct {
-	UID          string
+	UID string
+	// PartyID is shared by the players of the same party, empty for players created without a scenario
+	PartyID      string
 	MatchRequest *MatchRequest
+	// Assignment is set by the AssignmentTracker once the player is matched
+	Assignment *pb.Assignment
 }
 
 type RequestMatchFunc func(ctx context.Context, ticket *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error)
@@ -36,27 +45,30 @@ type RequestMatchFunc func(ctx context.Context, ticket *pb.CreateTicketRequest,
 - Request match on a interval basis
 */
 type TimeIntervalPlayerSimulator struct {
-	mux              *sync.Mutex
-	logger           *logrus.Entry
-	Interval         time.Duration
-	PlayersPool      int
-	RequestMatchFunc RequestMatchFunc
-	Players          []*Player
+	mux         *sync.Mutex
+	logger      *logrus.Entry
+	Interval    time.Duration
+	PlayersPool int
+	Creator     *TicketCreator
+	Players     []*Player
+	// Tracker waits for the assignments of the players, nil skips the tracking
+	Tracker  *AssignmentTracker
+	requests sync.WaitGroup
 }
 
-func NewTimeIntervalPlayerSimulator(interval string, playersPool int, requestMatchFunc RequestMatchFunc) (*TimeIntervalPlayerSimulator, error) {
+func NewTimeIntervalPlayerSimulator(interval string, playersPool int, creator *TicketCreator) (*TimeIntervalPlayerSimulator, error) {
 	duration, err := time.ParseDuration(interval)
 	if err != nil {
 		return nil, err
 	}
 
 	return &TimeIntervalPlayerSimulator{
-		mux:              &sync.Mutex{},
-		logger:           runtime.NewLogger(true),
-		Interval:         duration,
-		PlayersPool:      playersPool,
-		RequestMatchFunc: requestMatchFunc,
-		Players:          []*Player{},
+		mux:         &sync.Mutex{},
+		logger:      runtime.NewLogger(true),
+		Interval:    duration,
+		PlayersPool: playersPool,
+		Creator:     creator,
+		Players:     []*Player{},
 	}, nil
 }
 
@@ -68,20 +80,22 @@ func (p *TimeIntervalPlayerSimulator) Run(ctx context.Context) error {
 	ctxSimulator, cancel := context.WithCancel(ctx)
 	ticker := time.NewTicker(p.Interval)
 
+	done := make(chan struct{})
 	go func() {
 		defer func() {
 			ticker.Stop()
 			cancel()
+			close(done)
 		}()
 
 		//Create fist batch before ticker. Useful if longer intervals are set
-		p.CreateMatchmakingRequests()
+		p.CreateMatchmakingRequests(ctxSimulator)
 
 		for {
 			select {
 			case t := <-ticker.C:
 				p.logger.Infof("create matchmaking requests for %d Players at %s", p.PlayersPool, t.String())
-				p.CreateMatchmakingRequests()
+				p.CreateMatchmakingRequests(ctxSimulator)
 			case <-ctxSimulator.Done():
 				return
 			}
@@ -89,6 +103,8 @@ func (p *TimeIntervalPlayerSimulator) Run(ctx context.Context) error {
 	}()
 
 	<-ctx.Done()
+	<-done
+	p.requests.Wait()
 	return nil
 }
 
@@ -103,7 +119,7 @@ func (p *TimeIntervalPlayerSimulator) CreatePlayers(count int) ([]*Player, error
 		players = append(players, &Player{
 			UID: uuid.New().String(),
 			MatchRequest: &MatchRequest{
-				Tags:       []string{GAME_MODE_SESSION},
+				Tags:       []string{GAME_MODE_SESSION, SIMULATOR_TAG},
 				StringArgs: CreateStringArgs(),
 				DoubleArgs: CreateDoubleArgs(),
 			}})
@@ -112,29 +128,76 @@ func (p *TimeIntervalPlayerSimulator) CreatePlayers(count int) ([]*Player, error
 	return players, nil
 }
 
-func (p *TimeIntervalPlayerSimulator) RequestMatchForPlayers(players []*Player) error {
+// RequestMatchForPlayers creates the tickets of the players. The players whose ticket failed are not added.
+func (p *TimeIntervalPlayerSimulator) RequestMatchForPlayers(ctx context.Context, players []*Player) TicketStats {
+	stats := p.Creator.Create(ctx, p.logger, players)
+	p.AddPlayers(withTicket(players))
+
+	return stats
+}
+
+// groupParties groups the players by PartyID keeping the order they were created
+func groupParties(players []*Player) [][]*Player {
+	var parties [][]*Player
+	index := map[string]int{}
 	for _, player := range players {
-		req := &pb.CreateTicketRequest{
-			Ticket: &pb.Ticket{
-				SearchFields: &pb.SearchFields{
-					Tags:       player.MatchRequest.Tags,
-					StringArgs: player.MatchRequest.StringArgs,
-					DoubleArgs: player.MatchRequest.DoubleArgs,
-				},
-			},
+		if len(player.PartyID) == 0 {
+			parties = append(parties, []*Player{player})
+			continue
 		}
 
-		ticket, err := p.RequestMatchFunc(context.Background(), req)
-		if err != nil {
-			return err
+		i, ok := index[player.PartyID]
+		if !ok {
+			i = len(parties)
+			index[player.PartyID] = i
+			parties = append(parties, nil)
+		}
+		parties[i] = append(parties[i], player)
+	}
+
+	return parties
+}
+
+// partySearchFields uses the args of the first member. Party tickets carry the party size and the average skill of the members.
+func partySearchFields(party []*Player) *pb.SearchFields {
+	leader := party[0].MatchRequest
+	if len(party) == 1 {
+		return &pb.SearchFields{
+			Tags:       leader.Tags,
+			StringArgs: leader.StringArgs,
+			DoubleArgs: leader.DoubleArgs,
 		}
+	}
 
-		player.MatchRequest.Ticket = ticket
-		p.logger.Debugf("ticketID=%s playerUID=%s stringArgs=%s doubleArgs=%v", ticket.GetId(), player.UID, ticket.SearchFields.StringArgs, ticket.SearchFields.DoubleArgs)
+	doubleArgs := map[string]float64{}
+	for k, v := range leader.DoubleArgs {
+		doubleArgs[k] = v
 	}
 
-	p.AddPlayers(players)
-	return nil
+	var skill float64
+	for _, player := range party {
+		skill += player.MatchRequest.DoubleArgs["skill"]
+	}
+	doubleArgs["skill"] = skill / float64(len(party))
+	doubleArgs[extensions.PartySizeArg] = float64(len(party))
+
+	return &pb.SearchFields{
+		Tags:       leader.Tags,
+		StringArgs: leader.StringArgs,
+		DoubleArgs: doubleArgs,
+	}
+}
+
+// withTicket returns the players whose ticket was created
+func withTicket(players []*Player) []*Player {
+	var created []*Player
+	for _, player := range players {
+		if player.MatchRequest.Ticket != nil {
+			created = append(created, player)
+		}
+	}
+
+	return created
 }
 
 func (p *TimeIntervalPlayerSimulator) AddPlayers(players []*Player) {
@@ -144,16 +207,20 @@ func (p *TimeIntervalPlayerSimulator) AddPlayers(players []*Player) {
 	p.Players = append(p.Players, players...)
 }
 
-func (p *TimeIntervalPlayerSimulator) CreateMatchmakingRequests() {
+// CreateMatchmakingRequests creates the players pool and their tickets. The players are tracked until the context is done.
+func (p *TimeIntervalPlayerSimulator) CreateMatchmakingRequests(ctx context.Context) {
+	p.requests.Add(1)
 	go func() {
+		defer p.requests.Done()
 		players, err := p.CreatePlayers(p.PlayersPool)
 		if err != nil {
 			p.logger.Error(err)
 		}
 
-		err = p.RequestMatchForPlayers(players)
-		if err != nil {
-			p.logger.Error(err)
+		p.RequestMatchForPlayers(ctx, players)
+
+		if p.Tracker != nil {
+			p.Tracker.Track(ctx, players)
 		}
 
 		p.logger.Infof("total Players: %d", len(p.Players))
@@ -3,6 +3,7 @@ package players
 import (
 	"context"
 	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
@@ -54,6 +55,7 @@ func TestTimeIntervalPlayerSimulator_CreatePlayers(t *testing.T) {
 				require.Equal(t, tc.playersCount, len(players))
 				for _, player := range players {
 					p.logger.Infof("StringArgs: %s", player.MatchRequest.StringArgs)
+					require.Contains(t, player.MatchRequest.Tags, SIMULATOR_TAG)
 				}
 			}
 		})
@@ -94,18 +96,18 @@ func TestTimeIntervalPlayerSimulator_RequestMatchForPlayers(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			p := &TimeIntervalPlayerSimulator{
-				mux:              &sync.Mutex{},
-				logger:           runtime.NewLogger(true),
-				Players:          []*Player{},
-				RequestMatchFunc: requestMatchFunc,
+				mux:     &sync.Mutex{},
+				logger:  runtime.NewLogger(true),
+				Players: []*Player{},
+				Creator: NewTicketCreator(requestMatchFunc, 1, 0, 0, 0),
 			}
 
-			err := p.RequestMatchForPlayers(tc.players)
+			stats := p.RequestMatchForPlayers(context.Background(), tc.players)
 
 			if tc.wantErr {
-				require.Error(t, err)
+				require.Greater(t, stats.Failed, 0)
 			} else {
-				require.NoError(t, err)
+				require.Equal(t, TicketStats{Created: len(tc.players)}, stats)
 				for _, player := range tc.players {
 					require.NotNil(t, player.MatchRequest.Ticket)
 				}
@@ -114,3 +116,34 @@ func TestTimeIntervalPlayerSimulator_RequestMatchForPlayers(t *testing.T) {
 		})
 	}
 }
+
+func TestTicketCreator_Parties(t *testing.T) {
+	member := func(uid, partyID string, skill float64) *Player {
+		return &Player{
+			UID:     uid,
+			PartyID: partyID,
+			MatchRequest: &MatchRequest{
+				Tags:       []string{GAME_MODE_SESSION},
+				StringArgs: map[string]string{"region": "us-east-1", "world": "Dune"},
+				DoubleArgs: map[string]float64{"skill": skill, "latency": 25},
+			},
+		}
+	}
+
+	var requests []*pb.CreateTicketRequest
+	requestMatchFunc := func(ctx context.Context, request *pb.CreateTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+		requests = append(requests, request)
+		return &pb.Ticket{Id: uuid.New().String(), SearchFields: request.Ticket.SearchFields}, nil
+	}
+
+	players := []*Player{member("p1", "party-1", 100), member("p2", "", 10), member("p3", "party-1", 300)}
+	require.Equal(t, TicketStats{Created: 2}, NewTicketCreator(requestMatchFunc, 1, 0, 0, 0).Create(context.Background(), runtime.NewLogger(true), players))
+
+	require.Len(t, requests, 2)
+	require.Equal(t, map[string]float64{"skill": 200, "latency": 25, extensions.PartySizeArg: 2}, requests[0].Ticket.SearchFields.DoubleArgs)
+	require.Equal(t, map[string]float64{"skill": 10, "latency": 25}, requests[1].Ticket.SearchFields.DoubleArgs)
+
+	require.Same(t, players[0].MatchRequest.Ticket, players[2].MatchRequest.Ticket)
+	require.NotSame(t, players[0].MatchRequest.Ticket, players[1].MatchRequest.Ticket)
+	require.Equal(t, float64(100), players[0].MatchRequest.DoubleArgs["skill"], "the members keep their own skill")
+}
@@ -0,0 +1,355 @@
+package players
+
+import (
+	"context"
+	"fmt"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/extensions"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"net"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"time"
+)
+
+const (
+	// AssignmentModeWatch streams the assignment with WatchAssignments
+	AssignmentModeWatch = "watch"
+	// AssignmentModePoll calls GetTicket on every poll interval
+	AssignmentModePoll = "poll"
+
+	OutcomeMatched   Outcome = "matched"
+	OutcomeAbandoned Outcome = "abandoned"
+	OutcomeTimedOut  Outcome = "timed_out"
+
+	deleteTicketTimeout = 5 * time.Second
+)
+
+// Outcome is how the wait of a player for a match ended
+type Outcome string
+
+// TicketService is the subset of the Open Match FrontendServiceClient used by the players to follow their tickets
+type TicketService interface {
+	GetTicket(ctx context.Context, in *pb.GetTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error)
+	WatchAssignments(ctx context.Context, in *pb.WatchAssignmentsRequest, opts ...grpc.CallOption) (pb.FrontendService_WatchAssignmentsClient, error)
+	DeleteTicket(ctx context.Context, in *pb.DeleteTicketRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+// ConnectFunc connects the player to the GameServer of the assignment
+type ConnectFunc func(ctx context.Context, connection string) error
+
+// PlayerResult is the outcome of a player that requested a match
+type PlayerResult struct {
+	PlayerUID string
+	TicketID  string
+	Outcome   Outcome
+	// Dimensions are the string args of the ticket the profiles are built from, i.e. region and world
+	Dimensions map[string]string
+	// Wait is the time between the ticket request and the assignment, or the end of the wait if not matched
+	Wait       time.Duration
+	Connection string
+	// GameServer is the name of the allocated GameServer, the connection if the assignment does not carry it
+	GameServer string
+	// Connected is set if the player tried to connect to the assignment
+	Connected  *bool
+	ConnectErr error
+}
+
+// Summary counts the outcomes of the players of a simulation
+type Summary struct {
+	Matched       int
+	Abandoned     int
+	TimedOut      int
+	Connected     int
+	ConnectFailed int
+	// AverageWait is the mean time to match of the matched players
+	AverageWait time.Duration
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("matched: %d, abandoned: %d, timed out: %d, connected: %d, connect failed: %d, average time to match: %s",
+		s.Matched, s.Abandoned, s.TimedOut, s.Connected, s.ConnectFailed, s.AverageWait)
+}
+
+// AssignmentTracker waits for the assignment of every ticket created by a simulator. Players that are not matched
+// within their Patience give up and delete their ticket. Players still waiting when the simulation ends are timed out.
+type AssignmentTracker struct {
+	logger  *logrus.Entry
+	mux     sync.Mutex
+	wg      sync.WaitGroup
+	results []PlayerResult
+
+	Service TicketService
+	// Mode is watch or poll
+	Mode         string
+	PollInterval time.Duration
+	// Patience is the time a player waits for a match, zero waits until the simulation ends
+	Patience time.Duration
+	// Connect is called with the connection of the matched players, nil skips the connection
+	Connect ConnectFunc
+}
+
+func NewAssignmentTracker(service TicketService, mode string, pollInterval, patience time.Duration, connect ConnectFunc) (*AssignmentTracker, error) {
+	if mode != AssignmentModeWatch && mode != AssignmentModePoll {
+		return nil, errors.Errorf("assignment mode %q is invalid, it should be watch or poll", mode)
+	}
+
+	if pollInterval <= 0 {
+		return nil, errors.New("poll interval must be higher than zero")
+	}
+
+	return &AssignmentTracker{
+		logger:       runtime.Logger().WithField("source", "player_simulator"),
+		Service:      service,
+		Mode:         mode,
+		PollInterval: pollInterval,
+		Patience:     patience,
+		Connect:      connect,
+	}, nil
+}
+
+// Track follows the tickets of the players until the context is cancelled. Members of a party share the ticket and
+// its outcome. Players without ticket are ignored. The wait and patience of a party start when its ticket was requested,
+// not when the batch of tickets is handed over.
+func (t *AssignmentTracker) Track(ctx context.Context, players []*Player) {
+	var order []string
+	tickets := map[string][]*Player{}
+	for _, player := range players {
+		if player.MatchRequest.Ticket == nil {
+			continue
+		}
+
+		id := player.MatchRequest.Ticket.GetId()
+		if _, ok := tickets[id]; !ok {
+			order = append(order, id)
+		}
+		tickets[id] = append(tickets[id], player)
+	}
+
+	for _, id := range order {
+		t.wg.Add(1)
+		go func(party []*Player) {
+			defer t.wg.Done()
+			t.add(t.wait(ctx, party, requestedAt(party[0].MatchRequest))...)
+		}(tickets[id])
+	}
+}
+
+// Wait blocks until every tracked player has an outcome
+func (t *AssignmentTracker) Wait() {
+	t.wg.Wait()
+}
+
+func (t *AssignmentTracker) Results() []PlayerResult {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	return append([]PlayerResult{}, t.results...)
+}
+
+func (t *AssignmentTracker) Summary() Summary {
+	var summary Summary
+	var wait time.Duration
+	for _, r := range t.Results() {
+		switch r.Outcome {
+		case OutcomeMatched:
+			summary.Matched++
+			wait += r.Wait
+		case OutcomeAbandoned:
+			summary.Abandoned++
+		case OutcomeTimedOut:
+			summary.TimedOut++
+		}
+
+		if r.Connected != nil {
+			if *r.Connected {
+				summary.Connected++
+			} else {
+				summary.ConnectFailed++
+			}
+		}
+	}
+
+	if summary.Matched > 0 {
+		summary.AverageWait = wait / time.Duration(summary.Matched)
+	}
+
+	return summary
+}
+
+func (t *AssignmentTracker) wait(ctx context.Context, party []*Player, requested time.Time) []PlayerResult {
+	ticketID := party[0].MatchRequest.Ticket.GetId()
+	result := PlayerResult{TicketID: ticketID, Dimensions: party[0].MatchRequest.StringArgs}
+
+	ctxWait := ctx
+	if t.Patience > 0 {
+		var cancel context.CancelFunc
+		ctxWait, cancel = context.WithDeadline(ctx, requested.Add(t.Patience))
+		defer cancel()
+	}
+
+	assignment := t.waitAssignment(ctxWait, ticketID)
+	result.Wait = time.Since(requested)
+
+	switch {
+	case assignment != nil:
+		result.Outcome = OutcomeMatched
+		result.Connection = assignment.GetConnection()
+		result.GameServer = gameServerName(assignment)
+		t.logger.Debugf("ticketID=%s with %d players matched after %s on %s", ticketID, len(party), result.Wait, result.Connection)
+	case ctx.Err() != nil:
+		result.Outcome = OutcomeTimedOut
+	default:
+		result.Outcome = OutcomeAbandoned
+		t.deleteTicket(ticketID)
+		t.logger.Debugf("ticketID=%s with %d players gave up after %s", ticketID, len(party), result.Wait)
+	}
+
+	var results []PlayerResult
+	for _, player := range party {
+		r := result
+		r.PlayerUID = player.UID
+		if assignment != nil {
+			player.Assignment = assignment
+			t.connect(ctx, &r)
+		}
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// waitAssignment returns the assignment of the ticket or nil if the context is done first. Errors are retried.
+func (t *AssignmentTracker) waitAssignment(ctx context.Context, ticketID string) *pb.Assignment {
+	ticker := time.NewTicker(t.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		var assignment *pb.Assignment
+		var err error
+		if t.Mode == AssignmentModeWatch {
+			assignment, err = t.watch(ctx, ticketID)
+		} else {
+			assignment, err = t.poll(ctx, ticketID)
+		}
+
+		if assignment != nil {
+			return assignment
+		}
+
+		if err != nil && ctx.Err() == nil {
+			t.logger.Debugf("failed to get the assignment of ticketID=%s: %s", ticketID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch returns the first assignment with a connection streamed for the ticket
+func (t *AssignmentTracker) watch(ctx context.Context, ticketID string) (*pb.Assignment, error) {
+	stream, err := t.Service.WatchAssignments(ctx, &pb.WatchAssignmentsRequest{TicketId: ticketID})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.GetAssignment().GetConnection()) > 0 {
+			return resp.GetAssignment(), nil
+		}
+	}
+}
+
+func (t *AssignmentTracker) poll(ctx context.Context, ticketID string) (*pb.Assignment, error) {
+	ticket, err := t.Service.GetTicket(ctx, &pb.GetTicketRequest{TicketId: ticketID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ticket.GetAssignment().GetConnection()) > 0 {
+		return ticket.GetAssignment(), nil
+	}
+
+	return nil, nil
+}
+
+func (t *AssignmentTracker) connect(ctx context.Context, result *PlayerResult) {
+	if t.Connect == nil {
+		return
+	}
+
+	err := t.Connect(ctx, result.Connection)
+	connected := err == nil
+	result.Connected = &connected
+	result.ConnectErr = err
+	if err != nil {
+		t.logger.Warn(errors.Wrapf(err, "playerUID=%s failed to connect to %s", result.PlayerUID, result.Connection).Error())
+	}
+}
+
+// deleteTicket is not bound by the simulation context so the players giving up at the end still remove their tickets
+func (t *AssignmentTracker) deleteTicket(ticketID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), deleteTicketTimeout)
+	defer cancel()
+
+	if _, err := t.Service.DeleteTicket(ctx, &pb.DeleteTicketRequest{TicketId: ticketID}); err != nil {
+		t.logger.Warn(errors.Wrapf(err, "failed to delete ticketID=%s", ticketID).Error())
+	}
+}
+
+func (t *AssignmentTracker) add(results ...PlayerResult) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	t.results = append(t.results, results...)
+}
+
+// requestedAt falls back to the create time set by Open Match for the tickets not created by a TicketCreator
+func requestedAt(request *MatchRequest) time.Time {
+	if !request.Requested.IsZero() {
+		return request.Requested
+	}
+
+	if request.Ticket.GetCreateTime() != nil {
+		return request.Ticket.GetCreateTime().AsTime()
+	}
+
+	return time.Now()
+}
+
+func gameServerName(assignment *pb.Assignment) string {
+	gs, err := extensions.GetGameServer(assignment)
+	if err != nil || len(gs.GetName()) == 0 {
+		return assignment.GetConnection()
+	}
+
+	return gs.GetName()
+}
+
+// DialConnect opens and closes a connection to the assignment address, i.e. tcp or udp
+func DialConnect(network string, timeout time.Duration) ConnectFunc {
+	return func(ctx context.Context, connection string) error {
+		if _, _, err := net.SplitHostPort(connection); err != nil {
+			return errors.Wrapf(err, "connection %s is not a host:port address", connection)
+		}
+
+		dialer := &net.Dialer{Timeout: timeout}
+		conn, err := dialer.DialContext(ctx, network, connection)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+}
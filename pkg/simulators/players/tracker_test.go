@@ -0,0 +1,230 @@
+package players
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"open-match.dev/open-match/pkg/pb"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAssignmentTracker_Track(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mode        string
+		assignAfter time.Duration
+		patience    time.Duration
+		cancelAfter time.Duration
+		want        Outcome
+		wantDeleted bool
+	}{
+		{name: "it should match the player watching the assignment", mode: AssignmentModeWatch, assignAfter: 20 * time.Millisecond, patience: time.Second, want: OutcomeMatched},
+		{name: "it should match the player polling the ticket", mode: AssignmentModePoll, assignAfter: 20 * time.Millisecond, patience: time.Second, want: OutcomeMatched},
+		{name: "it should delete the ticket when the patience runs out", mode: AssignmentModeWatch, patience: 50 * time.Millisecond, want: OutcomeAbandoned, wantDeleted: true},
+		{name: "it should time out the player when the simulation is interrupted", mode: AssignmentModePoll, patience: time.Second, cancelAfter: 50 * time.Millisecond, want: OutcomeTimedOut},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := newFakeTicketService()
+			tracker, err := NewAssignmentTracker(service, tc.mode, 10*time.Millisecond, tc.patience, nil)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelAfter > 0 {
+				time.AfterFunc(tc.cancelAfter, cancel)
+			}
+
+			player := &Player{UID: "p1", MatchRequest: &MatchRequest{Ticket: &pb.Ticket{Id: "t1"}}}
+			tracker.Track(ctx, []*Player{player, {UID: "p2", MatchRequest: &MatchRequest{}}})
+			if tc.assignAfter > 0 {
+				time.AfterFunc(tc.assignAfter, func() { service.assign("t1", "10.0.0.1:7777") })
+			}
+			tracker.Wait()
+
+			results := tracker.Results()
+			require.Len(t, results, 1)
+			require.Equal(t, tc.want, results[0].Outcome)
+			require.Equal(t, tc.wantDeleted, service.deleted("t1"))
+			if tc.want == OutcomeMatched {
+				require.Equal(t, "10.0.0.1:7777", player.Assignment.GetConnection())
+				require.GreaterOrEqual(t, results[0].Wait, tc.assignAfter)
+			}
+		})
+	}
+}
+
+func TestAssignmentTracker_Track_Party(t *testing.T) {
+	service := newFakeTicketService()
+	tracker, err := NewAssignmentTracker(service, AssignmentModePoll, 10*time.Millisecond, 50*time.Millisecond, nil)
+	require.NoError(t, err)
+
+	ticket := &pb.Ticket{Id: "t1"}
+	party := []*Player{
+		{UID: "p1", PartyID: "party-1", MatchRequest: &MatchRequest{Ticket: ticket}},
+		{UID: "p2", PartyID: "party-1", MatchRequest: &MatchRequest{Ticket: ticket}},
+	}
+	tracker.Track(context.Background(), party)
+	tracker.Wait()
+
+	results := tracker.Results()
+	require.Len(t, results, 2)
+	require.ElementsMatch(t, []string{"p1", "p2"}, []string{results[0].PlayerUID, results[1].PlayerUID})
+	for _, r := range results {
+		require.Equal(t, OutcomeAbandoned, r.Outcome)
+	}
+	require.Equal(t, 1, service.deleteCalls())
+}
+
+func TestAssignmentTracker_Track_Requested(t *testing.T) {
+	service := newFakeTicketService()
+	service.assign("t1", "10.0.0.1:7777")
+	tracker, err := NewAssignmentTracker(service, AssignmentModePoll, 10*time.Millisecond, time.Second, nil)
+	require.NoError(t, err)
+
+	// The tickets of the batch took a while to be created, the players have been waiting since they requested them
+	requested := time.Now().Add(-300 * time.Millisecond)
+	matched := &Player{UID: "p1", MatchRequest: &MatchRequest{Ticket: &pb.Ticket{Id: "t1"}, Requested: requested}}
+	impatient := &Player{UID: "p2", MatchRequest: &MatchRequest{Ticket: &pb.Ticket{Id: "t2", CreateTime: timestamppb.New(time.Now().Add(-time.Second))}}}
+
+	start := time.Now()
+	tracker.Track(context.Background(), []*Player{matched, impatient})
+	tracker.Wait()
+	require.Less(t, time.Since(start), 500*time.Millisecond, "the patience started when the ticket was created")
+
+	results := map[string]PlayerResult{}
+	for _, r := range tracker.Results() {
+		results[r.PlayerUID] = r
+	}
+	require.Equal(t, OutcomeMatched, results["p1"].Outcome)
+	require.GreaterOrEqual(t, results["p1"].Wait, 300*time.Millisecond)
+	require.Equal(t, OutcomeAbandoned, results["p2"].Outcome)
+	require.GreaterOrEqual(t, results["p2"].Wait, time.Second)
+}
+
+func TestAssignmentTracker_Summary(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	service := newFakeTicketService()
+	service.assign("t1", ln.Addr().String())
+	service.assign("t2", "127.0.0.1:1")
+	service.assign("t3", "gameserver")
+
+	tracker, err := NewAssignmentTracker(service, AssignmentModePoll, 10*time.Millisecond, 50*time.Millisecond, DialConnect("tcp", time.Second))
+	require.NoError(t, err)
+
+	var players []*Player
+	for _, id := range []string{"t1", "t2", "t3", "t4"} {
+		players = append(players, &Player{UID: id, MatchRequest: &MatchRequest{Ticket: &pb.Ticket{Id: id}}})
+	}
+	tracker.Track(context.Background(), players)
+	tracker.Wait()
+
+	summary := tracker.Summary()
+	require.Equal(t, 3, summary.Matched)
+	require.Equal(t, 1, summary.Abandoned)
+	require.Equal(t, 0, summary.TimedOut)
+	require.Equal(t, 1, summary.Connected)
+	require.Equal(t, 2, summary.ConnectFailed)
+}
+
+func TestNewAssignmentTracker(t *testing.T) {
+	_, err := NewAssignmentTracker(newFakeTicketService(), "push", time.Second, time.Second, nil)
+	require.Error(t, err)
+
+	_, err = NewAssignmentTracker(newFakeTicketService(), AssignmentModePoll, 0, time.Second, nil)
+	require.Error(t, err)
+}
+
+// fakeTicketService keeps the assignments in memory. Watch streams are notified when a ticket is assigned.
+type fakeTicketService struct {
+	mux         sync.Mutex
+	assignments map[string]*pb.Assignment
+	deletes     map[string]bool
+	deleteCount int
+	changed     chan struct{}
+}
+
+func newFakeTicketService() *fakeTicketService {
+	return &fakeTicketService{
+		assignments: map[string]*pb.Assignment{},
+		deletes:     map[string]bool{},
+		changed:     make(chan struct{}),
+	}
+}
+
+func (f *fakeTicketService) assign(ticketID, connection string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.assignments[ticketID] = &pb.Assignment{Connection: connection}
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeTicketService) deleted(ticketID string) bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.deletes[ticketID]
+}
+
+func (f *fakeTicketService) deleteCalls() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.deleteCount
+}
+
+func (f *fakeTicketService) GetTicket(ctx context.Context, in *pb.GetTicketRequest, opts ...grpc.CallOption) (*pb.Ticket, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return &pb.Ticket{Id: in.GetTicketId(), Assignment: f.assignments[in.GetTicketId()]}, nil
+}
+
+func (f *fakeTicketService) WatchAssignments(ctx context.Context, in *pb.WatchAssignmentsRequest, opts ...grpc.CallOption) (pb.FrontendService_WatchAssignmentsClient, error) {
+	return &fakeWatchStream{ctx: ctx, ticketID: in.GetTicketId(), service: f}, nil
+}
+
+func (f *fakeTicketService) DeleteTicket(ctx context.Context, in *pb.DeleteTicketRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	f.deletes[in.GetTicketId()] = true
+	f.deleteCount++
+	return &emptypb.Empty{}, nil
+}
+
+type fakeWatchStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	ticketID string
+	service  *fakeTicketService
+}
+
+func (s *fakeWatchStream) Recv() (*pb.WatchAssignmentsResponse, error) {
+	for {
+		s.service.mux.Lock()
+		assignment, changed := s.service.assignments[s.ticketID], s.service.changed
+		s.service.mux.Unlock()
+
+		if assignment != nil {
+			return &pb.WatchAssignmentsResponse{Assignment: assignment}, nil
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		case <-changed:
+		}
+	}
+}
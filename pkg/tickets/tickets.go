@@ -0,0 +1,212 @@
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"open-match.dev/open-match/pkg/pb"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Filter selects the tickets by tags and age
+type Filter struct {
+	// Tags must all be present on the tickets
+	Tags []string
+	// OlderThan selects the tickets created more than OlderThan ago, zero selects any age
+	OlderThan time.Duration
+}
+
+// Empty returns true if the filter selects every ticket
+func (f Filter) Empty() bool {
+	return len(f.Tags) == 0 && f.OlderThan <= 0
+}
+
+// Pool builds the QueryService pool of the filter
+func (f Filter) Pool(now time.Time) *pb.Pool {
+	pool := &pb.Pool{Name: "tickets"}
+	for _, tag := range f.Tags {
+		pool.TagPresentFilters = append(pool.TagPresentFilters, &pb.TagPresentFilter{Tag: tag})
+	}
+
+	if f.OlderThan > 0 {
+		pool.CreatedBefore = timestamppb.New(now.Add(-f.OlderThan))
+	}
+
+	return pool
+}
+
+// FrontEndService deletes the tickets
+type FrontEndService interface {
+	DeleteTicket(ctx context.Context, req *pb.DeleteTicketRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+// DeleteStats counts the DeleteTicket calls
+type DeleteStats struct {
+	Deleted int `json:"deleted"`
+	Failed  int `json:"failed"`
+}
+
+// Manager lists the tickets with the QueryService and deletes them with the FrontEnd service. The QueryService only
+// returns the tickets waiting for a match, the tickets in a proposal or assigned are not listed.
+type Manager struct {
+	logger   *logrus.Entry
+	query    pb.QueryServiceClient
+	frontEnd FrontEndService
+	workers  int
+	now      func() time.Time
+}
+
+// NewManager returns a Manager deleting the tickets with at least one worker
+func NewManager(logger *logrus.Entry, query pb.QueryServiceClient, frontEnd FrontEndService, workers int) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &Manager{
+		logger:   logger,
+		query:    query,
+		frontEnd: frontEnd,
+		workers:  workers,
+		now:      time.Now,
+	}
+}
+
+// List returns the tickets of the filter in the order they were created
+func (m *Manager) List(ctx context.Context, filter Filter) ([]*pb.Ticket, error) {
+	stream, err := m.query.QueryTickets(ctx, &pb.QueryTicketsRequest{Pool: filter.Pool(m.now())})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query tickets")
+	}
+
+	var tickets []*pb.Ticket
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to receive tickets")
+		}
+
+		tickets = append(tickets, resp.GetTickets()...)
+	}
+
+	sort.SliceStable(tickets, func(i, j int) bool {
+		return tickets[i].GetCreateTime().AsTime().Before(tickets[j].GetCreateTime().AsTime())
+	})
+
+	return tickets, nil
+}
+
+// Delete deletes the tickets with the workers. Failed calls are logged and counted, the other tickets are still deleted.
+func (m *Manager) Delete(ctx context.Context, ids []string) DeleteStats {
+	queue := make(chan string)
+	go func() {
+		defer close(queue)
+		for _, id := range ids {
+			select {
+			case queue <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var stats DeleteStats
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				_, err := m.frontEnd.DeleteTicket(ctx, &pb.DeleteTicketRequest{TicketId: id})
+
+				mux.Lock()
+				if err != nil {
+					stats.Failed++
+					m.logger.WithError(err).Errorf("failed to delete ticket %s", id)
+				} else {
+					stats.Deleted++
+					m.logger.Debugf("ticket %s deleted", id)
+				}
+				mux.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return stats
+}
+
+// Expire deletes the tickets of the filter
+func (m *Manager) Expire(ctx context.Context, filter Filter) (DeleteStats, error) {
+	tickets, err := m.List(ctx, filter)
+	if err != nil {
+		return DeleteStats{}, err
+	}
+
+	return m.Delete(ctx, IDs(tickets)), nil
+}
+
+// IDs returns the ids of the tickets
+func IDs(tickets []*pb.Ticket) []string {
+	var ids []string
+	for _, ticket := range tickets {
+		ids = append(ids, ticket.GetId())
+	}
+
+	return ids
+}
+
+// WriteTable writes a line with the age and search fields of every ticket
+func WriteTable(out io.Writer, tickets []*pb.Ticket, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ID\tAGE\tTAGS\tSTRING ARGS\tDOUBLE ARGS")
+	for _, ticket := range tickets {
+		fields := ticket.GetSearchFields()
+		age := now.Sub(ticket.GetCreateTime().AsTime()).Truncate(time.Second)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ticket.GetId(), age, strings.Join(fields.GetTags(), ","), joinArgs(fields.GetStringArgs()), joinArgs(fields.GetDoubleArgs()))
+	}
+	fmt.Fprintf(w, "\n%d tickets\n", len(tickets))
+
+	return w.Flush()
+}
+
+// WriteJSON writes the tickets as a JSON list using the Open Match JSON encoding
+func WriteJSON(out io.Writer, tickets []*pb.Ticket) error {
+	list := []json.RawMessage{}
+	for _, ticket := range tickets {
+		data, err := protojson.Marshal(ticket)
+		if err != nil {
+			return err
+		}
+		list = append(list, data)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+func joinArgs[T any](args map[string]T) string {
+	var pairs []string
+	for k, v := range args {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,123 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"github.com/Octops/agones-discover-openmatch/internal/runtime"
+	"github.com/Octops/agones-discover-openmatch/pkg/openmatchtest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"open-match.dev/open-match/pkg/pb"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	om := openmatchtest.NewOpenMatch()
+	om.Start()
+	t.Cleanup(om.Stop)
+
+	conn, err := om.Dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ticket := func(tags ...string) *pb.Ticket {
+		return &pb.Ticket{SearchFields: &pb.SearchFields{Tags: tags, StringArgs: map[string]string{"world": "Dune"}}}
+	}
+
+	ids, err := om.CreateTickets(ticket("mode.session", "source.simulator"), ticket("mode.session"), ticket("mode.session", "source.simulator"))
+	require.NoError(t, err)
+
+	frontEnd := pb.NewFrontendServiceClient(conn)
+	manager := NewManager(runtime.NewLogger(true), pb.NewQueryServiceClient(conn), frontEnd, 2)
+
+	t.Run("it should list the tickets with the tags", func(t *testing.T) {
+		list, err := manager.List(context.Background(), Filter{Tags: []string{"source.simulator"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{ids[0], ids[2]}, IDs(list))
+
+		list, err = manager.List(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+	})
+
+	t.Run("it should only list the tickets older than the age", func(t *testing.T) {
+		list, err := manager.List(context.Background(), Filter{OlderThan: time.Minute})
+		require.NoError(t, err)
+		require.Empty(t, list)
+
+		manager.now = func() time.Time { return time.Now().Add(time.Hour) }
+		defer func() { manager.now = time.Now }()
+
+		list, err = manager.List(context.Background(), Filter{OlderThan: time.Minute})
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+	})
+
+	t.Run("it should expire the tickets of the filter and keep the others", func(t *testing.T) {
+		stats, err := manager.Expire(context.Background(), Filter{Tags: []string{"source.simulator"}})
+		require.NoError(t, err)
+		require.Equal(t, DeleteStats{Deleted: 2}, stats)
+
+		list, err := manager.List(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Equal(t, []string{ids[1]}, IDs(list))
+	})
+
+	t.Run("it should count the tickets that failed to be deleted", func(t *testing.T) {
+		failing := NewManager(runtime.NewLogger(true), pb.NewQueryServiceClient(conn), failingFrontEnd{frontEnd, ids[1]}, 1)
+
+		require.Equal(t, DeleteStats{Deleted: 1, Failed: 1}, failing.Delete(context.Background(), []string{ids[0], ids[1]}))
+	})
+}
+
+func TestFilter_Pool(t *testing.T) {
+	now := time.Now()
+
+	pool := Filter{Tags: []string{"source.simulator", "mode.session"}, OlderThan: time.Minute}.Pool(now)
+	require.Len(t, pool.GetTagPresentFilters(), 2)
+	require.Equal(t, now.Add(-time.Minute).UnixNano(), pool.GetCreatedBefore().AsTime().UnixNano())
+
+	require.Nil(t, Filter{}.Pool(now).GetCreatedBefore())
+	require.True(t, Filter{}.Empty())
+	require.False(t, Filter{OlderThan: time.Minute}.Empty())
+}
+
+func TestWriteTable(t *testing.T) {
+	now := time.Now()
+	list := []*pb.Ticket{{
+		Id:         "t1",
+		CreateTime: timestamppb.New(now.Add(-90 * time.Second)),
+		SearchFields: &pb.SearchFields{
+			Tags:       []string{"mode.session", "source.simulator"},
+			StringArgs: map[string]string{"world": "Dune", "region": "us-east-1"},
+			DoubleArgs: map[string]float64{"skill": 100},
+		},
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteTable(&out, list, now))
+	require.Contains(t, out.String(), "t1  1m30s  mode.session,source.simulator  region=us-east-1,world=Dune  skill=100")
+	require.Contains(t, out.String(), "1 tickets")
+
+	out.Reset()
+	require.NoError(t, WriteJSON(&out, nil))
+	require.Equal(t, "[]\n", out.String())
+}
+
+// failingFrontEnd fails to delete one ticket
+type failingFrontEnd struct {
+	FrontEndService
+	failID string
+}
+
+func (f failingFrontEnd) DeleteTicket(ctx context.Context, req *pb.DeleteTicketRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	if req.GetTicketId() == f.failID {
+		return nil, errors.New("frontend unavailable")
+	}
+
+	return f.FrontEndService.DeleteTicket(ctx, req, opts...)
+}